@@ -20,6 +20,20 @@ func NewArenaView(arena *core.Arena, characters []*CharData) *ArenaView {
 	}
 }
 
+// ItemColors are the colors of the items waiting to be picked up.
+var ItemColors = map[core.ItemKind]color.Color{
+	core.SpeedItem: color.RGBA{R: 255, G: 220, B: 40, A: 255},
+	core.GhostItem: color.RGBA{R: 235, G: 235, B: 255, A: 255},
+	core.BombItem:  color.RGBA{R: 255, G: 60, B: 40, A: 255},
+}
+
+// ghostColor is a paler version of a color, for a player in ghost mode.
+func ghostColor(c color.Color) color.Color {
+	r, g, b, _ := c.RGBA()
+	pale := func(v uint32) uint8 { return uint8((v>>8 + 255) / 2) }
+	return color.RGBA{R: pale(r), G: pale(g), B: pale(b), A: 255}
+}
+
 // GetSquareColor returns the appropriate color.Color for a square at (x, y)
 // based on its state and the current player positions.
 func (av *ArenaView) GetSquareColor(x, y int, square core.Square) color.Color {
@@ -31,16 +45,27 @@ func (av *ArenaView) GetSquareColor(x, y int, square core.Square) color.Color {
 		if player.IsAlive && player.Position.Equals(currentPos) {
 			// characters is 0-indexed, player.ID is 1-indexed
 			charData := av.Characters[player.ID-1]
+			if player.GhostTicks > 0 {
+				return ghostColor(charData.BrightColor)
+			}
 			return charData.BrightColor
 		}
 	}
 
+	if kind, ok := av.Arena.Items[currentPos]; ok {
+		return ItemColors[kind]
+	}
+
 	// Check for Arena Square Type
 	switch square {
 	case core.Open:
 		return color.RGBA{R: 34, G: 32, B: 52, A: 255}
 	case core.Wall:
 		return color.RGBA{R: 200, G: 200, B: 200, A: 255}
+	case core.SpeedPad:
+		return color.RGBA{R: 40, G: 140, B: 150, A: 255}
+	case core.Portal:
+		return color.RGBA{R: 220, G: 60, B: 220, A: 255}
 	default:
 		// Player Path / Trail
 		playerID := int(square)
@@ -50,7 +75,18 @@ func (av *ArenaView) GetSquareColor(x, y int, square core.Square) color.Color {
 	}
 }
 
+// squareLayout works out how big to draw each square so the whole arena
+// fits in the space for it, and where to put the arena to center it there.
+func (av *ArenaView) squareLayout() (size, left, top int) {
+	size = max(min(ArenaSize/av.Arena.Width, ArenaSize/av.Arena.Height), 1)
+	left = ArenaOffsetX + (ArenaSize-size*av.Arena.Width)/2
+	top = ArenaOffsetY + (ArenaSize-size*av.Arena.Height)/2
+	return size, left, top
+}
+
 func (av *ArenaView) Draw(screen *ebiten.Image) {
+	size, left, top := av.squareLayout()
+	scale := float64(size) / float64(SquareImage.Bounds().Dx())
 	for y := 0; y < av.Arena.Height; y++ {
 		for x := 0; x < av.Arena.Width; x++ {
 			square := av.Arena.Grid[y][x]
@@ -66,7 +102,8 @@ func (av *ArenaView) Draw(screen *ebiten.Image) {
 			cm.Scale(r, g, b, 1.0)
 
 			op := &colorm.DrawImageOptions{}
-			op.GeoM.Translate(float64(x*SquareSize+ArenaOffsetX), float64(y*SquareSize+ArenaOffsetY))
+			op.GeoM.Scale(scale, scale)
+			op.GeoM.Translate(float64(x*size+left), float64(y*size+top))
 
 			colorm.DrawImage(screen, SquareImage, cm, op)
 		}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"github.com/jonathanacross/gamedev/gocycle/core"
 	"image/color"
 	"os"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
@@ -24,6 +26,9 @@ type CharData struct {
 	BrightColor    color.Color
 	NewController  func() core.PlayerController
 	ControllerType ControllerType
+	// Set if NewController was chosen on the command line, which the match
+	// settings then leave alone
+	FromCommandLine bool
 }
 
 var Characters []CharData = loadCharData()
@@ -137,3 +142,18 @@ func loadCharData() []CharData {
 		},
 	}
 }
+
+// UseController makes the computer character with the given name be
+// played by controllers from newController instead, such as an external
+// bot or a trained genome.
+func UseController(name string, newController func() core.PlayerController) error {
+	for i := range Characters {
+		char := &Characters[i]
+		if char.ControllerType == ComputerPlayer && strings.EqualFold(char.Name, name) {
+			char.NewController = newController
+			char.FromCommandLine = true
+			return nil
+		}
+	}
+	return fmt.Errorf("no computer character named %q", name)
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"image/color"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/jonathanacross/gamedev/gocycle/core"
 )
 
 const (
@@ -23,9 +26,12 @@ type CharacterPicker struct {
 	NumPlayer1Selected int
 	NumPlayer2Selected int
 	NumSelected        int
+	// If hosting a network game, Player 2 characters are played by the
+	// clients, one each
+	Host *core.Host
 }
 
-func NewCharacterPicker() *CharacterPicker {
+func NewCharacterPicker(host *core.Host) *CharacterPicker {
 
 	chars := []*CharacterFrame{}
 	characterIndices := []int{6, 8, 0, 1, 2, 7, 9, 3, 4, 5}
@@ -44,6 +50,7 @@ func NewCharacterPicker() *CharacterPicker {
 		NumPlayer1Selected: 0,
 		NumPlayer2Selected: 0,
 		NumSelected:        0,
+		Host:               host,
 	}
 }
 
@@ -53,7 +60,16 @@ func (cs *CharacterPicker) Draw(screen *ebiten.Image) {
 	}
 	drawShadowedTextAt(screen, "Pick your characters", 130, 5, text.AlignStart, color.White)
 	drawShadowedTextAt(screen, "Player 1", 60, 25, text.AlignStart, color.White)
-	drawShadowedTextAt(screen, "Player 2", 60, 115, text.AlignStart, color.White)
+	if cs.Host != nil {
+		drawShadowedTextAt(screen, "Network", 60, 115, text.AlignStart, color.White)
+		lobby := fmt.Sprintf("Hosting on %s", cs.Host.Addr())
+		if clients := cs.Host.Clients(); len(clients) > 0 {
+			lobby = "Joined: " + strings.Join(clients, ", ")
+		}
+		drawShadowedTextAt(screen, lobby, 12, 220, text.AlignStart, color.White)
+	} else {
+		drawShadowedTextAt(screen, "Player 2", 60, 115, text.AlignStart, color.White)
+	}
 	drawShadowedTextAt(screen, "Computer opponents", 200, 25, text.AlignStart, color.White)
 	if cs.IsValid() {
 		drawShadowedTextAt(screen, "Press space to continue", 250, 205, text.AlignStart, color.White)
@@ -93,8 +109,8 @@ func (cs *CharacterPicker) canSelect(char *CharacterFrame) bool {
 		return false
 	}
 
-	// can only pick one human player 2
-	if char.CharData.ControllerType == HumanSecondPlayer && cs.NumPlayer2Selected >= 1 {
+	// can only pick one human player 2, or one for each network client
+	if char.CharData.ControllerType == HumanSecondPlayer && cs.NumPlayer2Selected >= cs.maxPlayer2() {
 		return false
 	}
 
@@ -116,6 +132,15 @@ func (cs *CharacterPicker) updateCounts(char *CharacterFrame, delta int) {
 	cs.NumSelected += delta
 }
 
+// maxPlayer2 is how many Player 2 characters can be picked.
+func (cs *CharacterPicker) maxPlayer2() int {
+	if cs.Host != nil {
+		return len(cs.Host.Clients())
+	}
+	return 1
+}
+
 func (cs *CharacterPicker) IsValid() bool {
-	return cs.NumSelected >= 2
+	// A network client may have left since its character was picked
+	return cs.NumSelected >= 2 && cs.NumPlayer2Selected <= cs.maxPlayer2()
 }
@@ -0,0 +1,337 @@
+// Command benchmark plays many rounds of gocycle between computer players,
+// without the game window, and reports how well each controller did.
+//
+// Each round seats a random choice of controllers on a random map. Rounds
+// are played in parallel, but everything about a round is chosen from the
+// seed before any are played, so the same seed always gives the same
+// results (except for controllers with a time budget, whose moves depend on
+// how busy the computer is).
+//
+// Usage:
+//
+//	go run ./cmd/benchmark -runs 500 -players 2,4 -format table
+//
+// Bots written as separate programs can play too, using the protocol
+// described in core/external.go:
+//
+//	go run ./cmd/benchmark -bot "mybot=python3 mybot.py" -controllers mybot,AreaController -players 2
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonathanacross/gamedev/gocycle/core"
+)
+
+// Config says which rounds the benchmark plays.
+type Config struct {
+	Runs int
+	// Rounds are shared out between these table sizes
+	TableSizes []int
+	// Controllers to choose the players from, by name
+	Controllers []string
+	// Maps to choose from for each table size
+	Arenas map[int][]*core.Map
+	// Rules for items, or nil to play without them
+	Items *core.ItemRules
+	// How rounds are scored, and whether they are played in two teams;
+	// players are ranked by their scores
+	Scoring core.Scoring
+	Teams   bool
+	// If set, a replay of each round is saved here
+	ReplayDir string
+	Seed      uint64
+	Parallel  int
+}
+
+// round is everything needed to play one round of the benchmark.
+type round struct {
+	index int
+	// names of the controllers, in seat order
+	names []string
+	arena *core.Map
+	seed  uint64
+}
+
+// roundResult is how one round finished.
+type roundResult struct {
+	round round
+	// ranks[i] is where seat i finished, 1 for first; players with the
+	// same score share the places between them, so a rank may be fractional
+	ranks  []float64
+	scores []float64
+	// team of each seat, or nil if everyone played for themselves
+	teams []int
+}
+
+func main() {
+	runs := flag.Int("runs", 1000, "Number of rounds to play.")
+	players := flag.String("players", "4", "Comma-separated table sizes to play, each 2, 3 or 4. Rounds are shared out between them.")
+	controllers := flag.String("controllers", "all", "Comma-separated controllers to play, or \"all\": "+strings.Join(core.ControllerNames(), ", "))
+	arenas := flag.String("arenas", "all", "Comma-separated names of the maps to play on, or \"all\".")
+	mapDir := flag.String("maps", "", "Also load the maps in this directory.")
+	items := flag.Bool("items", false, "Play with power-up items.")
+	scoring := flag.String("scoring", "rank", "How to score rounds: "+strings.Join(core.ScoringNames(), ", ")+".")
+	teams := flag.Bool("teams", false, "Play 2v2, with the first and third seats against the second and fourth. Needs -players 4.")
+	replayDir := flag.String("replay-dir", "", "Save a replay of each round to this directory.")
+	seed := flag.Uint64("seed", uint64(time.Now().UnixNano()), "Seed for the random choices; the same seed repeats the benchmark.")
+	parallel := flag.Int("parallel", runtime.NumCPU(), "Number of rounds to play at once.")
+	format := flag.String("format", "table", "Output format: table, csv or json.")
+	bots := []string{}
+	flag.Func("bot", "Add an external bot as name=command; may be repeated.", func(s string) error {
+		bots = append(bots, s)
+		return nil
+	})
+	genomes := []string{}
+	flag.Func("genome", "Add the best weights in a trainer checkpoint as a controller, as name=file; may be repeated.", func(s string) error {
+		genomes = append(genomes, s)
+		return nil
+	})
+	botTimeout := flag.Duration("bot-timeout", core.DefaultBotTimeout, "Time each external bot has to answer each tick.")
+	flag.Parse()
+
+	for _, bot := range bots {
+		name, command, err := core.ParseBot(bot)
+		if err != nil {
+			exit(err)
+		}
+		core.AddBot(name, command, *botTimeout)
+	}
+	for _, spec := range genomes {
+		name, path, ok := strings.Cut(spec, "=")
+		if !ok {
+			exit(fmt.Errorf("invalid genome %q: expected name=file", spec))
+		}
+		genome, err := core.LoadGenome(path)
+		if err != nil {
+			exit(err)
+		}
+		core.AddGenome(name, genome)
+	}
+
+	if *format != "table" && *format != "csv" && *format != "json" {
+		exit(fmt.Errorf("unknown format %q", *format))
+	}
+	if *mapDir != "" {
+		if err := core.AddMaps(*mapDir); err != nil {
+			exit(err)
+		}
+	}
+	config := Config{
+		Runs:      *runs,
+		Teams:     *teams,
+		ReplayDir: *replayDir,
+		Seed:      *seed,
+		Parallel:  *parallel,
+	}
+	var err error
+	if config.Scoring, err = core.ParseScoring(*scoring); err != nil {
+		exit(err)
+	}
+	if *items {
+		config.Items = &core.DefaultItemRules
+	}
+	if err := config.choose(*players, *controllers, *arenas); err != nil {
+		exit(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Playing %d rounds between %d controllers, seed %d\n", config.Runs, len(config.Controllers), config.Seed)
+	report := RunBenchmark(config)
+
+	switch *format {
+	case "csv":
+		err = report.WriteCSV(os.Stdout)
+	case "json":
+		err = report.WriteJSON(os.Stdout)
+	default:
+		report.WriteTable(os.Stdout)
+	}
+	if err != nil {
+		exit(err)
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(1)
+}
+
+// choose fills in the table sizes, controllers and maps from the
+// comma-separated lists given on the command line.
+func (c *Config) choose(players, controllers, arenas string) error {
+	if c.Runs <= 0 {
+		return fmt.Errorf("-runs must be positive")
+	}
+
+	c.Controllers = core.ControllerNames()
+	if controllers != "all" {
+		c.Controllers = core.SplitList(controllers)
+		for _, name := range c.Controllers {
+			if _, err := core.NewController(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.TableSizes = nil
+	for _, s := range core.SplitList(players) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 2 || n > 4 {
+			return fmt.Errorf("invalid table size %q", s)
+		}
+		if n > len(c.Controllers) {
+			return fmt.Errorf("%d-player tables need at least %d controllers, have %d", n, n, len(c.Controllers))
+		}
+		if c.Teams && core.PairTeams(n) == nil {
+			return fmt.Errorf("%d players can't be split into two teams", n)
+		}
+		c.TableSizes = append(c.TableSizes, n)
+	}
+	if len(c.TableSizes) == 0 {
+		return fmt.Errorf("no table sizes given")
+	}
+
+	c.Arenas = map[int][]*core.Map{}
+	for _, n := range c.TableSizes {
+		c.Arenas[n] = selectArenas(arenas, n)
+		if len(c.Arenas[n]) == 0 {
+			return fmt.Errorf("no maps with room for %d players", n)
+		}
+	}
+	return nil
+}
+
+// RunBenchmark plays all the rounds and collects the results.
+func RunBenchmark(config Config) *Report {
+	rounds := planRounds(config)
+	results := playRounds(rounds, config)
+	return NewReport(config, results)
+}
+
+// selectArenas returns the maps named in the -arenas flag that have room
+// for numPlayers players.
+func selectArenas(arenasFlag string, numPlayers int) []*core.Map {
+	available := core.MapsForPlayers(numPlayers)
+	if arenasFlag == "all" {
+		return available
+	}
+	wanted := map[string]bool{}
+	for _, name := range core.SplitList(arenasFlag) {
+		wanted[name] = true
+	}
+	result := []*core.Map{}
+	for _, m := range available {
+		if wanted[m.Name] {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// planRounds chooses the table size, controllers, seating, map and seed of
+// every round up front, so the results don't depend on the order in which
+// the rounds finish.
+func planRounds(config Config) []round {
+	rng := core.NewRand(config.Seed)
+	shuffled := append([]string{}, config.Controllers...)
+	rounds := make([]round, config.Runs)
+	for i := range rounds {
+		n := config.TableSizes[i%len(config.TableSizes)]
+		rng.Shuffle(len(shuffled), func(a, b int) {
+			shuffled[a], shuffled[b] = shuffled[b], shuffled[a]
+		})
+		rounds[i] = round{
+			index: i,
+			names: append([]string{}, shuffled[:n]...),
+			arena: config.Arenas[n][rng.IntN(len(config.Arenas[n]))],
+			seed:  rng.Uint64(),
+		}
+	}
+	return rounds
+}
+
+// playRounds plays the rounds on several goroutines, returning the results
+// in the same order as the rounds.
+func playRounds(rounds []round, config Config) []roundResult {
+	results := make([]roundResult, len(rounds))
+	jobs := make(chan round)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	finished := 0
+	for range max(config.Parallel, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				results[r.index] = playRound(r, config)
+
+				mu.Lock()
+				finished++
+				if finished%100 == 0 {
+					fmt.Fprintf(os.Stderr, "  %d/%d rounds played\n", finished, len(rounds))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, r := range rounds {
+		jobs <- r
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func playRound(r round, config Config) roundResult {
+	controllers := make([]core.PlayerController, len(r.names))
+	for i, name := range r.names {
+		// The names were checked before any rounds were planned
+		controllers[i], _ = core.NewController(name)
+	}
+	players := r.arena.NewPlayers(controllers)
+	rules := core.ScoreRules{Scoring: config.Scoring}
+	if config.Teams {
+		rules.Teams = core.PairTeams(len(players))
+	}
+	scores, replay := core.SimulateScoredRound(r.arena, players, r.names, r.seed, config.Items, rules)
+	core.CloseControllers(players)
+	if config.ReplayDir != "" {
+		path := filepath.Join(config.ReplayDir, fmt.Sprintf("run%05d.replay", r.index))
+		if err := replay.Save(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving replay: %v\n", err)
+		}
+	}
+
+	seatScores := make([]float64, len(players))
+	for i, p := range players {
+		seatScores[i] = float64(scores[p.ID])
+	}
+	return roundResult{round: r, ranks: ranksFromScores(seatScores), scores: seatScores, teams: rules.Teams}
+}
+
+// ranksFromScores ranks the players by score, highest first. Players with
+// the same score share the places they cover, so two players tied for first
+// are both 1.5.
+func ranksFromScores(scores []float64) []float64 {
+	ranks := make([]float64, len(scores))
+	for i, score := range scores {
+		ranks[i] = 1
+		for j, other := range scores {
+			switch {
+			case other > score:
+				ranks[i]++
+			case other == score && i != j:
+				ranks[i] += 0.5
+			}
+		}
+	}
+	return ranks
+}
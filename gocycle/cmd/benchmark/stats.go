@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jonathanacross/gamedev/gocycle/core"
+)
+
+// z value for a 95% confidence interval
+const z95 = 1.96
+
+// Stats accumulates one controller's results at tables of one size.
+type Stats struct {
+	Games int
+	// sums of the ranks and their squares, for the mean and its interval
+	rankSum     float64
+	rankSquares float64
+	// rounds won, sharing a round between players that tie for first
+	wins float64
+	// sum of the points scored, under the benchmark's scoring
+	scoreSum float64
+}
+
+func (s *Stats) add(rank, winShare, score float64) {
+	s.Games++
+	s.rankSum += rank
+	s.rankSquares += rank * rank
+	s.wins += winShare
+	s.scoreSum += score
+}
+
+func (s *Stats) MeanRank() float64 {
+	return s.rankSum / float64(s.Games)
+}
+
+// RankInterval is a 95% confidence interval for the mean rank, from the
+// standard error of the ranks, kept within the ranks possible at the
+// table. With fewer than two games nothing is known, so it covers them all.
+func (s *Stats) RankInterval(numPlayers int) (low, high float64) {
+	if s.Games < 2 {
+		return 1, float64(numPlayers)
+	}
+	n := float64(s.Games)
+	mean := s.MeanRank()
+	variance := max((s.rankSquares-n*mean*mean)/(n-1), 0)
+	margin := z95 * math.Sqrt(variance/n)
+	return max(mean-margin, 1), min(mean+margin, float64(numPlayers))
+}
+
+func (s *Stats) MeanScore() float64 {
+	return s.scoreSum / float64(s.Games)
+}
+
+func (s *Stats) WinRate() float64 {
+	return s.wins / float64(s.Games)
+}
+
+// WinInterval is the Wilson score interval for the win rate, which
+// unlike the usual normal interval stays between 0 and 1 and works for
+// controllers that win nearly always or nearly never.
+func (s *Stats) WinInterval() (low, high float64) {
+	return wilsonInterval(s.WinRate(), float64(s.Games))
+}
+
+func wilsonInterval(p, n float64) (low, high float64) {
+	denom := 1 + z95*z95/n
+	center := (p + z95*z95/(2*n)) / denom
+	margin := z95 * math.Sqrt(p*(1-p)/n+z95*z95/(4*n*n)) / denom
+	return max(center-margin, 0), min(center+margin, 1)
+}
+
+// PairStats counts how one controller did against another at the same
+// table.
+type PairStats struct {
+	Games int
+	// rounds the first controller finished ahead of the second, counting
+	// finishing level as half
+	ahead float64
+}
+
+// Score is the fraction of rounds the first controller finished ahead.
+func (p *PairStats) Score() float64 {
+	return p.ahead / float64(p.Games)
+}
+
+// TableStats collects the results of the rounds played at one table size.
+type TableStats struct {
+	Players int
+	Rounds  int
+	// How the rounds were scored, and whether they were played in teams
+	Scoring core.Scoring
+	Teams   bool
+	// results for each controller
+	controllers map[string]*Stats
+	// results for the first controller against the second
+	headToHead map[[2]string]*PairStats
+}
+
+func NewTableStats(numPlayers int) *TableStats {
+	return &TableStats{
+		Players:     numPlayers,
+		controllers: map[string]*Stats{},
+		headToHead:  map[[2]string]*PairStats{},
+	}
+}
+
+func (t *TableStats) get(name string) *Stats {
+	if _, ok := t.controllers[name]; !ok {
+		t.controllers[name] = &Stats{}
+	}
+	return t.controllers[name]
+}
+
+func (t *TableStats) getPair(name1, name2 string) *PairStats {
+	key := [2]string{name1, name2}
+	if _, ok := t.headToHead[key]; !ok {
+		t.headToHead[key] = &PairStats{}
+	}
+	return t.headToHead[key]
+}
+
+// Add records the result of one round. teams[i] is the team of seat i, or
+// nil if everyone played for themselves; each player on a winning team
+// wins the round, shared with any other teams that tie for first.
+func (t *TableStats) Add(names []string, ranks, scores []float64, teams []int) {
+	t.Rounds++
+
+	best := ranks[0]
+	for _, rank := range ranks {
+		best = min(best, rank)
+	}
+	winningTeams := map[int]bool{}
+	for i, rank := range ranks {
+		if rank == best {
+			winningTeams[teamOf(teams, i)] = true
+		}
+	}
+
+	for i, name := range names {
+		winShare := 0.0
+		if ranks[i] == best {
+			winShare = 1 / float64(len(winningTeams))
+		}
+		t.get(name).add(ranks[i], winShare, scores[i])
+
+		for j, other := range names {
+			if i == j {
+				continue
+			}
+			pair := t.getPair(name, other)
+			pair.Games++
+			switch {
+			case ranks[i] < ranks[j]:
+				pair.ahead++
+			case ranks[i] == ranks[j]:
+				pair.ahead += 0.5
+			}
+		}
+	}
+}
+
+func teamOf(teams []int, seat int) int {
+	if teams == nil {
+		return seat
+	}
+	return teams[seat]
+}
+
+// sortedNames returns the controllers that played, best mean rank first.
+func (t *TableStats) sortedNames() []string {
+	names := []string{}
+	for name := range t.controllers {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		mi, mj := t.controllers[names[i]].MeanRank(), t.controllers[names[j]].MeanRank()
+		if mi != mj {
+			return mi < mj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// Report holds the benchmark results for each table size.
+type Report struct {
+	Tables []*TableStats
+}
+
+func NewReport(config Config, results []roundResult) *Report {
+	tables := map[int]*TableStats{}
+	report := &Report{}
+	for _, n := range config.TableSizes {
+		if tables[n] == nil {
+			tables[n] = NewTableStats(n)
+			tables[n].Scoring = config.Scoring
+			tables[n].Teams = config.Teams
+			report.Tables = append(report.Tables, tables[n])
+		}
+	}
+	for _, r := range results {
+		tables[len(r.round.names)].Add(r.round.names, r.ranks, r.scores, r.teams)
+	}
+	return report
+}
+
+// ControllerSummary and PairSummary are the results as written out.
+type ControllerSummary struct {
+	Name       string     `json:"name"`
+	Games      int        `json:"games"`
+	MeanRank   float64    `json:"mean_rank"`
+	MeanRankCI [2]float64 `json:"mean_rank_ci95"`
+	MeanScore  float64    `json:"mean_score"`
+	WinRate    float64    `json:"win_rate"`
+	WinRateCI  [2]float64 `json:"win_rate_ci95"`
+}
+
+type PairSummary struct {
+	Controller string `json:"controller"`
+	Opponent   string `json:"opponent"`
+	Games      int    `json:"games"`
+	// fraction of rounds the controller finished ahead of the opponent
+	Score float64 `json:"score"`
+}
+
+type TableSummary struct {
+	Players     int                 `json:"players"`
+	Rounds      int                 `json:"rounds"`
+	Scoring     string              `json:"scoring"`
+	Teams       bool                `json:"teams"`
+	Controllers []ControllerSummary `json:"controllers"`
+	HeadToHead  []PairSummary       `json:"head_to_head"`
+}
+
+func (t *TableStats) Summary() TableSummary {
+	summary := TableSummary{Players: t.Players, Rounds: t.Rounds, Scoring: t.Scoring.String(), Teams: t.Teams}
+	names := t.sortedNames()
+	for _, name := range names {
+		s := t.controllers[name]
+		rankLow, rankHigh := s.RankInterval(t.Players)
+		winLow, winHigh := s.WinInterval()
+		summary.Controllers = append(summary.Controllers, ControllerSummary{
+			Name:       name,
+			Games:      s.Games,
+			MeanRank:   s.MeanRank(),
+			MeanRankCI: [2]float64{rankLow, rankHigh},
+			MeanScore:  s.MeanScore(),
+			WinRate:    s.WinRate(),
+			WinRateCI:  [2]float64{winLow, winHigh},
+		})
+	}
+	for _, name1 := range names {
+		for _, name2 := range names {
+			if p, ok := t.headToHead[[2]string{name1, name2}]; ok {
+				summary.HeadToHead = append(summary.HeadToHead, PairSummary{
+					Controller: name1,
+					Opponent:   name2,
+					Games:      p.Games,
+					Score:      p.Score(),
+				})
+			}
+		}
+	}
+	return summary
+}
+
+// WriteTable prints the results for people to read: a table of the
+// controllers, best first, and a matrix of how often each finished ahead
+// of each other.
+func (r *Report) WriteTable(out io.Writer) {
+	for _, t := range r.Tables {
+		if t.Rounds == 0 {
+			continue
+		}
+		teams := ""
+		if t.Teams {
+			teams = ", in two teams"
+		}
+		fmt.Fprintf(out, "\n%d-player tables: %d rounds, %s scoring%s\n\n", t.Players, t.Rounds, t.Scoring, teams)
+		fmt.Fprintf(out, "%-26s %6s %9s %14s %8s %16s %10s\n", "Controller", "Games", "Mean rank", "95% CI", "Win rate", "95% CI", "Mean score")
+		fmt.Fprintln(out, strings.Repeat("-", 95))
+		names := t.sortedNames()
+		for _, name := range names {
+			s := t.controllers[name]
+			rankLow, rankHigh := s.RankInterval(t.Players)
+			winLow, winHigh := s.WinInterval()
+			fmt.Fprintf(out, "%-26s %6d %9.2f %14s %7.1f%% %16s %10.2f\n",
+				name, s.Games, s.MeanRank(), fmt.Sprintf("[%.2f, %.2f]", rankLow, rankHigh),
+				100*s.WinRate(), fmt.Sprintf("[%.1f%%, %.1f%%]", 100*winLow, 100*winHigh), s.MeanScore())
+		}
+
+		// Columns are numbered to keep the matrix narrow
+		fmt.Fprintf(out, "\nHead to head: how often each row finished ahead of each column\n\n%-29s", "")
+		for j := range names {
+			fmt.Fprintf(out, " %5d", j+1)
+		}
+		fmt.Fprintln(out)
+		for i, name := range names {
+			fmt.Fprintf(out, "%2d %-26s", i+1, name)
+			for _, other := range names {
+				p, ok := t.headToHead[[2]string{name, other}]
+				if !ok {
+					fmt.Fprintf(out, " %5s", "-")
+					continue
+				}
+				fmt.Fprintf(out, " %4.0f%%", 100*p.Score())
+			}
+			fmt.Fprintln(out)
+		}
+	}
+}
+
+// WriteCSV writes one row per controller and table size, followed by one
+// row per pair of controllers, with the columns that don't apply left empty.
+func (r *Report) WriteCSV(out io.Writer) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"players", "scoring", "teams", "controller", "opponent", "games", "mean_rank", "mean_rank_low", "mean_rank_high",
+		"win_rate", "win_rate_low", "win_rate_high", "mean_score", "score"})
+	f := func(x float64) string { return strconv.FormatFloat(x, 'f', 4, 64) }
+	for _, t := range r.Tables {
+		summary := t.Summary()
+		table := []string{strconv.Itoa(t.Players), summary.Scoring, strconv.FormatBool(summary.Teams)}
+		for _, c := range summary.Controllers {
+			w.Write(append(table, c.Name, "", strconv.Itoa(c.Games), f(c.MeanRank), f(c.MeanRankCI[0]), f(c.MeanRankCI[1]),
+				f(c.WinRate), f(c.WinRateCI[0]), f(c.WinRateCI[1]), f(c.MeanScore), ""))
+		}
+		for _, p := range summary.HeadToHead {
+			w.Write(append(table, p.Controller, p.Opponent, strconv.Itoa(p.Games), "", "", "", "", "", "", "", f(p.Score)))
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// WriteJSON writes a list with the summary of each table size.
+func (r *Report) WriteJSON(out io.Writer) error {
+	summaries := []TableSummary{}
+	for _, t := range r.Tables {
+		summaries = append(summaries, t.Summary())
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(summaries)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonathanacross/gamedev/gocycle/core"
+)
+
+func TestTableStats(t *testing.T) {
+	stats := NewTableStats(3)
+	stats.Add([]string{"A", "B", "C"}, []float64{1, 2, 3}, []float64{4, 2, 0}, nil)
+	// B and C crash together after A
+	stats.Add([]string{"C", "A", "B"}, []float64{2.5, 1, 2.5}, []float64{1, 4, 1}, nil)
+	// A and B crash together first
+	stats.Add([]string{"B", "C", "A"}, []float64{1.5, 3, 1.5}, []float64{3, 0, 3}, nil)
+
+	a := stats.get("A")
+	if a.Games != 3 || a.MeanRank() != 3.5/3 {
+		t.Errorf("A: want 3 games with mean rank %v, got %d, %v", 3.5/3, a.Games, a.MeanRank())
+	}
+	if a.WinRate() != 2.5/3 {
+		t.Errorf("A: want win rate %v, got %v", 2.5/3, a.WinRate())
+	}
+	if a.MeanScore() != 11.0/3 {
+		t.Errorf("A: want mean score %v, got %v", 11.0/3, a.MeanScore())
+	}
+	if c := stats.get("C"); c.WinRate() != 0 {
+		t.Errorf("C: want win rate 0, got %v", c.WinRate())
+	}
+
+	ab := stats.getPair("A", "B")
+	if ab.Games != 3 || ab.Score() != 2.5/3 {
+		t.Errorf("A v B: want score %v over 3 games, got %v over %d", 2.5/3, ab.Score(), ab.Games)
+	}
+	if ba := stats.getPair("B", "A"); math.Abs(ba.Score()+ab.Score()-1) > 1e-9 {
+		t.Errorf("B v A should mirror A v B, got %v", ba.Score())
+	}
+
+	if names := stats.sortedNames(); !reflect.DeepEqual(names, []string{"A", "B", "C"}) {
+		t.Errorf("want controllers sorted by mean rank, got %v", names)
+	}
+}
+
+func TestIntervals(t *testing.T) {
+	s := &Stats{}
+	for _, rank := range []float64{1, 2, 1, 2, 1, 2} {
+		s.add(rank, 0, 0)
+	}
+	low, high := s.RankInterval(2)
+	if !(low < 1.5 && 1.5 < high) || low < 1 || high > 2 {
+		t.Errorf("rank interval [%v, %v] should contain 1.5 and stay in [1, 2]", low, high)
+	}
+
+	low, high = wilsonInterval(0, 10)
+	if low != 0 || !(high > 0 && high < 0.5) {
+		t.Errorf("no wins in 10: got [%v, %v]", low, high)
+	}
+	low, high = wilsonInterval(0.5, 100)
+	if math.Abs(low-0.4038) > 0.001 || math.Abs(high-0.5962) > 0.001 {
+		t.Errorf("half of 100: got [%v, %v], want about [0.404, 0.596]", low, high)
+	}
+}
+
+func TestTeamWins(t *testing.T) {
+	stats := NewTableStats(4)
+	// A and C's team wins
+	stats.Add([]string{"A", "B", "C", "D"}, []float64{1.5, 3.5, 1.5, 3.5}, []float64{1, 0, 1, 0}, []int{0, 1, 0, 1})
+	if a, b := stats.get("A"), stats.get("B"); a.WinRate() != 1 || b.WinRate() != 0 {
+		t.Errorf("want the whole winning team to win, got A %v, B %v", a.WinRate(), b.WinRate())
+	}
+}
+
+func TestRanksFromScores(t *testing.T) {
+	// The rank scoring gives 0, 2, 4, 6, with ties sharing the points
+	for _, tt := range []struct{ scores, ranks []float64 }{
+		{[]float64{0, 2, 4, 6}, []float64{4, 3, 2, 1}},
+		{[]float64{5, 5, 0, 2}, []float64{1.5, 1.5, 4, 3}},
+		{[]float64{120, 80, 120}, []float64{1.5, 3, 1.5}},
+	} {
+		if ranks := ranksFromScores(tt.scores); !reflect.DeepEqual(ranks, tt.ranks) {
+			t.Errorf("ranksFromScores(%v) = %v, want %v", tt.scores, ranks, tt.ranks)
+		}
+	}
+}
+
+func TestPlanRounds(t *testing.T) {
+	config := Config{Runs: 20, Seed: 5}
+	if err := config.choose("2,4", "all", "all"); err != nil {
+		t.Fatal(err)
+	}
+	rounds := planRounds(config)
+	if !reflect.DeepEqual(rounds, planRounds(config)) {
+		t.Errorf("the same seed should plan the same rounds")
+	}
+	for i, r := range rounds {
+		if len(r.names) != config.TableSizes[i%2] || len(r.arena.Spawns) < len(r.names) {
+			t.Errorf("round %d: %d players on map %s", i, len(r.names), r.arena.Name)
+		}
+		seen := map[string]bool{}
+		for _, name := range r.names {
+			if seen[name] {
+				t.Errorf("round %d: %s plays twice", i, name)
+			}
+			seen[name] = true
+		}
+	}
+
+	if err := config.choose("4", "WallHugger,AreaController", "all"); err == nil {
+		t.Errorf("expected an error with too few controllers for the table")
+	}
+	if err := config.choose("2", "NoSuchController,WallHugger", "all"); err == nil {
+		t.Errorf("expected an error for an unknown controller")
+	}
+	config.Teams = true
+	if err := config.choose("2,4", "all", "all"); err == nil {
+		t.Errorf("expected an error for 2-player tables in teams")
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+	config := Config{Runs: 6, Seed: 1, Parallel: 3}
+	if err := config.choose("2,3", "RandomAvoiding,WallHugger,RandomTurner_0.1", core.GetMap(0).Name); err != nil {
+		t.Fatal(err)
+	}
+	report := RunBenchmark(config)
+	if len(report.Tables) != 2 || report.Tables[0].Rounds != 3 || report.Tables[1].Rounds != 3 {
+		t.Fatalf("want 3 rounds at each table size, got %+v", report.Tables)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var summaries []TableSummary
+	if err := json.Unmarshal(buf.Bytes(), &summaries); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(summaries) != 2 || summaries[1].Players != 3 || len(summaries[1].Controllers) != 3 {
+		t.Errorf("unexpected JSON summary: %+v", summaries)
+	}
+
+	buf.Reset()
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.HasPrefix(lines[0], "players,scoring,teams,controller,opponent") {
+		t.Errorf("missing CSV header: %q", lines[0])
+	}
+
+	buf.Reset()
+	report.WriteTable(&buf)
+	if !strings.Contains(buf.String(), "2-player tables: 3 rounds, rank scoring") {
+		t.Errorf("table output missing its heading:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,344 @@
+// Command trainer tunes the weights of core.WeightedController by
+// evolution, without the game window.
+//
+// Each generation, every genome in the population plays rounds on the
+// built-in maps against other genomes from the population, and any
+// controllers named with -opponents. A genome's fitness is the average
+// share of the rank points it won. The fittest genomes are kept, and the
+// rest of the next generation is bred from them by crossing and mutating
+// their weights.
+//
+// Every generation is saved to a JSON checkpoint in the output directory,
+// best genome first, and best.json always holds the latest. A checkpoint
+// can be used to carry on training with -resume, played against in the
+// benchmark with -genome, or played against in the game with -genome.
+//
+// Usage:
+//
+//	go run ./cmd/trainer -generations 30 -population 24 -opponents AreaController
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonathanacross/gamedev/gocycle/core"
+)
+
+// Config says how the trainer evolves the genomes.
+type Config struct {
+	Generations int
+	Population  int
+	// Rounds each genome plays per generation
+	Games int
+	// Table sizes to play at, chosen in turn
+	TableSizes []int
+	// Controllers, by name, that take some of the seats at each table
+	Opponents []string
+	// Genomes kept unchanged from one generation to the next
+	Elite int
+	// Standard deviation of the noise added to each weight by a mutation
+	Sigma    float64
+	Seed     uint64
+	Parallel int
+	// Directory the checkpoints are saved in
+	OutDir string
+}
+
+// game is one round played to judge the genomes. Seat i is played by
+// genome genomes[i], or by the controller opponents[i] if genomes[i] is -1.
+type game struct {
+	genomes   []int
+	opponents []string
+	arena     *core.Map
+	seed      uint64
+}
+
+func main() {
+	generations := flag.Int("generations", 20, "Number of generations to evolve.")
+	population := flag.Int("population", 16, "Number of genomes in each generation.")
+	games := flag.Int("games", 12, "Rounds each genome plays per generation.")
+	players := flag.String("players", "2,4", "Comma-separated table sizes to play, each 2, 3 or 4.")
+	opponents := flag.String("opponents", "", "Comma-separated controllers to also play against: "+strings.Join(core.ControllerNames(), ", "))
+	elite := flag.Int("elite", 4, "Number of the best genomes kept as they are each generation.")
+	sigma := flag.Float64("sigma", 0.2, "Size of the random changes made to the weights.")
+	seed := flag.Uint64("seed", uint64(time.Now().UnixNano()), "Seed for the random choices.")
+	parallel := flag.Int("parallel", runtime.NumCPU(), "Number of rounds to play at once.")
+	outDir := flag.String("out", "genomes", "Directory to save the checkpoints in.")
+	resume := flag.String("resume", "", "Carry on from the genomes in this checkpoint.")
+	flag.Parse()
+
+	config := Config{
+		Generations: *generations,
+		Population:  *population,
+		Games:       *games,
+		Elite:       *elite,
+		Sigma:       *sigma,
+		Seed:        *seed,
+		Parallel:    *parallel,
+		OutDir:      *outDir,
+	}
+	if err := config.choose(*players, *opponents); err != nil {
+		exit(err)
+	}
+
+	start := []core.Genome{core.DefaultGenome}
+	firstGeneration := 0
+	if *resume != "" {
+		checkpoint, err := core.LoadCheckpoint(*resume)
+		if err != nil {
+			exit(err)
+		}
+		start = nil
+		for _, g := range checkpoint.Population {
+			start = append(start, g.Genome)
+		}
+		firstGeneration = checkpoint.Generation + 1
+	}
+	if err := os.MkdirAll(config.OutDir, 0o755); err != nil {
+		exit(err)
+	}
+
+	err := Train(config, start, firstGeneration, func(c *core.Checkpoint) error {
+		best := c.Population[0]
+		fmt.Fprintf(os.Stderr, "generation %d: best fitness %.3f %+v\n", c.Generation, best.Fitness, best.Genome)
+		path := filepath.Join(config.OutDir, fmt.Sprintf("gen%04d.json", c.Generation))
+		if err := c.Save(path); err != nil {
+			return err
+		}
+		return c.Save(filepath.Join(config.OutDir, "best.json"))
+	})
+	if err != nil {
+		exit(err)
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(1)
+}
+
+// choose fills in the table sizes and opponents from the comma-separated
+// lists given on the command line.
+func (c *Config) choose(players, opponents string) error {
+	if c.Population < 2 || c.Games <= 0 || c.Generations <= 0 {
+		return fmt.Errorf("need at least 2 genomes, and a positive number of games and generations")
+	}
+	if c.Elite < 1 || c.Elite > c.Population {
+		return fmt.Errorf("-elite must be between 1 and the population")
+	}
+	c.Opponents = core.SplitList(opponents)
+	for _, name := range c.Opponents {
+		if _, err := core.NewController(name); err != nil {
+			return err
+		}
+	}
+	c.TableSizes = nil
+	for _, s := range core.SplitList(players) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 2 || n > 4 {
+			return fmt.Errorf("invalid table size %q", s)
+		}
+		if n > c.Population {
+			return fmt.Errorf("%d-player tables need a population of at least %d", n, n)
+		}
+		if len(core.MapsForPlayers(n)) == 0 {
+			return fmt.Errorf("no maps with room for %d players", n)
+		}
+		c.TableSizes = append(c.TableSizes, n)
+	}
+	if len(c.TableSizes) == 0 {
+		return fmt.Errorf("no table sizes given")
+	}
+	return nil
+}
+
+// Train evolves the population, starting from the given genomes, for
+// config.Generations generations, calling save with each generation once
+// it has been judged.
+func Train(config Config, start []core.Genome, firstGeneration int, save func(*core.Checkpoint) error) error {
+	rng := core.NewRand(config.Seed)
+	population := fillPopulation(start, config, rng)
+	for gen := firstGeneration; gen < firstGeneration+config.Generations; gen++ {
+		scored := judge(population, config, rng)
+		if err := save(&core.Checkpoint{Generation: gen, Seed: config.Seed, Population: scored}); err != nil {
+			return err
+		}
+		population = breed(scored, config, rng)
+	}
+	return nil
+}
+
+// fillPopulation makes a full population from the starting genomes, adding
+// mutated copies of them if there are too few.
+func fillPopulation(start []core.Genome, config Config, rng *rand.Rand) []core.Genome {
+	population := append([]core.Genome{}, start[:min(len(start), config.Population)]...)
+	for i := 0; len(population) < config.Population; i++ {
+		population = append(population, mutate(start[i%len(start)], config.Sigma, rng))
+	}
+	return population
+}
+
+// judge plays the generation's rounds and returns the genomes with their
+// fitness, best first.
+func judge(population []core.Genome, config Config, rng *rand.Rand) []core.ScoredGenome {
+	games := planGames(len(population), config, rng)
+	results := playGames(games, population, config)
+
+	points := make([]float64, len(population))
+	played := make([]int, len(population))
+	for i, g := range games {
+		for seat, genome := range g.genomes {
+			if genome >= 0 {
+				points[genome] += results[i][seat]
+				played[genome]++
+			}
+		}
+	}
+
+	scored := make([]core.ScoredGenome, len(population))
+	for i, genome := range population {
+		scored[i].Genome = genome
+		if played[i] > 0 {
+			scored[i].Fitness = points[i] / float64(played[i])
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Fitness > scored[j].Fitness
+	})
+	return scored
+}
+
+// planGames seats every genome at config.Games tables, filling the other
+// seats with the opponents and other genomes.
+func planGames(numGenomes int, config Config, rng *rand.Rand) []game {
+	games := []game{}
+	for genome := range numGenomes {
+		for k := range config.Games {
+			n := config.TableSizes[k%len(config.TableSizes)]
+			g := game{genomes: []int{genome}, opponents: []string{""}}
+			// Other genomes in a random order, so none plays twice
+			others := rng.Perm(numGenomes)
+			for len(g.genomes) < n {
+				if len(config.Opponents) > 0 && rng.IntN(2) == 0 {
+					g.genomes = append(g.genomes, -1)
+					g.opponents = append(g.opponents, config.Opponents[rng.IntN(len(config.Opponents))])
+					continue
+				}
+				if others[0] == genome {
+					others = others[1:]
+				}
+				g.genomes = append(g.genomes, others[0])
+				g.opponents = append(g.opponents, "")
+				others = others[1:]
+			}
+			// Starting places differ, so shuffle the seats
+			rng.Shuffle(n, func(a, b int) {
+				g.genomes[a], g.genomes[b] = g.genomes[b], g.genomes[a]
+				g.opponents[a], g.opponents[b] = g.opponents[b], g.opponents[a]
+			})
+			maps := core.MapsForPlayers(n)
+			g.arena = maps[rng.IntN(len(maps))]
+			g.seed = rng.Uint64()
+			games = append(games, g)
+		}
+	}
+	return games
+}
+
+// playGames plays the games on several goroutines. For each game it
+// returns the share of the rank points each seat won, from 0 for last
+// place to 1 for first.
+func playGames(games []game, population []core.Genome, config Config) [][]float64 {
+	results := make([][]float64, len(games))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(config.Parallel, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = playGame(games[i], population)
+			}
+		}()
+	}
+	for i := range games {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func playGame(g game, population []core.Genome) []float64 {
+	controllers := make([]core.PlayerController, len(g.genomes))
+	for seat, genome := range g.genomes {
+		if genome >= 0 {
+			controllers[seat] = &core.WeightedController{Genome: population[genome]}
+		} else {
+			// The names were checked before any games were planned
+			controllers[seat], _ = core.NewController(g.opponents[seat])
+		}
+	}
+	players := g.arena.NewPlayers(controllers)
+	scores, _ := core.SimulateScoredRound(g.arena, players, nil, g.seed, nil, core.ScoreRules{})
+	core.CloseControllers(players)
+
+	// Rank scores go from 0 for last place to 2(n-1) for first
+	shares := make([]float64, len(players))
+	for i, p := range players {
+		shares[i] = float64(scores[p.ID]) / float64(2*(len(players)-1))
+	}
+	return shares
+}
+
+// breed makes the next generation: the elite unchanged, and children of
+// parents chosen by tournament, which favours the fitter genomes.
+func breed(scored []core.ScoredGenome, config Config, rng *rand.Rand) []core.Genome {
+	next := []core.Genome{}
+	for _, g := range scored[:config.Elite] {
+		next = append(next, g.Genome)
+	}
+	for len(next) < config.Population {
+		a := tournament(scored, rng)
+		b := tournament(scored, rng)
+		next = append(next, mutate(crossover(a, b, rng), config.Sigma, rng))
+	}
+	return next
+}
+
+// tournament picks the better of two genomes chosen at random.
+func tournament(scored []core.ScoredGenome, rng *rand.Rand) core.Genome {
+	i, j := rng.IntN(len(scored)), rng.IntN(len(scored))
+	// scored is sorted best first
+	return scored[min(i, j)].Genome
+}
+
+// crossover takes each weight from one parent or the other.
+func crossover(a, b core.Genome, rng *rand.Rand) core.Genome {
+	child := a
+	from := b.Weights()
+	for i, w := range child.Weights() {
+		if rng.IntN(2) == 0 {
+			*w = *from[i]
+		}
+	}
+	return child
+}
+
+// mutate adds normally distributed noise to each weight.
+func mutate(g core.Genome, sigma float64, rng *rand.Rand) core.Genome {
+	for _, w := range g.Weights() {
+		*w += sigma * rng.NormFloat64()
+	}
+	return g
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jonathanacross/gamedev/gocycle/core"
+)
+
+func TestPlanGames(t *testing.T) {
+	config := Config{Generations: 1, Population: 4, Games: 3, Elite: 1}
+	if err := config.choose("2,3", "WallHugger"); err != nil {
+		t.Fatal(err)
+	}
+	games := planGames(4, config, core.NewRand(1))
+	if !reflect.DeepEqual(games, planGames(4, config, core.NewRand(1))) {
+		t.Errorf("the same seed should plan the same games")
+	}
+	if len(games) != 12 {
+		t.Fatalf("want 3 games for each of 4 genomes, got %d", len(games))
+	}
+	for i, g := range games {
+		n := config.TableSizes[i%3%2]
+		if len(g.genomes) != n || len(g.arena.Spawns) < n {
+			t.Errorf("game %d: %d players on map %s, want %d", i, len(g.genomes), g.arena.Name, n)
+		}
+		seen := map[int]bool{}
+		for seat, genome := range g.genomes {
+			if genome >= 0 && seen[genome] {
+				t.Errorf("game %d: genome %d plays twice", i, genome)
+			}
+			seen[genome] = true
+			if (genome < 0) != (g.opponents[seat] != "") {
+				t.Errorf("game %d: seat %d should have a genome or an opponent", i, seat)
+			}
+		}
+	}
+}
+
+func TestBreed(t *testing.T) {
+	config := Config{Population: 5, Elite: 2, Sigma: 0.1}
+	scored := []core.ScoredGenome{
+		{Genome: core.Genome{Area: 1}, Fitness: 0.9},
+		{Genome: core.Genome{FloodFill: 1}, Fitness: 0.5},
+		{Genome: core.Genome{WallDistance: 1}, Fitness: 0.1},
+	}
+	next := breed(scored, config, core.NewRand(1))
+	if len(next) != 5 || next[0] != scored[0].Genome || next[1] != scored[1].Genome {
+		t.Errorf("want the 2 best kept and 3 children, got %+v", next)
+	}
+}
+
+func TestTrain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("plays several rounds")
+	}
+	config := Config{Generations: 2, Population: 3, Games: 1, Elite: 1, Sigma: 0.2, Seed: 1, Parallel: 2}
+	if err := config.choose("2", ""); err != nil {
+		t.Fatal(err)
+	}
+	checkpoints := []*core.Checkpoint{}
+	err := Train(config, []core.Genome{core.DefaultGenome}, 5, func(c *core.Checkpoint) error {
+		checkpoints = append(checkpoints, c)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != 2 || checkpoints[0].Generation != 5 || checkpoints[1].Generation != 6 {
+		t.Fatalf("want generations 5 and 6, got %d checkpoints", len(checkpoints))
+	}
+	for _, c := range checkpoints {
+		if len(c.Population) != 3 {
+			t.Errorf("generation %d: want 3 genomes, got %d", c.Generation, len(c.Population))
+		}
+		for i := 1; i < len(c.Population); i++ {
+			if c.Population[i].Fitness > c.Population[i-1].Fitness {
+				t.Errorf("generation %d: genomes not sorted by fitness", c.Generation)
+			}
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package core
 
-import "math"
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+)
 
 type Arena struct {
 	// Grid stores the state of each square (Open, Wall, or PlayerID)
@@ -10,6 +14,48 @@ type Arena struct {
 
 	// Slice of all players, indexed by ID - 1 (e.g., PlayerID 1 is at index 0)
 	Players []*Player
+
+	// If set, a player leaving one edge of the grid comes back in on the
+	// opposite edge
+	Wrap bool
+	// Each portal's square, mapped to the square of the other end of the pair
+	Portals map[Vector]Vector
+
+	// The squares of the arena before any trails were drawn, so that
+	// speed pads come back when a trail over them is cleared
+	tiles [][]Square
+
+	// Items waiting to be picked up. Empty unless EnableItems was called.
+	Items     map[Vector]ItemKind
+	itemRules *ItemRules
+	itemRng   *rand.Rand
+
+	// Source of all random choices made by the controllers, so a round can
+	// be replayed exactly from its seed. Copies of the arena share it.
+	rng *rand.Rand
+
+	// If set, Update appends each tick's directions to it
+	replay *Replay
+}
+
+// NewRand creates a random number generator for an arena. The same seed
+// always gives the same sequence of numbers.
+func NewRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, 0))
+}
+
+// Rand returns the random number generator controllers should use. An
+// arena built without one gets a generator with seed 0.
+func (a *Arena) Rand() *rand.Rand {
+	if a.rng == nil {
+		a.rng = NewRand(0)
+	}
+	return a.rng
+}
+
+// isPassable reports whether a player can drive onto a square.
+func isPassable(square Square) bool {
+	return square == Open || square == SpeedPad
 }
 
 // isCollision checks if a given position is blocked by a Wall or an existing path.
@@ -19,20 +65,95 @@ func (a *Arena) isCollision(pos Vector) bool {
 		return true
 	}
 
-	// Check grid content collision (Wall or another player's path/head)
-	// Any value that is not Open (0) indicates a collision.
+	// Check grid content collision (Wall or another player's path/head).
+	// Portals count too: a player who gets to one is sent through it by
+	// Next, so only ends up on a portal if it comes straight out into another.
 	squareState := a.Grid[pos.Y][pos.X]
 
-	return squareState != Open
+	return !isPassable(squareState)
+}
+
+// Next returns the square a player at pos reaches by moving one step in
+// dir, wrapping around the edges if the arena wraps and going through any
+// portal on the way. The result may be outside the grid.
+func (a *Arena) Next(pos, dir Vector) Vector {
+	next := a.wrap(pos.Add(dir))
+	if exit, ok := a.Portals[next]; ok {
+		// Come out of the other end, still going the same way
+		next = a.wrap(exit.Add(dir))
+	}
+	return next
+}
+
+// wrap brings a position that has gone off the grid back in on the other
+// side, if the arena wraps.
+func (a *Arena) wrap(pos Vector) Vector {
+	if !a.Wrap {
+		return pos
+	}
+	return Vector{
+		X: (pos.X%a.Width + a.Width) % a.Width,
+		Y: (pos.Y%a.Height + a.Height) % a.Height,
+	}
+}
+
+// onGrid reports whether pos is inside the arena.
+func (a *Arena) onGrid(pos Vector) bool {
+	return pos.Y >= 0 && pos.Y < a.Height && pos.X >= 0 && pos.X < a.Width
+}
+
+// moveSquares returns the squares the player drives over if it moves in
+// dir on the next tick, ending with its new position. That is two squares
+// if it is on a speed pad or has a speed item, and one otherwise.
+func (a *Arena) moveSquares(p *Player, dir Vector) []Vector {
+	next := a.Next(p.Position, dir)
+	if !p.Boosted && p.SpeedTicks == 0 {
+		return []Vector{next}
+	}
+	return []Vector{next, a.Next(next, dir)}
 }
 
-// clearPath iterates through a player's path and sets the corresponding Grid squares back to Open.
-// This is used when a player dies to remove their trail.
+// blocks reports whether the player would crash by driving onto pos. It
+// is isCollision, except that a ghost can drive through trails.
+func (a *Arena) blocks(p *Player, pos Vector) bool {
+	if !a.isCollision(pos) {
+		return false
+	}
+	// Nobody can drive off the grid, which can happen if the map has open
+	// squares on an edge and doesn't wrap
+	if !a.onGrid(pos) {
+		return true
+	}
+	isTrail := a.Grid[pos.Y][pos.X] > 0
+	return !(p.GhostTicks > 0 && isTrail)
+}
+
+// canMove reports whether the player can move in dir on the next tick
+// without hitting a wall or trail.
+func (a *Arena) canMove(p *Player, dir Vector) bool {
+	for _, pos := range a.moveSquares(p, dir) {
+		if a.blocks(p, pos) {
+			return false
+		}
+	}
+	return true
+}
+
+// tileAt returns the square at pos before any trails were drawn.
+func (a *Arena) tileAt(pos Vector) Square {
+	if a.tiles == nil {
+		return Open
+	}
+	return a.tiles[pos.Y][pos.X]
+}
+
+// clearPath iterates through a player's path and sets the corresponding Grid squares back
+// to what they were at the start. This is used when a player dies to remove their trail.
 func (a *Arena) clearPath(p *Player) {
 	for _, pos := range p.Path {
 		// Ensure the path position is within the arena bounds before clearing
 		if pos.Y >= 0 && pos.Y < a.Height && pos.X >= 0 && pos.X < a.Width {
-			a.Grid[pos.Y][pos.X] = Open
+			a.Grid[pos.Y][pos.X] = a.tileAt(pos)
 		}
 	}
 }
@@ -57,7 +178,8 @@ func rectangleGrid(width, height int) [][]Square {
 }
 
 // NewArena is a constructor for setting up the initial grid and players.
-func NewArena(w, h int, players []*Player) *Arena {
+// All random choices during the game are drawn from rng.
+func NewArena(w, h int, players []*Player, rng *rand.Rand) *Arena {
 	grid := rectangleGrid(w, h)
 
 	// Mark player starting positions on the grid
@@ -70,16 +192,13 @@ func NewArena(w, h int, players []*Player) *Arena {
 		Width:   w,
 		Height:  h,
 		Players: players,
+		rng:     rng,
 	}
 }
 
-func NewArenaFromGrid(grid [][]Square, players []*Player) *Arena {
+func NewArenaFromGrid(grid [][]Square, players []*Player, rng *rand.Rand) *Arena {
 	// Make a copy of the grid to avoid mutating the input
-	newGrid := make([][]Square, len(grid))
-	for i := range grid {
-		newGrid[i] = make([]Square, len(grid[i]))
-		copy(newGrid[i], grid[i])
-	}
+	newGrid := copyGrid(grid)
 
 	// Mark player starting positions on the grid
 	for _, p := range players {
@@ -91,30 +210,86 @@ func NewArenaFromGrid(grid [][]Square, players []*Player) *Arena {
 		Width:   len(grid[0]),
 		Height:  len(grid),
 		Players: players,
+		tiles:   grid,
+		rng:     rng,
+	}
+}
+
+// NewArenaFromMap sets up a round on a map, with its wrapping edges and
+// portals.
+func NewArenaFromMap(m *Map, players []*Player, rng *rand.Rand) *Arena {
+	a := NewArenaFromGrid(m.Grid, players, rng)
+	a.Wrap = m.Wrap
+	a.Portals = m.Portals
+	return a
+}
+
+func copyItems(items map[Vector]ItemKind) map[Vector]ItemKind {
+	if items == nil {
+		return nil
+	}
+	newItems := make(map[Vector]ItemKind, len(items))
+	for pos, kind := range items {
+		newItems[pos] = kind
 	}
+	return newItems
 }
 
-// Update moves all living players and handles collisions.
+func copyGrid(grid [][]Square) [][]Square {
+	newGrid := make([][]Square, len(grid))
+	for i := range grid {
+		newGrid[i] = make([]Square, len(grid[i]))
+		copy(newGrid[i], grid[i])
+	}
+	return newGrid
+}
+
+// Update moves all living players and handles collisions, then maybe adds
+// an item.  Players are always handled in ID order, so the result depends
+// only on the arena and its random number generators.
 func (a *Arena) Update() {
-	// Collect all desired new positions and determine potential casualties
-	newPositions := make(map[int]Vector) // map[PlayerID]NewPosition
-	collidedIDs := make(map[int]bool)    // map[PlayerID]collided
+	tick := make([]Vector, len(a.Players))
+	a.step(func(i int, p *Player) Vector {
+		// Get next direction from the player's controller (Human/AI)
+		dir := p.Controller.GetDirection(a, p.ID)
+		tick[i] = dir
+		return dir
+	})
+	// Not part of step, so the AI's simulations don't place items
+	a.spawnItem()
+	if a.replay != nil {
+		a.replay.Ticks = append(a.replay.Ticks, tick)
+	}
+}
 
-	for _, p := range a.Players {
+// Record makes every later Update add its tick to the replay, and notes
+// in it whether items are on.
+func (a *Arena) Record(r *Replay) {
+	a.replay = r
+	r.Items = a.itemRules
+}
+
+// step moves every living player in the direction chosen for it and
+// handles collisions.  chooseDirection is given the player's index in
+// Players.  It is shared by Update and the AI's simulations.
+func (a *Arena) step(chooseDirection func(i int, p *Player) Vector) {
+	// Collect the squares each player drives over, ending with its new
+	// position, and determine potential casualties, indexed like Players
+	moves := make([][]Vector, len(a.Players))
+	collided := make([]bool, len(a.Players))
+
+	for i, p := range a.Players {
 		if !p.IsAlive {
 			continue
 		}
 
-		// Get next direction from the player's controller (Human/AI)
-		nextDir := p.Controller.GetDirection(a, p.ID)
-		p.Direction = nextDir
-		nextPos := p.Position.Add(p.Direction)
-		newPositions[p.ID] = nextPos
+		p.Direction = chooseDirection(i, p)
+		moves[i] = a.moveSquares(p, p.Direction)
 
 		// Check for collision with walls or paths
 		// This checks collision with *existing* paths/walls/heads on the grid.
-		if a.isCollision(nextPos) {
-			collidedIDs[p.ID] = true
+		if !a.canMove(p, p.Direction) {
+			collided[i] = true
 		}
 	}
 
@@ -122,59 +297,81 @@ func (a *Arena) Update() {
 
 	// Check for players attempting to move into the same square.
 	// This handles head-to-head collisions that bypass the grid check.
-	for id, pos := range newPositions {
-		if collidedIDs[id] {
+	for i, p := range a.Players {
+		if !p.IsAlive || collided[i] {
 			continue
 		}
 
 		// Check for multiple players attempting the same square
-		for otherID, otherPos := range newPositions {
-			if id == otherID {
+		for j, other := range a.Players {
+			if i == j || !other.IsAlive {
 				continue
 			}
-			if pos.Equals(otherPos) {
-				collidedIDs[id] = true
-				collidedIDs[otherID] = true
+			if sharesSquare(moves[i], moves[j]) {
+				collided[i] = true
+				collided[j] = true
 			}
 		}
 	}
 
 	// Finalize State Changes
-	playersToMove := make(map[int]*Player)
+	playersToMove := []int{} // indices into Players
 
-	for _, p := range a.Players {
+	for i, p := range a.Players {
 		if !p.IsAlive {
 			continue
 		}
 
-		if collidedIDs[p.ID] {
+		if collided[i] {
 			// Player dies and their trail is cleared *before* they update their position.
 			a.clearPath(p)
 			p.IsAlive = false
 		} else {
-			playersToMove[p.ID] = p
+			playersToMove = append(playersToMove, i)
 		}
 	}
 
-	// Only after all collisions are determined, move the survivors and draw their new path
-	for _, p := range playersToMove {
-		// Store old position (the trail segment)
-		oldPos := p.Position
+	// Only after all collisions are determined, move the survivors and draw their new path.
+	// The old position is already marked on the grid as part of the trail.
+	for _, i := range playersToMove {
+		p := a.Players[i]
+
+		// Mark the squares driven over and the *new* head position on the grid.
+		// This prevents other players from moving into a surviving player's head
+		// on subsequent turns.
+		for _, pos := range moves[i] {
+			if !isPassable(a.Grid[pos.Y][pos.X]) {
+				// A ghost going through a trail leaves it as it is
+				continue
+			}
+			a.Grid[pos.Y][pos.X] = Square(p.ID)
+			// Add the square to the Path history.
+			p.Path = append(p.Path, pos)
+		}
 
 		// Update the player's position to the new head location
-		p.Position = newPositions[p.ID]
+		p.Position = moves[i][len(moves[i])-1]
 
-		// Mark the *old* position as the permanent trail/path.
-		a.Grid[oldPos.Y][oldPos.X] = Square(p.ID)
+		// Stopping on a speed pad gives a double move on the next tick
+		p.Boosted = a.tileAt(p.Position) == SpeedPad
 
-		// Mark the *new* head position on the grid.
-		// This prevents other players from moving into a surviving player's head
-		// on subsequent turns.
-		a.Grid[p.Position.Y][p.Position.X] = Square(p.ID)
+		// Items wear off a tick at a time, and new ones are picked up
+		p.SpeedTicks = max(p.SpeedTicks-1, 0)
+		p.GhostTicks = max(p.GhostTicks-1, 0)
+		a.collectItems(p, moves[i])
+	}
+}
 
-		// Add the new head position to the Path history.
-		p.Path = append(p.Path, p.Position)
+// sharesSquare reports whether two moves drive over any of the same squares.
+func sharesSquare(move1, move2 []Vector) bool {
+	for _, pos1 := range move1 {
+		for _, pos2 := range move2 {
+			if pos1.Equals(pos2) {
+				return true
+			}
+		}
 	}
+	return false
 }
 
 // bfsQueueItem is a helper struct for the multi-source BFS.
@@ -182,10 +379,13 @@ type bfsQueueItem struct {
 	ID   int
 	Pos  Vector
 	Dist int
+	// Whether the next move from this square goes two squares
+	Boosted bool
 }
 
 // findClosestAssignments uses a multi-source BFS (Voronoi partitioning) to determine
-// which player controls which square based on Manhattan distance.
+// which player controls which square based on the number of ticks needed to
+// reach it, following wrapping edges, portals and speed pads.
 func (a *Arena) findClosestAssignments() ([][]int, [][]int) {
 	// Initialize the distance and assignment grids
 	distanceGrid := make([][]int, a.Height)
@@ -211,7 +411,32 @@ func (a *Arena) findClosestAssignments() ([][]int, [][]int) {
 		distanceGrid[pos.Y][pos.X] = 0
 		assignmentGrid[pos.Y][pos.X] = p.ID
 
-		queue = append(queue, bfsQueueItem{ID: p.ID, Pos: pos, Dist: 0})
+		queue = append(queue, bfsQueueItem{ID: p.ID, Pos: pos, Dist: 0, Boosted: p.Boosted || p.SpeedTicks > 0})
+	}
+
+	// visit records that a player can reach a square in dist ticks, and
+	// reports whether the square can be driven onto.
+	visit := func(current bfsQueueItem, pos Vector, dist int) bool {
+		// Do not traverse into walls or existing paths (or off the grid).
+		if a.isCollision(pos) {
+			return false
+		}
+		x, y := pos.X, pos.Y
+
+		// --- Distance Comparison
+		if dist < distanceGrid[y][x] {
+			// Found a shorter path (new controlling player)
+			distanceGrid[y][x] = dist
+			assignmentGrid[y][x] = current.ID
+			queue = append(queue, bfsQueueItem{ID: current.ID, Pos: pos, Dist: dist,
+				Boosted: a.Grid[y][x] == SpeedPad})
+		} else if dist == distanceGrid[y][x] {
+			// Found an equally short path (Neutral zone)
+			if assignmentGrid[y][x] != current.ID {
+				assignmentGrid[y][x] = 0 // Mark as neutral tie zone
+			}
+		}
+		return true
 	}
 
 	// Run the BFS
@@ -222,30 +447,12 @@ func (a *Arena) findClosestAssignments() ([][]int, [][]int) {
 		nextDist := current.Dist + 1
 
 		for _, dir := range []Vector{Up, Down, Left, Right} {
-			nextPos := current.Pos.Add(dir)
-			x, y := nextPos.X, nextPos.Y
-
-			// Check bounds
-			if y < 0 || y >= a.Height || x < 0 || x >= a.Width {
-				continue
-			}
-
-			// Do not traverse into walls or existing paths.
-			if a.Grid[y][x] != Open {
-				continue
-			}
-
-			// --- Distance Comparison
-			if nextDist < distanceGrid[y][x] {
-				// Found a shorter path (new controlling player)
-				distanceGrid[y][x] = nextDist
-				assignmentGrid[y][x] = current.ID
-				queue = append(queue, bfsQueueItem{ID: current.ID, Pos: nextPos, Dist: nextDist})
-			} else if nextDist == distanceGrid[y][x] {
-				// Found an equally short path (Neutral zone)
-				if assignmentGrid[y][x] != current.ID {
-					assignmentGrid[y][x] = 0 // Mark as neutral tie zone
-				}
+			nextPos := a.Next(current.Pos, dir)
+			if visit(current, nextPos, nextDist) && current.Boosted {
+				// A boosted move reaches the square after as well, in the
+				// same tick. (The player can't actually stop on the first
+				// square, but counting it as reached is close enough.)
+				visit(current, a.Next(nextPos, dir), nextDist)
 			}
 		}
 	}
@@ -287,11 +494,7 @@ func (a *Arena) ComputePlayerScores() map[int]int {
 // DeepCopy creates a complete copy of the Arena, including deep copies of the Grid and Players.
 func (a *Arena) DeepCopy() *Arena {
 	// 1. Copy the Grid (deep copy)
-	newGrid := make([][]Square, a.Height)
-	for y := range a.Height {
-		newGrid[y] = make([]Square, a.Width)
-		copy(newGrid[y], a.Grid[y])
-	}
+	newGrid := copyGrid(a.Grid)
 
 	// Copy the Players (deep copy)
 	newPlayers := make([]*Player, len(a.Players))
@@ -301,6 +504,7 @@ func (a *Arena) DeepCopy() *Arena {
 		// Deep copy the Path slice
 		newP.Path = make([]Vector, len(p.Path))
 		copy(newP.Path, p.Path)
+		newP.Collected = slices.Clone(p.Collected)
 
 		// The controller field cannot be deep-copied cleanly, but for a search
 		// where we only care about its *state* (IsAlive, Position),
@@ -310,10 +514,18 @@ func (a *Arena) DeepCopy() *Arena {
 		newPlayers[i] = &newP
 	}
 
+	// The tiles and portals never change, so they can be shared
 	return &Arena{
-		Grid:    newGrid,
-		Width:   a.Width,
-		Height:  a.Height,
-		Players: newPlayers,
+		Grid:      newGrid,
+		Width:     a.Width,
+		Height:    a.Height,
+		Players:   newPlayers,
+		Wrap:      a.Wrap,
+		Portals:   a.Portals,
+		tiles:     a.tiles,
+		Items:     copyItems(a.Items),
+		itemRules: a.itemRules,
+		itemRng:   a.itemRng,
+		rng:       a.rng,
 	}
 }
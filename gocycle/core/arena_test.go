@@ -60,7 +60,7 @@ func TestNewArena(t *testing.T) {
 	w, h := 10, 8
 	p1 := NewPlayer(1, Vector{X: 5, Y: 5}, Right, &mockController{})
 
-	arena := NewArena(w, h, []*Player{p1})
+	arena := NewArena(w, h, []*Player{p1}, NewRand(1))
 
 	if arena.Width != w || arena.Height != h {
 		t.Errorf("NewArena dimensions incorrect. Got W=%d, H=%d, want W=%d, H=%d", arena.Width, arena.Height, w, h)
@@ -248,6 +248,25 @@ func TestUpdatePathCollision(t *testing.T) {
 	}
 }
 
+func TestUpdateOffOpenEdge(t *testing.T) {
+	// A map that doesn't wrap may have open squares on its edge
+	for _, ghost := range []bool{false, true} {
+		arena := newMapArena(t, "gocycle map\ngrid\n.....\n..>..\n.....\n", &forcedController{Dir: Right})
+		p := arena.Players[0]
+		if ghost {
+			p.GhostTicks = 10
+		}
+
+		arena.Update()
+		arena.Update()
+		arena.Update() // Off the edge
+
+		if p.IsAlive {
+			t.Errorf("ghost %v: expected player to crash off the edge of the map", ghost)
+		}
+	}
+}
+
 // --- Voronoi Scoring Algorithm Tests ---
 
 func TestFindClosestAssignments(t *testing.T) {
@@ -352,3 +371,33 @@ func TestComputePlayerScores(t *testing.T) {
 		t.Errorf("ComputePlayerScores integration failed (Symmetry case).\nGot: %v\nWant: %v", scores, expectedScores)
 	}
 }
+
+// --- Simulation Tests ---
+
+// newRandomPlayers creates players whose controllers all make random choices.
+func newRandomPlayers() []*Player {
+	return GetMap(0).NewPlayers([]PlayerController{
+		&RandomAvoidingController{},
+		&RandomTurnerController{TurnProb: 0.1},
+		&RandomTurnerController{TurnProb: 0.3},
+		&WallHuggerController{},
+	})
+}
+
+func TestSimulateRoundIsReproducible(t *testing.T) {
+	for seed := uint64(1); seed <= 5; seed++ {
+		players1 := newRandomPlayers()
+		scores1 := SimulateRound(GetMap(0).Grid, players1, seed)
+		players2 := newRandomPlayers()
+		scores2 := SimulateRound(GetMap(0).Grid, players2, seed)
+
+		if !reflect.DeepEqual(scores1, scores2) {
+			t.Errorf("seed %d: scores differ between runs: %v vs %v", seed, scores1, scores2)
+		}
+		for i := range players1 {
+			if !reflect.DeepEqual(players1[i].Path, players2[i].Path) {
+				t.Errorf("seed %d: player %d took a different path", seed, players1[i].ID)
+			}
+		}
+	}
+}
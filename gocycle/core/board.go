@@ -0,0 +1,637 @@
+package core
+
+// board.go contains Board, a compact copy of an arena for the searches to
+// play moves on. Squares are numbered y*Width+x, which squares are blocked
+// is kept in bitsets, and the square a step leads to in each direction is
+// worked out once, with the wrapping edges and portals, when the board is
+// made. A tick is made and taken back in place, and the Voronoi
+// partitioning reuses its buffers, so searching a position allocates
+// nothing.
+//
+// Board follows the rules of Arena.step, apart from one thing: a bomb is
+// picked up, but its explosion isn't played out.
+
+// Directions by index, in the order the controllers try them. The opposite
+// of direction d is d^1.
+var boardDirs = [4]Vector{Up, Down, Left, Right}
+
+const noSquare = -1
+
+// bitset is a set of squares.
+type bitset []uint64
+
+func newBitset(size int) bitset {
+	return make(bitset, (size+63)/64)
+}
+
+func (b bitset) has(sq int) bool {
+	return b[sq>>6]&(1<<(sq&63)) != 0
+}
+
+func (b bitset) set(sq int) {
+	b[sq>>6] |= 1 << (sq & 63)
+}
+
+func (b bitset) clear(sq int) {
+	b[sq>>6] &^= 1 << (sq & 63)
+}
+
+// boardPlayer is a player's state on a Board.
+type boardPlayer struct {
+	square  int
+	dir     int
+	alive   bool
+	boosted bool
+	// ticks left of the speed and ghost items
+	speedTicks int
+	ghostTicks int
+	// value of the items picked up, as counted by ComputePlayerValues
+	collected int
+	// length of the player's trail in paths
+	pathLen int
+}
+
+type boardItem struct {
+	square int
+	kind   ItemKind
+	taken  bool
+}
+
+// boardChange is something a tick did to the board, to be undone.
+type boardChange struct {
+	kind  int
+	index int
+}
+
+const (
+	// a square was added to a trail
+	markedSquare = iota
+	// a player crashed and its trail was cleared
+	clearedTrail
+	// an item was picked up
+	tookItem
+)
+
+// boardTick marks where a tick's saved players and changes start.
+type boardTick struct {
+	players int
+	changes int
+}
+
+type Board struct {
+	Width  int
+	Height int
+
+	// next[sq*4+d] is the square reached by one step from sq in direction
+	// d, or noSquare if that is off the grid
+	next []int
+	// walls, portals and trails
+	blocked bitset
+	// walls and portals, which are never cleared
+	fixed bitset
+	pads  bitset
+
+	players []boardPlayer
+	// squares of each player's trail, in the order they were driven over
+	paths     [][]int
+	items     []boardItem
+	itemRules ItemRules
+
+	// what is needed to take back each tick
+	ticks   []boardTick
+	saved   []boardPlayer
+	changes []boardChange
+
+	// buffers for step
+	moves    [][2]int
+	numMoves []int
+	collided []bool
+
+	// buffers for Values. A square has been reached in the current search
+	// if its cell's stamp is stamp, so nothing has to be cleared between
+	// searches.
+	cells  []voronoiCell
+	stamp  uint32
+	queue  []boardQueueItem
+	values []int
+}
+
+// voronoiCell is what the Voronoi partitioning knows about a square.
+type voronoiCell struct {
+	stamp uint32
+	dist  int32
+	// index of the player who owns the square, plus 1, or 0 for no one
+	owner int32
+}
+
+type boardQueueItem struct {
+	square int32
+	// index of the player whose search reached the square, plus 1
+	owner   int32
+	dist    int32
+	boosted bool
+}
+
+// NewBoard copies the arena onto a board. Every player's direction must be
+// one of Up, Down, Left or Right.
+func NewBoard(a *Arena) *Board {
+	size := a.Width * a.Height
+	b := &Board{
+		Width:    a.Width,
+		Height:   a.Height,
+		next:     make([]int, size*4),
+		blocked:  newBitset(size),
+		fixed:    newBitset(size),
+		pads:     newBitset(size),
+		moves:    make([][2]int, len(a.Players)),
+		numMoves: make([]int, len(a.Players)),
+		collided: make([]bool, len(a.Players)),
+		cells:    make([]voronoiCell, size),
+		values:   make([]int, len(a.Players)),
+	}
+	if a.itemRules != nil {
+		b.itemRules = *a.itemRules
+	}
+
+	for y := range a.Height {
+		for x := range a.Width {
+			pos := Vector{X: x, Y: y}
+			sq := b.square(pos)
+			if a.isCollision(pos) {
+				b.blocked.set(sq)
+				if a.Grid[y][x] <= 0 {
+					b.fixed.set(sq)
+				}
+			}
+			if a.tileAt(pos) == SpeedPad {
+				b.pads.set(sq)
+			}
+			for d, dir := range boardDirs {
+				b.next[sq*4+d] = b.square(a.Next(pos, dir))
+			}
+		}
+	}
+
+	for _, p := range a.Players {
+		bp := boardPlayer{
+			square:     b.square(p.Position),
+			dir:        dirIndex(p.Direction),
+			alive:      p.IsAlive,
+			boosted:    p.Boosted,
+			speedTicks: p.SpeedTicks,
+			ghostTicks: p.GhostTicks,
+		}
+		for _, kind := range p.Collected {
+			bp.collected += ItemValues[kind]
+		}
+		path := []int{}
+		if p.IsAlive {
+			// Only a living player's trail is ever cleared
+			for _, pos := range p.Path {
+				path = append(path, b.square(pos))
+			}
+		}
+		bp.pathLen = len(path)
+		b.players = append(b.players, bp)
+		b.paths = append(b.paths, path)
+	}
+
+	for y := range a.Height {
+		for x := range a.Width {
+			pos := Vector{X: x, Y: y}
+			if kind, ok := a.Items[pos]; ok {
+				b.items = append(b.items, boardItem{square: b.square(pos), kind: kind})
+			}
+		}
+	}
+	return b
+}
+
+// square returns the number of the square at pos, or noSquare if pos is
+// off the grid.
+func (b *Board) square(pos Vector) int {
+	if pos.X < 0 || pos.X >= b.Width || pos.Y < 0 || pos.Y >= b.Height {
+		return noSquare
+	}
+	return pos.Y*b.Width + pos.X
+}
+
+func dirIndex(dir Vector) int {
+	for d, v := range boardDirs {
+		if v == dir {
+			return d
+		}
+	}
+	panic("not a direction")
+}
+
+// IsAlive reports whether the player with index i in Players is alive.
+func (b *Board) IsAlive(i int) bool {
+	return b.players[i].alive
+}
+
+// Position returns where the player with index i is.
+func (b *Board) Position(i int) Vector {
+	sq := b.players[i].square
+	return Vector{X: sq % b.Width, Y: sq / b.Width}
+}
+
+// Direction returns the direction the player with index i last moved in.
+func (b *Board) Direction(i int) Vector {
+	return boardDirs[b.players[i].dir]
+}
+
+// IsBlocked reports whether a player (other than a ghost) would crash by
+// driving onto pos.
+func (b *Board) IsBlocked(pos Vector) bool {
+	sq := b.square(pos)
+	return sq == noSquare || b.blocked.has(sq)
+}
+
+func (b *Board) numAlive() int {
+	n := 0
+	for _, p := range b.players {
+		if p.alive {
+			n++
+		}
+	}
+	return n
+}
+
+// moveSquares fills squares with the squares player i drives over if it
+// moves in direction d, as Arena.moveSquares does, and returns how many
+// there are.
+func (b *Board) moveSquares(i, d int, squares *[2]int) int {
+	p := &b.players[i]
+	squares[0] = b.step(p.square, d)
+	if !p.boosted && p.speedTicks == 0 {
+		return 1
+	}
+	squares[1] = b.step(squares[0], d)
+	return 2
+}
+
+// step returns the square one step from sq in direction d.
+func (b *Board) step(sq, d int) int {
+	if sq == noSquare {
+		return noSquare
+	}
+	return b.next[sq*4+d]
+}
+
+// blocks reports whether player i would crash by driving onto sq.
+func (b *Board) blocks(i, sq int) bool {
+	if sq == noSquare {
+		return true
+	}
+	if !b.blocked.has(sq) {
+		return false
+	}
+	isTrail := !b.fixed.has(sq)
+	return !(b.players[i].ghostTicks > 0 && isTrail)
+}
+
+// canMove reports whether player i can move in direction d without
+// hitting a wall or trail.
+func (b *Board) canMove(i, d int) bool {
+	var squares [2]int
+	n := b.moveSquares(i, d, &squares)
+	for _, sq := range squares[:n] {
+		if b.blocks(i, sq) {
+			return false
+		}
+	}
+	return true
+}
+
+// mightCollide is isPossiblePlayerCollision: it reports whether moving
+// player i in direction d might run into another player moving at the
+// same time.
+func (b *Board) mightCollide(i, d int) bool {
+	var mine, theirs [2]int
+	n := b.moveSquares(i, d, &mine)
+	for j, other := range b.players {
+		if j == i || !other.alive {
+			continue
+		}
+		for od := range boardDirs {
+			m := b.moveSquares(j, od, &theirs)
+			if sharesBoardSquare(mine[:n], theirs[:m]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sharesBoardSquare is sharesSquare for squares on a board. Squares off
+// the grid are never shared, which is only different from sharesSquare
+// for moves that crash anyway.
+func sharesBoardSquare(move1, move2 []int) bool {
+	for _, sq1 := range move1 {
+		for _, sq2 := range move2 {
+			if sq1 == sq2 && sq1 != noSquare {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Move plays a tick, as Arena.step does, with each living player moving
+// in the direction given for its index. It can be taken back with Undo.
+func (b *Board) Move(dirs []Vector) {
+	indices := make([]int, len(dirs))
+	for i, dir := range dirs {
+		if b.players[i].alive {
+			indices[i] = dirIndex(dir)
+		}
+	}
+	b.move(indices)
+}
+
+// move is Move with the directions given by index.
+func (b *Board) move(dirs []int) {
+	b.ticks = append(b.ticks, boardTick{players: len(b.saved), changes: len(b.changes)})
+	b.saved = append(b.saved, b.players...)
+
+	for i := range b.players {
+		p := &b.players[i]
+		b.collided[i] = false
+		if !p.alive {
+			continue
+		}
+		p.dir = dirs[i]
+		b.numMoves[i] = b.moveSquares(i, p.dir, &b.moves[i])
+		b.collided[i] = !b.canMove(i, p.dir)
+	}
+
+	// Head-on collisions
+	for i, p := range b.players {
+		if !p.alive || b.collided[i] {
+			continue
+		}
+		for j, other := range b.players {
+			if i == j || !other.alive {
+				continue
+			}
+			if sharesBoardSquare(b.moves[i][:b.numMoves[i]], b.moves[j][:b.numMoves[j]]) {
+				b.collided[i] = true
+				b.collided[j] = true
+			}
+		}
+	}
+
+	for i := range b.players {
+		p := &b.players[i]
+		if !p.alive || !b.collided[i] {
+			continue
+		}
+		for _, sq := range b.paths[i][:p.pathLen] {
+			b.blocked.clear(sq)
+		}
+		p.alive = false
+		b.changes = append(b.changes, boardChange{kind: clearedTrail, index: i})
+	}
+
+	for i := range b.players {
+		p := &b.players[i]
+		if !p.alive {
+			continue
+		}
+		squares := b.moves[i][:b.numMoves[i]]
+		for _, sq := range squares {
+			if b.blocked.has(sq) {
+				// A ghost going through a trail leaves it as it is
+				continue
+			}
+			b.blocked.set(sq)
+			b.changes = append(b.changes, boardChange{kind: markedSquare, index: sq})
+			b.paths[i] = append(b.paths[i][:p.pathLen], sq)
+			p.pathLen++
+		}
+		p.square = squares[len(squares)-1]
+		p.boosted = b.pads.has(p.square)
+		p.speedTicks = max(p.speedTicks-1, 0)
+		p.ghostTicks = max(p.ghostTicks-1, 0)
+		b.collectItems(i, squares)
+	}
+}
+
+// collectItems is Arena.collectItems, except that bombs don't go off.
+func (b *Board) collectItems(i int, squares []int) {
+	p := &b.players[i]
+	for _, sq := range squares {
+		for k := range b.items {
+			item := &b.items[k]
+			if item.taken || item.square != sq {
+				continue
+			}
+			item.taken = true
+			b.changes = append(b.changes, boardChange{kind: tookItem, index: k})
+			p.collected += ItemValues[item.kind]
+			switch item.kind {
+			case SpeedItem:
+				p.speedTicks = b.itemRules.SpeedTicks
+			case GhostItem:
+				p.ghostTicks = b.itemRules.GhostTicks
+			}
+		}
+	}
+}
+
+// Undo takes back the last tick played by Move.
+func (b *Board) Undo() {
+	tick := b.ticks[len(b.ticks)-1]
+	b.ticks = b.ticks[:len(b.ticks)-1]
+
+	copy(b.players, b.saved[tick.players:])
+	b.saved = b.saved[:tick.players]
+	for k := len(b.changes) - 1; k >= tick.changes; k-- {
+		c := b.changes[k]
+		switch c.kind {
+		case markedSquare:
+			b.blocked.clear(c.index)
+		case clearedTrail:
+			// The player's saved trail length is already back
+			for _, sq := range b.paths[c.index][:b.players[c.index].pathLen] {
+				b.blocked.set(sq)
+			}
+		case tookItem:
+			b.items[c.index].taken = false
+		}
+	}
+	b.changes = b.changes[:tick.changes]
+}
+
+// Values works out the same values as ComputePlayerValues, indexed by
+// player index rather than ID. The result is only good until the next
+// call.
+func (b *Board) Values() []int {
+	b.stamp++
+	if b.stamp == 0 {
+		// The stamps have wrapped around, so old ones could match
+		clear(b.cells)
+		b.stamp = 1
+	}
+	stamp, cells, next, blocked := b.stamp, b.cells, b.next, b.blocked
+
+	queue := b.queue[:0]
+	for i, p := range b.players {
+		if !p.alive {
+			continue
+		}
+		cells[p.square] = voronoiCell{stamp: stamp, owner: int32(i + 1)}
+		queue = append(queue, boardQueueItem{square: int32(p.square), owner: int32(i + 1), boosted: p.boosted || p.speedTicks > 0})
+	}
+
+	// The BFS of findClosestAssignments. Squares come off the queue in
+	// order of distance, so the first player to reach a square is always
+	// one of the closest.
+	for head := 0; head < len(queue); head++ {
+		current := queue[head]
+		dist := current.dist + 1
+		for d := range boardDirs {
+			sq := next[int(current.square)*4+d]
+			for steps := 1; ; steps++ {
+				if sq == noSquare || blocked.has(sq) {
+					break
+				}
+				c := &cells[sq]
+				if c.stamp != stamp {
+					*c = voronoiCell{stamp: stamp, dist: dist, owner: current.owner}
+					queue = append(queue, boardQueueItem{square: int32(sq), owner: current.owner, dist: dist, boosted: b.pads.has(sq)})
+				} else if c.dist == dist && c.owner != current.owner {
+					// Reached as soon by someone else, so no one's
+					c.owner = 0
+				}
+				// A boosted move reaches the square after as well
+				if !current.boosted || steps == 2 {
+					break
+				}
+				sq = next[sq*4+d]
+			}
+		}
+	}
+	b.queue = queue
+
+	values := b.values
+	for i, p := range b.players {
+		values[i] = p.collected
+	}
+	// Every square reached is in the queue once
+	for _, item := range queue {
+		if owner := cells[item.square].owner; owner > 0 {
+			values[owner-1]++
+		}
+	}
+	for _, item := range b.items {
+		if c := cells[item.square]; !item.taken && c.stamp == stamp && c.owner > 0 {
+			values[c.owner-1] += ItemValues[item.kind] / 2
+		}
+	}
+	return values
+}
+
+// FastMinimaxController makes the same choices as MinimaxAreaController,
+// apart from where a bomb would go off, but searches on a Board, which is
+// much faster, so it can afford to look further ahead.
+type FastMinimaxController struct {
+	MaxDepth int
+
+	// positions searched, for the benchmarks
+	nodes int
+}
+
+func (fc *FastMinimaxController) GetDirection(arena *Arena, playerID int) Vector {
+	depth := max(fc.MaxDepth, 1)
+	b := NewBoard(arena)
+	s := &boardSearch{board: b, me: playerID - 1}
+	// A buffer of directions for each level of the search
+	for range depth + 1 {
+		s.dirs = append(s.dirs, make([]int, len(arena.Players)))
+	}
+	_, bestDir := s.maxN(depth)
+	fc.nodes += s.nodes
+	return boardDirs[bestDir]
+}
+
+type boardSearch struct {
+	board *Board
+	// index of the player choosing a move
+	me    int
+	dirs  [][]int
+	nodes int
+}
+
+// maxN is MinimaxAreaController.maxNSearch on a board. It returns the best
+// value the player can get and the direction to get it.
+func (s *boardSearch) maxN(depth int) (int, int) {
+	s.nodes++
+	b := s.board
+	player := &b.players[s.me]
+	if depth == 0 || b.numAlive() <= 1 || !player.alive {
+		return b.Values()[s.me], player.dir
+	}
+
+	var safe [4]int
+	numSafe := 0
+	for d := range boardDirs {
+		if d != player.dir^1 && b.canMove(s.me, d) && !b.mightCollide(s.me, d) {
+			safe[numSafe] = d
+			numSafe++
+		}
+	}
+	if numSafe == 0 {
+		return b.Values()[s.me], player.dir
+	}
+
+	// Every opponent makes its best move one tick ahead, as AreaController
+	dirs := s.dirs[depth]
+	for i, p := range b.players {
+		if i != s.me && p.alive {
+			dirs[i] = s.greedyMove(i)
+		}
+	}
+
+	maxValue, bestDir := -1, player.dir
+	for _, d := range safe[:numSafe] {
+		dirs[s.me] = d
+		b.move(dirs)
+		value := 0
+		if b.players[s.me].alive {
+			value, _ = s.maxN(depth - 1)
+		}
+		b.Undo()
+		if value > maxValue {
+			maxValue, bestDir = value, d
+		}
+	}
+	return maxValue, bestDir
+}
+
+// greedyMove is AreaController.GetDirection on a board, for player i.
+func (s *boardSearch) greedyMove(i int) int {
+	b := s.board
+	p := &b.players[i]
+	bestDir, maxValue := p.dir, -1
+	square, boosted := p.square, p.boosted
+
+	var squares [2]int
+	for d := range boardDirs {
+		if d == p.dir^1 || !b.canMove(i, d) || b.mightCollide(i, d) {
+			continue
+		}
+		// Just move the head; the squares driven over aren't marked
+		n := b.moveSquares(i, d, &squares)
+		p.square = squares[n-1]
+		p.boosted = b.pads.has(p.square)
+		value := b.Values()[i]
+		p.square, p.boosted = square, boosted
+
+		if value > maxValue {
+			maxValue, bestDir = value, d
+		}
+	}
+	return bestDir
+}
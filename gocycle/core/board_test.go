@@ -0,0 +1,186 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// boardTestMap wraps, and has speed pads and portals.
+const boardTestMap = "gocycle map\nwrap\ngrid\n" +
+	"..........*.\n" +
+	".>..1...#...\n" +
+	"..*.....#..<\n" +
+	"....#.......\n" +
+	".1..#...*...\n" +
+	"......^.....\n"
+
+// checkBoard checks that the board matches the arena.
+func checkBoard(t *testing.T, b *Board, a *Arena, when string) {
+	t.Helper()
+	for i, p := range a.Players {
+		if b.IsAlive(i) != p.IsAlive {
+			t.Fatalf("%s: player %d alive %v, want %v", when, p.ID, b.IsAlive(i), p.IsAlive)
+		}
+		if p.IsAlive && (b.Position(i) != p.Position || b.Direction(i) != p.Direction) {
+			t.Fatalf("%s: player %d at %v going %v, want %v going %v", when, p.ID,
+				b.Position(i), b.Direction(i), p.Position, p.Direction)
+		}
+	}
+	for y := range a.Height {
+		for x := range a.Width {
+			pos := Vector{X: x, Y: y}
+			if b.IsBlocked(pos) != a.isCollision(pos) {
+				t.Fatalf("%s: square %v blocked %v, want %v", when, pos, b.IsBlocked(pos), a.isCollision(pos))
+			}
+		}
+	}
+	values := a.ComputePlayerValues()
+	for i, v := range b.Values() {
+		if v != values[i+1] {
+			t.Fatalf("%s: values %v, want %v", when, b.Values(), values)
+		}
+	}
+}
+
+func TestBoardFollowsArena(t *testing.T) {
+	m, err := ReadMap("board", strings.NewReader(boardTestMap))
+	if err != nil {
+		t.Fatalf("ReadMap failed: %v", err)
+	}
+	for _, m := range append([]*Map{m}, Maps()...) {
+		for seed := uint64(1); seed <= 3; seed++ {
+			players := m.NewPlayers([]PlayerController{
+				&RandomTurnerController{TurnProb: 0.2},
+				&RandomTurnerController{TurnProb: 0.05},
+				&RandomAvoidingController{},
+			})
+			arena := NewArenaFromMap(m, players, NewRand(seed))
+			replay := &Replay{}
+			arena.Record(replay)
+
+			// One board follows the whole round, and is then taken back to
+			// the start
+			b := NewBoard(arena)
+			start := slices.Clone(b.Values())
+			for tick := 0; tick < 300 && len(replay.Ticks) == tick; tick++ {
+				alive := 0
+				for _, p := range players {
+					if p.IsAlive {
+						alive++
+					}
+				}
+				if alive == 0 {
+					break
+				}
+				arena.Update()
+				b.Move(replay.Ticks[tick])
+				checkBoard(t, b, arena, fmt.Sprintf("%s seed %d tick %d", m.Name, seed, tick))
+			}
+			for range replay.Ticks {
+				b.Undo()
+			}
+			if values := b.Values(); !slices.Equal(values, start) {
+				t.Errorf("%s seed %d: values after undoing %v, want %v", m.Name, seed, values, start)
+			}
+		}
+	}
+}
+
+func TestBoardUndo(t *testing.T) {
+	arena := newMapArena(t, "gocycle map\ngrid\n######\n#>.*.#\n#..<.#\n######\n", &mockController{}, &mockController{})
+	arena.Players[0].Boosted = true
+	before := arena.DeepCopy()
+
+	b := NewBoard(arena)
+	b.Move([]Vector{Right, Left})
+	b.Move([]Vector{Down, Left})
+	if b.IsAlive(0) {
+		t.Errorf("expected player 1 to crash into player 2's trail")
+	}
+	if b.IsBlocked(Vector{X: 1, Y: 1}) {
+		t.Errorf("expected player 1's trail to be cleared")
+	}
+	b.Undo()
+	b.Undo()
+	checkBoard(t, b, before, "after undoing")
+}
+
+func TestBoardItems(t *testing.T) {
+	text := "gocycle map\ngrid\n#########\n#>......#\n#.......#\n#######.#\n#<......#\n#########\n"
+	arena := newItemArena(t, text, &forcedController{Dir: Right}, &forcedController{Dir: Right})
+	arena.Items[Vector{X: 2, Y: 1}] = SpeedItem
+	arena.Items[Vector{X: 5, Y: 1}] = GhostItem
+	arena.Items[Vector{X: 6, Y: 2}] = BombItem
+	before := NewBoard(arena).Values()[0]
+
+	b := NewBoard(arena)
+	for tick := range 3 {
+		arena.Update()
+		b.Move([]Vector{Right, Right})
+		checkBoard(t, b, arena, fmt.Sprintf("tick %d", tick))
+	}
+	for range 3 {
+		b.Undo()
+	}
+	if got := b.Values()[0]; got != before {
+		t.Errorf("value after undoing: got %d, want %d", got, before)
+	}
+}
+
+func TestFastMinimaxMatchesMinimax(t *testing.T) {
+	for seed := uint64(1); seed <= 2; seed++ {
+		players := GetMap(2).NewPlayers([]PlayerController{
+			&AreaController{},
+			&RandomTurnerController{TurnProb: 0.1},
+			&AreaController{},
+		})
+		arena := NewArenaFromMap(GetMap(2), players, NewRand(seed))
+		for tick := 0; tick < 60; tick++ {
+			if tick%5 == 0 {
+				for _, p := range players {
+					if !p.IsAlive {
+						continue
+					}
+					want := (&MinimaxAreaController{MaxDepth: 3}).GetDirection(arena, p.ID)
+					if got := (&FastMinimaxController{MaxDepth: 3}).GetDirection(arena, p.ID); got != want {
+						t.Fatalf("seed %d tick %d player %d: got %v, want %v", seed, tick, p.ID, got, want)
+					}
+				}
+			}
+			arena.Update()
+		}
+	}
+}
+
+// benchmarkArena returns a round on the open map a few ticks in.
+func benchmarkArena() *Arena {
+	m := GetMap(0)
+	players := m.NewPlayers([]PlayerController{&AreaController{}, &AreaController{}, &AreaController{}, &AreaController{}})
+	arena := NewArenaFromMap(m, players, NewRand(1))
+	for range 20 {
+		arena.Update()
+	}
+	return arena
+}
+
+func BenchmarkMinimaxAreaController(b *testing.B) {
+	arena := benchmarkArena()
+	mac := &MinimaxAreaController{MaxDepth: 3}
+	b.ResetTimer()
+	for range b.N {
+		mac.GetDirection(arena, 1)
+	}
+	b.ReportMetric(float64(mac.nodes)/b.Elapsed().Seconds(), "nodes/s")
+}
+
+func BenchmarkFastMinimaxController(b *testing.B) {
+	arena := benchmarkArena()
+	fc := &FastMinimaxController{MaxDepth: 3}
+	b.ResetTimer()
+	for range b.N {
+		fc.GetDirection(arena, 1)
+	}
+	b.ReportMetric(float64(fc.nodes)/b.Elapsed().Seconds(), "nodes/s")
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// controllers.go names the computer players, so that tools such as the
+// benchmark can create them from the command line.
+
+// ControllerFactories makes a new controller of each named kind. Each call
+// returns a fresh controller, so no two players ever share one, even if a
+// controller starts keeping state between ticks.
+var ControllerFactories = map[string]func() PlayerController{
+	"RandomAvoiding":          func() PlayerController { return &RandomAvoidingController{} },
+	"RandomTurner_0.1":        func() PlayerController { return &RandomTurnerController{TurnProb: 0.10} },
+	"RandomTurner_0.005":      func() PlayerController { return &RandomTurnerController{TurnProb: 0.005} },
+	"WallHugger":              func() PlayerController { return &WallHuggerController{} },
+	"AreaController":          func() PlayerController { return &AreaController{} },
+	"MinimaxAreaController_3": func() PlayerController { return &MinimaxAreaController{MaxDepth: 3} },
+	"FastMinimax_4":           func() PlayerController { return &FastMinimaxController{MaxDepth: 4} },
+	"TreeSearch_10ms":         func() PlayerController { return &TreeSearchController{TimeBudget: 10 * time.Millisecond} },
+	"Weighted":                func() PlayerController { return &WeightedController{Genome: DefaultGenome} },
+}
+
+// ControllerNames returns the names in ControllerFactories, sorted.
+func ControllerNames() []string {
+	names := make([]string, 0, len(ControllerFactories))
+	for name := range ControllerFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewController makes a new controller of the named kind.
+func NewController(name string) (PlayerController, error) {
+	factory, ok := ControllerFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown controller %q", name)
+	}
+	return factory(), nil
+}
+
+// AddBot adds the external program run by command to ControllerFactories
+// under the given name. See ExternalController.
+func AddBot(name string, command []string, timeout time.Duration) {
+	ControllerFactories[name] = func() PlayerController {
+		return NewExternalController(command, timeout)
+	}
+}
+
+// AddGenome adds a WeightedController with the given weights to
+// ControllerFactories under the given name.
+func AddGenome(name string, genome Genome) {
+	ControllerFactories[name] = func() PlayerController {
+		return &WeightedController{Genome: genome}
+	}
+}
+
+// ParseBot splits a bot given on the command line as "name=command args"
+// into its name and command.
+func ParseBot(spec string) (name string, command []string, err error) {
+	name, commandLine, ok := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	command = strings.Fields(commandLine)
+	if !ok || name == "" || len(command) == 0 {
+		return "", nil, fmt.Errorf("invalid bot %q: expected name=command", spec)
+	}
+	return name, command, nil
+}
+
+// SplitList splits a comma-separated flag, ignoring spaces and empty items.
+func SplitList(s string) []string {
+	result := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,267 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// external.go lets a program written in any language steer a player. The
+// program is started when its player first needs a direction, and is sent
+// the whole arena on its standard input every tick:
+//
+//	state <width> <height> <you> <players> <wrap>
+//	<height lines of width characters>
+//	player <id> <x> <y> <direction> <alive>
+//	portal <x1> <y1> <x2> <y2>
+//	go
+//
+// <you> is the ID of the program's player, and <wrap> is 1 if players
+// leaving one edge come back on the opposite edge, 0 if not. In the grid,
+// '#' is a wall, '.' an open square, '*' a speed pad, '@' a portal, and the
+// digits 1 to 9 are the trails of the players with those IDs. There is one
+// player line for each player, in ID order, with the direction given as U,
+// D, L or R and <alive> 1 or 0, and one portal line for each pair of
+// portals. Coordinates start from 0 at the top left.
+//
+// After "go" the program writes a line with the direction it wants: U, D, L
+// or R. If it doesn't answer within the time allowed, the player keeps
+// going in its current direction, and the late answer is thrown away.
+// Anything the program writes to standard error is passed on, for
+// debugging. Standard input is closed at the end of the round.
+
+// DefaultBotTimeout is how long a bot has to answer each tick.
+const DefaultBotTimeout = 50 * time.Millisecond
+
+const (
+	// time a bot has to answer the first tick, which includes starting up
+	botStartTimeout = 5 * time.Second
+	// states that can wait for a bot to read them before it is given up on
+	maxUnreadStates = 16
+	// time a bot has to exit after its input is closed
+	botExitTimeout = 3 * time.Second
+)
+
+// ExternalController is a PlayerController that asks another program for
+// each direction. It should be closed when the round is over.
+type ExternalController struct {
+	Command []string
+	Timeout time.Duration
+
+	cmd *exec.Cmd
+	// States waiting to be written to the bot. They are written on another
+	// goroutine, so a bot that stops reading can't hold up the game.
+	states chan string
+	lines  chan string
+	// closed by Close, so the goroutine reading the bot's output can stop
+	done chan struct{}
+	// answers still to come for ticks that timed out
+	late int
+	// set once the bot has failed, after which it is no longer asked
+	err error
+}
+
+func NewExternalController(command []string, timeout time.Duration) *ExternalController {
+	return &ExternalController{Command: command, Timeout: timeout}
+}
+
+func (ec *ExternalController) start() error {
+	if len(ec.Command) == 0 {
+		return fmt.Errorf("no command given")
+	}
+	cmd := exec.Command(ec.Command[0], ec.Command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	ec.cmd = cmd
+	ec.states = make(chan string, maxUnreadStates)
+	ec.lines = make(chan string)
+	ec.done = make(chan struct{})
+	go func(states <-chan string) {
+		for state := range states {
+			if _, err := io.WriteString(stdin, state); err != nil {
+				// The bot has gone; the reader will find out
+				break
+			}
+		}
+		stdin.Close()
+	}(ec.states)
+	go func(lines chan<- string, done <-chan struct{}) {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-done:
+				return
+			}
+		}
+		close(lines)
+	}(ec.lines, ec.done)
+	return nil
+}
+
+func (ec *ExternalController) GetDirection(arena *Arena, playerID int) Vector {
+	current := arena.Players[playerID-1].Direction
+	if ec.err != nil {
+		return current
+	}
+	dir, err := ec.ask(arena, playerID, current)
+	if err != nil {
+		ec.err = err
+		log.Printf("bot %v: %v; going straight from now on", ec.Command, err)
+		return current
+	}
+	return dir
+}
+
+// ask sends the arena to the bot and reads its answer. It returns current
+// if the bot is too slow, and an error if the bot can't be used.
+func (ec *ExternalController) ask(arena *Arena, playerID int, current Vector) (Vector, error) {
+	timeout := ec.Timeout
+	if ec.cmd == nil {
+		if err := ec.start(); err != nil {
+			return Vector{}, err
+		}
+		timeout = max(timeout, botStartTimeout)
+	}
+	select {
+	case ec.states <- FormatBotState(arena, playerID):
+	default:
+		return Vector{}, fmt.Errorf("not reading its input")
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case line, ok := <-ec.lines:
+			if !ok {
+				return Vector{}, fmt.Errorf("exited")
+			}
+			if ec.late > 0 {
+				ec.late--
+				continue
+			}
+			return parseBotDirection(line)
+		case <-deadline.C:
+			ec.late++
+			return current, nil
+		}
+	}
+}
+
+func parseBotDirection(line string) (Vector, error) {
+	s := strings.TrimSpace(line)
+	if len(s) == 1 && s != "." {
+		if dir, err := parseDirection(s[0]); err == nil {
+			return dir, nil
+		}
+	}
+	return Vector{}, fmt.Errorf("invalid direction %q", line)
+}
+
+// Close ends the bot's input and waits a moment for it to exit, killing it
+// if it doesn't.
+func (ec *ExternalController) Close() error {
+	if ec.cmd == nil {
+		return nil
+	}
+	cmd := ec.cmd
+	ec.cmd = nil
+	close(ec.done)
+	close(ec.states)
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(botExitTimeout):
+		cmd.Process.Kill()
+		return <-exited
+	}
+}
+
+// botChars are the characters for the squares in the grid sent to bots.
+var botChars = map[Square]byte{
+	Open:     '.',
+	Wall:     '#',
+	SpeedPad: '*',
+	Portal:   '@',
+}
+
+// FormatBotState writes the arena as it is sent to a bot steering the
+// player with the given ID, including the final "go" line.
+func FormatBotState(arena *Arena, playerID int) string {
+	var sb strings.Builder
+	wrap := 0
+	if arena.Wrap {
+		wrap = 1
+	}
+	fmt.Fprintf(&sb, "state %d %d %d %d %d\n", arena.Width, arena.Height, playerID, len(arena.Players), wrap)
+	for _, row := range arena.Grid {
+		for _, square := range row {
+			c, ok := botChars[square]
+			if !ok {
+				c = byte('0' + int(square)%10)
+			}
+			sb.WriteByte(c)
+		}
+		sb.WriteByte('\n')
+	}
+	for _, p := range arena.Players {
+		alive := 0
+		if p.IsAlive {
+			alive = 1
+		}
+		fmt.Fprintf(&sb, "player %d %d %d %c %d\n", p.ID, p.Position.X, p.Position.Y, directionChars[p.Direction], alive)
+	}
+
+	// Each pair once, in reading order, so the same arena is always sent
+	// the same way
+	ends := []Vector{}
+	for from, to := range arena.Portals {
+		if from.Y < to.Y || (from.Y == to.Y && from.X < to.X) {
+			ends = append(ends, from)
+		}
+	}
+	sort.Slice(ends, func(i, j int) bool {
+		if ends[i].Y != ends[j].Y {
+			return ends[i].Y < ends[j].Y
+		}
+		return ends[i].X < ends[j].X
+	})
+	for _, from := range ends {
+		to := arena.Portals[from]
+		fmt.Fprintf(&sb, "portal %d %d %d %d\n", from.X, from.Y, to.X, to.Y)
+	}
+	sb.WriteString("go\n")
+	return sb.String()
+}
+
+// CloseControllers closes the controllers of the players that need it,
+// such as ExternalControllers, once their round is over.
+func CloseControllers(players []*Player) {
+	for _, p := range players {
+		if c, ok := p.Controller.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				log.Printf("closing controller of player %d: %v", p.ID, err)
+			}
+		}
+	}
+}
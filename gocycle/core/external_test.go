@@ -0,0 +1,152 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatBotState(t *testing.T) {
+	// Player 2 crashes into the wall, and its trail is cleared
+	arena := newMapArena(t, "gocycle map\ngrid\n#1###\n#>.*#\n#..<#\n###1#\n", &mockController{}, &mockController{})
+	arena.Update()
+	expected := "state 5 4 2 2 0\n" +
+		"#@###\n" +
+		"#11*#\n" +
+		"#...#\n" +
+		"###@#\n" +
+		"player 1 2 1 R 1\n" +
+		"player 2 3 2 R 0\n" +
+		"portal 1 0 3 3\n" +
+		"go\n"
+	if got := FormatBotState(arena, 2); got != expected {
+		t.Errorf("FormatBotState:\ngot:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+// newTestBot runs this test binary as a bot; see TestBotProcess.
+func newTestBot(t *testing.T, mode string, timeout time.Duration) *ExternalController {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("starts another process")
+	}
+	t.Setenv("GOCYCLE_BOT", mode)
+	return NewExternalController([]string{os.Args[0], "-test.run=^TestBotProcess$"}, timeout)
+}
+
+func TestExternalController(t *testing.T) {
+	bot := newTestBot(t, "safe", time.Second)
+	defer bot.Close()
+	arena := newMapArena(t, "gocycle map\ngrid\n####\n#>.#\n#..#\n####\n", bot)
+
+	// Going straight would hit the wall after the first tick
+	arena.Update()
+	arena.Update()
+	p := arena.Players[0]
+	if !p.IsAlive || p.Position != (Vector{X: 2, Y: 2}) {
+		t.Errorf("expected the bot to turn down at the wall, got %v alive=%v", p.Position, p.IsAlive)
+	}
+	if err := bot.Close(); err != nil {
+		t.Errorf("bot did not exit cleanly: %v", err)
+	}
+}
+
+func TestExternalControllerTimeout(t *testing.T) {
+	bot := newTestBot(t, "slow", 20*time.Millisecond)
+	defer bot.Close()
+	arena := newMapArena(t, "gocycle map\ngrid\n######\n#>...#\n#....#\n######\n", bot)
+
+	// The first answer may take longer, to give the bot time to start
+	if dir := bot.GetDirection(arena, 1); dir != Down {
+		t.Errorf("first tick: got %v, want Down", dir)
+	}
+	for range 2 {
+		if dir := bot.GetDirection(arena, 1); dir != Right {
+			t.Errorf("slow answer: got %v, want the current direction", dir)
+		}
+	}
+}
+
+func TestExternalControllerMissingProgram(t *testing.T) {
+	bot := NewExternalController([]string{filepath.Join(t.TempDir(), "no-such-bot")}, DefaultBotTimeout)
+	arena := newMapArena(t, "gocycle map\ngrid\n####\n#>.#\n####\n", bot)
+	if dir := bot.GetDirection(arena, 1); dir != Right {
+		t.Errorf("got %v, want the current direction", dir)
+	}
+}
+
+func TestParseBot(t *testing.T) {
+	name, command, err := ParseBot("mybot=python3 bot.py --fast")
+	if err != nil || name != "mybot" || !slices.Equal(command, []string{"python3", "bot.py", "--fast"}) {
+		t.Errorf("got %q %q %v", name, command, err)
+	}
+	for _, spec := range []string{"python3 bot.py", "=bot", "mybot="} {
+		if _, _, err := ParseBot(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := SplitList(" greedy, ,random,,wall ")
+	want := []string{"greedy", "random", "wall"}
+	if !slices.Equal(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if got := SplitList(""); len(got) != 0 {
+		t.Errorf("want no items, got %v", got)
+	}
+}
+
+// TestBotProcess is the bot for the external controller tests. It does
+// nothing when run normally. In "safe" mode it takes the first open square
+// next to it; in "slow" mode it always goes down, too slowly.
+func TestBotProcess(t *testing.T) {
+	mode := os.Getenv("GOCYCLE_BOT")
+	if mode == "" {
+		t.Skip("only run by the external controller tests")
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	var grid []string
+	var me string
+	positions := map[string]Vector{}
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch fields[0] {
+		case "state":
+			me = fields[3]
+			height, _ := strconv.Atoi(fields[2])
+			grid = grid[:0]
+			for range height {
+				scanner.Scan()
+				grid = append(grid, scanner.Text())
+			}
+		case "player":
+			x, _ := strconv.Atoi(fields[2])
+			y, _ := strconv.Atoi(fields[3])
+			positions[fields[1]] = Vector{X: x, Y: y}
+		case "go":
+			if mode == "slow" {
+				time.Sleep(200 * time.Millisecond)
+				fmt.Println("D")
+				continue
+			}
+			answer := "U"
+			for _, d := range []string{"U", "R", "D", "L"} {
+				dir, _ := parseDirection(d[0])
+				next := positions[me].Add(dir)
+				if grid[next.Y][next.X] == '.' {
+					answer = d
+					break
+				}
+			}
+			fmt.Println(answer)
+		}
+	}
+}
@@ -1,324 +1,95 @@
 package core
 
-// grids.go contains predefined arena grids and functions to access them.
+import (
+	"embed"
+	"fmt"
+	"path"
+	"strings"
+)
 
-const (
-	ArenaWidth  = 50
-	ArenaHeight = 50
+// grids.go keeps the list of arena maps: the built-in ones in the maps
+// directory, plus any loaded from a directory of map files with AddMaps.
 
-	PlayerStartLow  = 12
-	PlayerStartHigh = 37
-)
+//go:embed maps/*.txt
+var builtinMapFiles embed.FS
 
-var grids = loadGridsFromStrings(gridData)
+var maps = loadBuiltinMaps()
 
-func GetGrid(level int) [][]Square {
-	if level < 0 || level >= len(grids) {
-		level = 0
+func loadBuiltinMaps() []*Map {
+	entries, err := builtinMapFiles.ReadDir("maps")
+	if err != nil {
+		panic(err)
+	}
+	result := []*Map{}
+	for _, e := range entries {
+		f, err := builtinMapFiles.Open(path.Join("maps", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		m, err := ReadMap(strings.TrimSuffix(e.Name(), ".txt"), f)
+		f.Close()
+		if err != nil {
+			panic(fmt.Sprintf("built-in map %s: %v", e.Name(), err))
+		}
+		result = append(result, m)
 	}
-	return grids[level]
+	return result
 }
 
-// GetStartVectors returns a slice of core.Vector representing the starting
-// grid coordinates for the given number of players (up to 4).
-// These positions are designed for the default 50x50 arena size grids in this file.
-func GetStartVectors(numPlayers int) []Vector {
-	// The positions are returned in a fixed order.
-	allPositions := []Vector{
-		{X: PlayerStartLow, Y: PlayerStartLow},   // Top-Left
-		{X: PlayerStartHigh, Y: PlayerStartHigh}, // Bottom-Right
-		{X: PlayerStartLow, Y: PlayerStartHigh},  // Bottom-Left
-		{X: PlayerStartHigh, Y: PlayerStartLow},  // Top-Right
+// AddMaps loads the maps in a directory and makes them available to play.
+// A map with the same name as an earlier one replaces it.
+func AddMaps(dir string) error {
+	loaded, err := LoadMapDir(dir)
+	if err != nil {
+		return err
 	}
-
-	if numPlayers <= 0 || numPlayers > len(allPositions) {
-		return nil
+	for _, m := range loaded {
+		replaced := false
+		for i := range maps {
+			if maps[i].Name == m.Name {
+				maps[i] = m
+				replaced = true
+			}
+		}
+		if !replaced {
+			maps = append(maps, m)
+		}
 	}
-
-	return allPositions[:numPlayers]
+	return nil
 }
 
-func loadGridsFromStrings(gridData [][]string) [][][]Square {
-	var grids [][][]Square
+// Maps returns every available map.
+func Maps() []*Map {
+	return maps
+}
 
-	for _, level := range gridData {
-		height := len(level)
-		if height == 0 {
-			return nil
+// MapsForPlayers returns the maps with enough spawn points for a round
+// with the given number of players.
+func MapsForPlayers(numPlayers int) []*Map {
+	result := []*Map{}
+	for _, m := range maps {
+		if len(m.Spawns) >= numPlayers {
+			result = append(result, m)
 		}
-		width := len(level[0])
-		grid := make([][]Square, height)
-		for y := range height {
-			grid[y] = make([]Square, width)
-			for x := range width {
-				if level[y][x] == '#' {
-					grid[y][x] = Wall
-				} else {
-					grid[y][x] = Open
-				}
-			}
-		}
-		grids = append(grids, grid)
 	}
-	return grids
+	return result
+}
+
+// GetMap returns the map with the given index, or the first map if there
+// is no such map.
+func GetMap(level int) *Map {
+	if level < 0 || level >= len(maps) {
+		level = 0
+	}
+	return maps[level]
 }
 
-var gridData = [][]string{
-	{
-		"##################################################",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"##################################################",
-	},
-	{
-		"##################################################",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................################................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................#..............#................#",
-		"#................################................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"##################################################",
-	}, {
-		"##################################################",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#....................########....................#",
-		"#....................#......#....................#",
-		"#....................#......#....................#",
-		"#....................#......#....................#",
-		"#....................#......#....................#",
-		"#....................#......#....................#",
-		"#....................#......#....................#",
-		"#....................########....................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#....########....................................#",
-		"#....#......#........................########....#",
-		"#....#......#........................#......#....#",
-		"#....#......#........................#......#....#",
-		"#....#......#........................#......#....#",
-		"#....#......#........................#......#....#",
-		"#....#......#........................#......#....#",
-		"#....########........................#......#....#",
-		"#....................................########....#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#....................########....................#",
-		"#....................#......#....................#",
-		"#....................#......#....................#",
-		"#....................#......#....................#",
-		"#....................#......#....................#",
-		"#....................#......#....................#",
-		"#....................#......#....................#",
-		"#....................########....................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"##################################################",
-	}, {
-		"##################################################",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#...................##########...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#..........##########........##########..........#",
-		"#..........#..........................#..........#",
-		"#..........#..........................#..........#",
-		"#..........#..........................#..........#",
-		"#..........#..........................#..........#",
-		"#..........#..........................#..........#",
-		"#..........#..........................#..........#",
-		"#..........#..........................#..........#",
-		"#..........#..........................#..........#",
-		"#..........##########........##########..........#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................#........#...................#",
-		"#...................##########...................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"##################################################",
-	}, {
-		"##################################################",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#.....##########..................##########.....#",
-		"#.....#........#..................#........#.....#",
-		"#.....#.########..................########.#.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....###................................###.....#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#.....###................................###.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....#.#................................#.#.....#",
-		"#.....#.########..................########.#.....#",
-		"#.....#........#..................#........#.....#",
-		"#.....##########..................##########.....#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"#................................................#",
-		"##################################################",
-	},
+// FindMap returns the map with the given name, or nil if there is none.
+func FindMap(name string) *Map {
+	for _, m := range maps {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
 }
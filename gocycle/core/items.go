@@ -0,0 +1,176 @@
+package core
+
+import (
+	"math/rand/v2"
+)
+
+// items.go handles power-ups: items that appear on open squares during a
+// round and do something to the player who drives over them.
+
+type ItemKind int
+
+const (
+	// SpeedItem makes the player move two squares a tick for a while.
+	SpeedItem ItemKind = iota
+	// GhostItem lets the player drive through trails for a while.
+	GhostItem
+	// BombItem clears the trails around the square it was on.
+	BombItem
+
+	numItemKinds
+)
+
+func (k ItemKind) String() string {
+	switch k {
+	case SpeedItem:
+		return "speed"
+	case GhostItem:
+		return "ghost"
+	case BombItem:
+		return "bomb"
+	}
+	return "unknown"
+}
+
+// ItemRules says how often items appear and how strong they are.
+type ItemRules struct {
+	// Chance of a new item appearing on each tick
+	SpawnChance float64
+	// No new items appear while there are this many on the arena
+	MaxItems int
+	// How many ticks the speed and ghost items last
+	SpeedTicks int
+	GhostTicks int
+	// A bomb clears trails up to this many squares away, in each direction
+	BombRadius int
+}
+
+var DefaultItemRules = ItemRules{
+	SpawnChance: 0.05,
+	MaxItems:    3,
+	SpeedTicks:  15,
+	GhostTicks:  8,
+	BombRadius:  3,
+}
+
+// EnableItems makes items appear in the arena during the round. Call it
+// before the first Update. Items are placed using their own random number
+// generator, seeded from the arena's, so that a controller that uses more
+// or fewer random numbers doesn't change where they appear, and a replay
+// places them in the same squares.
+func (a *Arena) EnableItems(rules ItemRules) {
+	a.itemRules = &rules
+	a.itemRng = rand.New(rand.NewPCG(a.Rand().Uint64(), 1))
+	a.Items = map[Vector]ItemKind{}
+}
+
+// spawnItem maybe puts a new item on a random open square.
+func (a *Arena) spawnItem() {
+	if a.itemRules == nil || len(a.Items) >= a.itemRules.MaxItems {
+		return
+	}
+	if a.itemRng.Float64() >= a.itemRules.SpawnChance {
+		return
+	}
+
+	// Squares in reading order, so the choice only depends on the random number
+	free := []Vector{}
+	for y := range a.Height {
+		for x := range a.Width {
+			pos := Vector{X: x, Y: y}
+			if _, taken := a.Items[pos]; a.Grid[y][x] == Open && !taken {
+				free = append(free, pos)
+			}
+		}
+	}
+	if len(free) == 0 {
+		return
+	}
+	pos := free[a.itemRng.IntN(len(free))]
+	a.Items[pos] = ItemKind(a.itemRng.IntN(int(numItemKinds)))
+}
+
+// collectItems gives the player any items on the squares it just drove over.
+func (a *Arena) collectItems(p *Player, squares []Vector) {
+	for _, pos := range squares {
+		kind, ok := a.Items[pos]
+		if !ok {
+			continue
+		}
+		delete(a.Items, pos)
+		p.Collected = append(p.Collected, kind)
+
+		switch kind {
+		case SpeedItem:
+			p.SpeedTicks = a.itemRules.SpeedTicks
+		case GhostItem:
+			p.GhostTicks = a.itemRules.GhostTicks
+		case BombItem:
+			a.explode(pos, a.itemRules.BombRadius)
+		}
+	}
+}
+
+// explode clears every trail square within radius of center, apart from
+// the heads of living players. On a map that wraps, the blast goes round
+// the edges too.
+func (a *Arena) explode(center Vector, radius int) {
+	cleared := map[Vector]bool{}
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			pos := a.wrap(center.Add(Vector{X: dx, Y: dy}))
+			if a.onGrid(pos) && a.Grid[pos.Y][pos.X] > 0 {
+				cleared[pos] = true
+			}
+		}
+	}
+	for _, p := range a.Players {
+		if p.IsAlive {
+			delete(cleared, p.Position)
+		}
+	}
+
+	for pos := range cleared {
+		a.Grid[pos.Y][pos.X] = a.tileAt(pos)
+	}
+	// The cleared squares are no longer part of anyone's trail, so must not
+	// be cleared again when their owner dies
+	for _, p := range a.Players {
+		path := []Vector{}
+		for _, pos := range p.Path {
+			if !cleared[pos] {
+				path = append(path, pos)
+			}
+		}
+		p.Path = path
+	}
+}
+
+// ItemValues is how many squares of area the AI controllers think each
+// item is worth. Change it to make them more or less keen on items.
+var ItemValues = map[ItemKind]int{
+	SpeedItem: 10,
+	GhostItem: 15,
+	BombItem:  20,
+}
+
+// ComputePlayerValues is ComputePlayerScores with items added in, which is
+// how the AI controllers judge a position. Each player gets the value of
+// every item it has picked up, plus half the value of each item in its
+// area, since it will probably get there first.
+func (a *Arena) ComputePlayerValues() map[int]int {
+	_, assignmentGrid := a.findClosestAssignments()
+	scores := calculateScores(assignmentGrid, a.Players)
+
+	for _, p := range a.Players {
+		for _, kind := range p.Collected {
+			scores[p.ID] += ItemValues[kind]
+		}
+	}
+	for pos, kind := range a.Items {
+		if id := assignmentGrid[pos.Y][pos.X]; id > 0 {
+			scores[id] += ItemValues[kind] / 2
+		}
+	}
+	return scores
+}
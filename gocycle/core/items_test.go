@@ -0,0 +1,180 @@
+package core
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// newItemArena is newMapArena with items turned on, but none appearing by
+// themselves, so tests can place them.
+func newItemArena(t *testing.T, text string, controllers ...PlayerController) *Arena {
+	t.Helper()
+	arena := newMapArena(t, text, controllers...)
+	rules := DefaultItemRules
+	rules.SpawnChance = 0
+	arena.EnableItems(rules)
+	return arena
+}
+
+func TestSpawnItem(t *testing.T) {
+	arena := newMapArena(t, "gocycle map\ngrid\n######\n#>...#\n#....#\n######\n", &forcedController{Dir: Down})
+	arena.EnableItems(ItemRules{SpawnChance: 1, MaxItems: 2})
+
+	for range 3 {
+		arena.Update()
+	}
+
+	if len(arena.Items) != 2 {
+		t.Fatalf("expected 2 items, got %v", arena.Items)
+	}
+	for pos := range arena.Items {
+		if arena.Grid[pos.Y][pos.X] != Open {
+			t.Errorf("item at %v is not on an open square", pos)
+		}
+	}
+}
+
+func TestSpeedItem(t *testing.T) {
+	arena := newItemArena(t, "gocycle map\ngrid\n#########\n#>......#\n#########\n", &forcedController{Dir: Right})
+	p := arena.Players[0]
+	arena.Items[Vector{X: 2, Y: 1}] = SpeedItem
+
+	arena.Update() // Pick up the item
+	if len(arena.Items) != 0 || p.SpeedTicks != DefaultItemRules.SpeedTicks {
+		t.Fatalf("speed item not picked up: items %v, speed ticks %d", arena.Items, p.SpeedTicks)
+	}
+
+	arena.Update()
+	if p.Position != (Vector{X: 4, Y: 1}) || p.SpeedTicks != DefaultItemRules.SpeedTicks-1 {
+		t.Errorf("expected a double move to (4, 1), got %v with %d speed ticks left", p.Position, p.SpeedTicks)
+	}
+}
+
+func TestGhostItem(t *testing.T) {
+	text := `gocycle map
+spawn 1 1 R
+spawn 3 3 U
+grid
+#######
+#.....#
+#.....#
+#.....#
+#######
+`
+	arena := newItemArena(t, text, &forcedController{Dir: Right}, &forcedController{Dir: Up})
+	ghost, other := arena.Players[0], arena.Players[1]
+	ghost.GhostTicks = 3
+	// The other player's trail runs down column 3
+	arena.Grid[1][3] = Square(2)
+	arena.Grid[2][3] = Square(2)
+	other.Path = []Vector{{X: 3, Y: 1}, {X: 3, Y: 2}, {X: 3, Y: 3}}
+	other.Controller = &forcedController{Dir: Left}
+
+	arena.Update()
+	arena.Update() // Through the trail
+
+	if !ghost.IsAlive || ghost.Position != (Vector{X: 3, Y: 1}) {
+		t.Fatalf("ghost should have driven onto the trail, got %v (alive %v)", ghost.Position, ghost.IsAlive)
+	}
+	if arena.Grid[1][3] != Square(2) {
+		t.Errorf("a ghost should leave the trail it goes through as it is")
+	}
+	if slices.Contains(ghost.Path, Vector{X: 3, Y: 1}) {
+		t.Errorf("the other player's trail should not be part of the ghost's path")
+	}
+
+	arena.Update() // Back onto open squares
+	if !ghost.IsAlive || arena.Grid[1][4] != Square(1) || ghost.GhostTicks != 0 {
+		t.Errorf("ghost should have left the trail normally: %v, ticks %d", ghost.Position, ghost.GhostTicks)
+	}
+}
+
+func TestBombItem(t *testing.T) {
+	text := `gocycle map
+spawn 1 1 R
+spawn 7 3 U
+grid
+#########
+#.......#
+#.......#
+#.......#
+#########
+`
+	arena := newItemArena(t, text, &forcedController{Dir: Right}, &forcedController{Dir: Up})
+	p1, p2 := arena.Players[0], arena.Players[1]
+	// Player 2 has a trail along the bottom row
+	for x := 2; x <= 7; x++ {
+		arena.Grid[3][x] = Square(2)
+	}
+	p2.Path = []Vector{{X: 2, Y: 3}, {X: 3, Y: 3}, {X: 4, Y: 3}, {X: 5, Y: 3}, {X: 6, Y: 3}, {X: 7, Y: 3}}
+	arena.Items[Vector{X: 3, Y: 1}] = BombItem
+	arena.itemRules.BombRadius = 2
+
+	arena.Update()
+	arena.Update() // Pick up the bomb
+
+	for x := 1; x <= 5; x++ {
+		if arena.Grid[3][x] != Open {
+			t.Errorf("square (%d, 3) should have been cleared, got %v", x, arena.Grid[3][x])
+		}
+	}
+	if arena.Grid[3][6] != Square(2) || arena.Grid[1][3] != Square(1) {
+		t.Errorf("bomb cleared too much or the bomber's own head")
+	}
+	expectedPath := []Vector{{X: 6, Y: 3}, {X: 7, Y: 3}, {X: 7, Y: 2}, {X: 7, Y: 1}}
+	if !reflect.DeepEqual(p2.Path, expectedPath) {
+		t.Errorf("cleared squares should leave the path: got %v, want %v", p2.Path, expectedPath)
+	}
+	if !slices.Contains(p1.Collected, BombItem) {
+		t.Errorf("bomb should be recorded as collected")
+	}
+}
+
+func TestBombItemWraps(t *testing.T) {
+	text := `gocycle map
+wrap
+spawn 0 1 R
+spawn 4 3 U
+grid
+........
+........
+........
+........
+`
+	arena := newItemArena(t, text, &forcedController{Dir: Right}, &forcedController{Dir: Up})
+	p2 := arena.Players[1]
+	// Player 2 has a trail at the right end of the bomb's row
+	for x := 5; x <= 7; x++ {
+		arena.Grid[1][x] = Square(2)
+	}
+	p2.Path = append([]Vector{{X: 5, Y: 1}, {X: 6, Y: 1}, {X: 7, Y: 1}}, p2.Path...)
+	arena.Items[Vector{X: 1, Y: 1}] = BombItem
+	arena.itemRules.BombRadius = 2
+
+	arena.Update() // Pick up the bomb
+
+	if arena.Grid[1][7] != Open {
+		t.Errorf("the blast should have gone round the left edge to clear (7, 1), got %v", arena.Grid[1][7])
+	}
+	if arena.Grid[1][6] != Square(2) || arena.Grid[1][5] != Square(2) {
+		t.Errorf("bomb cleared squares out of range: %v", arena.Grid[1])
+	}
+	if slices.Contains(p2.Path, Vector{X: 7, Y: 1}) {
+		t.Errorf("cleared square should leave the path: %v", p2.Path)
+	}
+}
+
+func TestComputePlayerValuesCountsItems(t *testing.T) {
+	text := "gocycle map\nspawn 1 1 R\nspawn 5 1 L\ngrid\n#######\n#.....#\n#######\n"
+	arena := newItemArena(t, text, &mockController{}, &mockController{})
+	arena.Items[Vector{X: 2, Y: 1}] = GhostItem
+	arena.Players[1].Collected = []ItemKind{SpeedItem}
+
+	area := arena.ComputePlayerScores()
+	values := arena.ComputePlayerValues()
+
+	if values[1] != area[1]+ItemValues[GhostItem]/2 || values[2] != area[2]+ItemValues[SpeedItem] {
+		t.Errorf("values %v don't add the items to the areas %v", values, area)
+	}
+}
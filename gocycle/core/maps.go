@@ -0,0 +1,369 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maps.go reads arena maps from files, so new arenas can be added without
+// changing any code. A map can be written as text or drawn as a PNG image.
+//
+// A text map looks like this:
+//
+//	gocycle map
+//	name Pillars
+//	spawn 12 12 R
+//	tile + wall
+//	wrap
+//	grid
+//	####.#####
+//	#>.1.+*..#
+//	.*..+.1.<.
+//	####.#####
+//
+// The "name" line is optional; without it the map is named after its file.
+// Each "spawn X Y D" line gives a starting square and direction (U, D, L or
+// R). Starting squares can also be marked in the grid with ^, v, < or >,
+// which point in the starting direction; these come after any spawn lines,
+// in reading order. "tile C kind" lines let the grid use the character C for
+// one of the tileKinds, in addition to the standard '#' for a wall, '.' for
+// an open square and '*' for a speed pad. The two squares marked with the
+// same digit are the ends of a pair of portals. With the "wrap" line,
+// players going off one edge of the map come back on the opposite edge.
+//
+// In a PNG map each pixel is one square: black is open, white is a wall,
+// cyan is a speed pad, and spawn points are drawn in the spawnColors, which
+// give the starting direction. Players take the spawn points in reading
+// order. Portals are magenta-ish, with full red and blue; the two pixels
+// with the same amount of green are a pair. PNG maps can't wrap.
+
+// Spawn is a place where a player can start a round.
+type Spawn struct {
+	Position  Vector
+	Direction Vector
+}
+
+// Map is an arena layout: its squares, and where the players start.
+type Map struct {
+	Name   string
+	Grid   [][]Square
+	Spawns []Spawn
+	// Whether the edges wrap around, and which portals are linked, as in
+	// Arena
+	Wrap    bool
+	Portals map[Vector]Vector
+}
+
+func (m *Map) Width() int {
+	return len(m.Grid[0])
+}
+
+func (m *Map) Height() int {
+	return len(m.Grid)
+}
+
+// NewPlayers creates a player at each of the first len(controllers) spawn
+// points. Player i+1 is steered by controllers[i].
+func (m *Map) NewPlayers(controllers []PlayerController) []*Player {
+	players := make([]*Player, len(controllers))
+	for i, c := range controllers {
+		spawn := m.Spawns[i]
+		players[i] = NewPlayer(i+1, spawn.Position, spawn.Direction, c)
+	}
+	return players
+}
+
+// tileKinds names the squares a map can be built from.
+var tileKinds = map[string]Square{
+	"open":  Open,
+	"wall":  Wall,
+	"speed": SpeedPad,
+}
+
+// defaultTileChars are the characters every text map understands.
+var defaultTileChars = map[byte]Square{
+	'#': Wall,
+	'.': Open,
+	'*': SpeedPad,
+}
+
+// spawnChars mark a spawn point in a text map, pointing in its direction.
+var spawnChars = map[byte]Vector{
+	'^': Up,
+	'v': Down,
+	'<': Left,
+	'>': Right,
+}
+
+// spawnColors mark a spawn point in a PNG map.
+var spawnColors = map[color.RGBA]Vector{
+	{R: 255, G: 0, B: 0, A: 255}:   Up,
+	{R: 0, G: 255, B: 0, A: 255}:   Down,
+	{R: 0, G: 0, B: 255, A: 255}:   Left,
+	{R: 255, G: 255, B: 0, A: 255}: Right,
+}
+
+// tileColors are the colors of the other squares in a PNG map.
+var tileColors = map[color.RGBA]Square{
+	{R: 0, G: 0, B: 0, A: 255}:       Open,
+	{R: 255, G: 255, B: 255, A: 255}: Wall,
+	{R: 0, G: 255, B: 255, A: 255}:   SpeedPad,
+}
+
+// isPortalChar reports whether a character in a text map is a portal.
+func isPortalChar(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isPortalColor reports whether a pixel in a PNG map is a portal.
+func isPortalColor(c color.RGBA) bool {
+	return c.R == 255 && c.B == 255 && c.G != 255 && c.A == 255
+}
+
+// linkPortals puts the portals on the map, given the squares of each pair.
+func (m *Map) linkPortals(pairs map[int][]Vector) error {
+	for id, ends := range pairs {
+		if len(ends) != 2 {
+			return fmt.Errorf("portal %d has %d ends, want 2", id, len(ends))
+		}
+		if m.Portals == nil {
+			m.Portals = map[Vector]Vector{}
+		}
+		m.Portals[ends[0]] = ends[1]
+		m.Portals[ends[1]] = ends[0]
+	}
+	return nil
+}
+
+const mapHeader = "gocycle map"
+
+// ReadMap parses a text map. The name is used if the map doesn't give one.
+func ReadMap(name string, rd io.Reader) (*Map, error) {
+	scanner := bufio.NewScanner(rd)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != mapHeader {
+		return nil, fmt.Errorf("not a gocycle map")
+	}
+
+	m := &Map{Name: name}
+	tileChars := map[byte]Square{}
+	for c, square := range defaultTileChars {
+		tileChars[c] = square
+	}
+
+	inGrid := false
+	markers := []Spawn{}
+	portals := map[int][]Vector{}
+	for lineNum := 2; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if inGrid {
+			if line == "" {
+				continue
+			}
+			y := len(m.Grid)
+			row := make([]Square, len(line))
+			for x := range line {
+				if dir, ok := spawnChars[line[x]]; ok {
+					markers = append(markers, Spawn{Position: Vector{X: x, Y: y}, Direction: dir})
+					row[x] = Open
+					continue
+				}
+				if isPortalChar(line[x]) {
+					id := int(line[x] - '0')
+					portals[id] = append(portals[id], Vector{X: x, Y: y})
+					row[x] = Portal
+					continue
+				}
+				square, ok := tileChars[line[x]]
+				if !ok {
+					return nil, fmt.Errorf("line %d: unknown square %q", lineNum, line[x])
+				}
+				row[x] = square
+			}
+			m.Grid = append(m.Grid, row)
+			continue
+		}
+
+		var err error
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "name" && len(fields) >= 2:
+			m.Name = strings.Join(fields[1:], " ")
+		case fields[0] == "spawn" && len(fields) == 4:
+			var spawn Spawn
+			_, err = fmt.Sscan(fields[1]+" "+fields[2], &spawn.Position.X, &spawn.Position.Y)
+			if err == nil {
+				if len(fields[3]) != 1 {
+					err = fmt.Errorf("invalid direction %q", fields[3])
+				} else {
+					spawn.Direction, err = parseDirection(fields[3][0])
+				}
+			}
+			if spawn.Direction == (Vector{}) && err == nil {
+				err = fmt.Errorf("spawn needs a direction")
+			}
+			m.Spawns = append(m.Spawns, spawn)
+		case fields[0] == "tile" && len(fields) == 3 && len(fields[1]) == 1:
+			square, ok := tileKinds[fields[2]]
+			if !ok {
+				err = fmt.Errorf("unknown tile kind %q", fields[2])
+			}
+			if _, isSpawn := spawnChars[fields[1][0]]; isSpawn || isPortalChar(fields[1][0]) {
+				err = fmt.Errorf("%q marks a spawn point or portal", fields[1])
+			}
+			tileChars[fields[1][0]] = square
+		case len(fields) == 1 && fields[0] == "wrap":
+			m.Wrap = true
+		case len(fields) == 1 && fields[0] == "grid":
+			inGrid = true
+		default:
+			err = fmt.Errorf("unexpected %q", line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !inGrid {
+		return nil, fmt.Errorf("map has no grid section")
+	}
+
+	m.Spawns = append(m.Spawns, markers...)
+	if err := m.linkPortals(portals); err != nil {
+		return nil, err
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MapFromImage reads a map drawn as an image, one pixel per square.
+func MapFromImage(name string, img image.Image) (*Map, error) {
+	m := &Map{Name: name}
+	portals := map[int][]Vector{}
+	bounds := img.Bounds()
+	for y := range bounds.Dy() {
+		row := make([]Square, bounds.Dx())
+		for x := range bounds.Dx() {
+			c := color.RGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+			if dir, ok := spawnColors[c]; ok {
+				m.Spawns = append(m.Spawns, Spawn{Position: Vector{X: x, Y: y}, Direction: dir})
+				row[x] = Open
+				continue
+			}
+			if isPortalColor(c) {
+				portals[int(c.G)] = append(portals[int(c.G)], Vector{X: x, Y: y})
+				row[x] = Portal
+				continue
+			}
+			square, ok := tileColors[c]
+			if !ok {
+				return nil, fmt.Errorf("pixel (%d, %d): unknown color %v", x, y, c)
+			}
+			row[x] = square
+		}
+		m.Grid = append(m.Grid, row)
+	}
+	if err := m.linkPortals(portals); err != nil {
+		return nil, err
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// check makes sure the map is a rectangle and every spawn point is on an
+// open square, so the map can be played.
+func (m *Map) check() error {
+	if len(m.Grid) == 0 || len(m.Grid[0]) == 0 {
+		return fmt.Errorf("map %s is empty", m.Name)
+	}
+	for y, row := range m.Grid {
+		if len(row) != m.Width() {
+			return fmt.Errorf("map %s: row %d has %d squares, want %d", m.Name, y, len(row), m.Width())
+		}
+	}
+	seen := map[Vector]bool{}
+	for _, s := range m.Spawns {
+		p := s.Position
+		if p.X < 0 || p.X >= m.Width() || p.Y < 0 || p.Y >= m.Height() || m.Grid[p.Y][p.X] != Open {
+			return fmt.Errorf("map %s: spawn point (%d, %d) is not on an open square", m.Name, p.X, p.Y)
+		}
+		if seen[p] {
+			return fmt.Errorf("map %s: two spawn points at (%d, %d)", m.Name, p.X, p.Y)
+		}
+		seen[p] = true
+	}
+	return nil
+}
+
+// LoadMap reads a map file, which is a PNG image if its name ends in .png
+// and a text map otherwise.
+func LoadMap(path string) (*Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		img, err := png.Decode(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		m, err := MapFromImage(name, img)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return m, nil
+	}
+	m, err := ReadMap(name, f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// isMapFile reports whether LoadMapDir should read the file.
+func isMapFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".txt", ".map", ".png":
+		return true
+	}
+	return false
+}
+
+// LoadMapDir reads every map file in a directory, in order of file name.
+func LoadMapDir(dir string) ([]*Map, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	maps := []*Map{}
+	for _, e := range entries {
+		if e.IsDir() || !isMapFile(e.Name()) {
+			continue
+		}
+		m, err := LoadMap(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		maps = append(maps, m)
+	}
+	return maps, nil
+}
@@ -0,0 +1,322 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadMap(t *testing.T) {
+	text := `gocycle map
+name Two Rooms
+spawn 1 1 R
+tile + wall
+grid
+#######
+#..+.<#
+#^.+..#
+#######
+`
+	m, err := ReadMap("tworooms", strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ReadMap failed: %v", err)
+	}
+	if m.Name != "Two Rooms" || m.Width() != 7 || m.Height() != 4 {
+		t.Errorf("got map %q of size %dx%d, want \"Two Rooms\" of size 7x4", m.Name, m.Width(), m.Height())
+	}
+	if m.Grid[1][3] != Wall || m.Grid[2][1] != Open {
+		t.Errorf("squares not read correctly: %v", m.Grid)
+	}
+
+	// Spawn lines come first, then the markers in reading order
+	expectedSpawns := []Spawn{
+		{Position: Vector{X: 1, Y: 1}, Direction: Right},
+		{Position: Vector{X: 5, Y: 1}, Direction: Left},
+		{Position: Vector{X: 1, Y: 2}, Direction: Up},
+	}
+	if !reflect.DeepEqual(m.Spawns, expectedSpawns) {
+		t.Errorf("spawns: got %v, want %v", m.Spawns, expectedSpawns)
+	}
+
+	players := m.NewPlayers([]PlayerController{&mockController{}, &mockController{}})
+	if len(players) != 2 || players[1].ID != 2 || players[1].Position != (Vector{X: 5, Y: 1}) || players[1].Direction != Left {
+		t.Errorf("players not placed on the spawn points: %+v", players)
+	}
+}
+
+func TestReadMapErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing header":     "grid\n###\n",
+		"no grid":            "gocycle map\nname Empty\n",
+		"unknown square":     "gocycle map\ngrid\n#?#\n",
+		"ragged rows":        "gocycle map\ngrid\n###\n#.\n",
+		"spawn in wall":      "gocycle map\nspawn 0 0 R\ngrid\n###\n#.#\n###\n",
+		"spawn off the map":  "gocycle map\nspawn 5 1 R\ngrid\n###\n#.#\n###\n",
+		"spawn without dir":  "gocycle map\nspawn 1 1 .\ngrid\n###\n#.#\n###\n",
+		"unknown tile kind":  "gocycle map\ntile + lava\ngrid\n###\n",
+		"duplicate spawns":   "gocycle map\nspawn 1 1 R\ngrid\n###\n#>#\n###\n",
+		"tile is spawn char": "gocycle map\ntile < wall\ngrid\n###\n",
+	}
+	for name, text := range tests {
+		if _, err := ReadMap(name, strings.NewReader(text)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// newMapImage draws a 5x4 arena with a wall around it and two spawn points.
+func newMapImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 5, 4))
+	for y := range 4 {
+		for x := range 5 {
+			c := color.RGBA{A: 255}
+			if x == 0 || y == 0 || x == 4 || y == 3 {
+				c = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	img.Set(3, 1, color.RGBA{R: 0, G: 0, B: 255, A: 255})   // Left
+	img.Set(1, 2, color.RGBA{R: 255, G: 255, B: 0, A: 255}) // Right
+	return img
+}
+
+func TestMapFromImage(t *testing.T) {
+	m, err := MapFromImage("small", newMapImage())
+	if err != nil {
+		t.Fatalf("MapFromImage failed: %v", err)
+	}
+	if m.Width() != 5 || m.Height() != 4 || m.Grid[0][0] != Wall || m.Grid[1][1] != Open {
+		t.Errorf("squares not read correctly: %v", m.Grid)
+	}
+	expectedSpawns := []Spawn{
+		{Position: Vector{X: 3, Y: 1}, Direction: Left},
+		{Position: Vector{X: 1, Y: 2}, Direction: Right},
+	}
+	if !reflect.DeepEqual(m.Spawns, expectedSpawns) {
+		t.Errorf("spawns: got %v, want %v", m.Spawns, expectedSpawns)
+	}
+
+	img := newMapImage()
+	img.Set(2, 2, color.RGBA{R: 10, G: 20, B: 30, A: 255})
+	if _, err := MapFromImage("bad", img); err == nil {
+		t.Errorf("expected an error for an unknown color")
+	}
+}
+
+func TestLoadMapDir(t *testing.T) {
+	dir := t.TempDir()
+	text := "gocycle map\ngrid\n####\n#>.#\n#.<#\n####\n"
+	if err := os.WriteFile(filepath.Join(dir, "b-text.txt"), []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, "a-image.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, newMapImage()); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	// Files that aren't maps are skipped
+	if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("not a map"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	maps, err := LoadMapDir(dir)
+	if err != nil {
+		t.Fatalf("LoadMapDir failed: %v", err)
+	}
+	if len(maps) != 2 || maps[0].Name != "a-image" || maps[1].Name != "b-text" {
+		t.Fatalf("expected maps a-image and b-text, got %v", maps)
+	}
+	if len(maps[1].Spawns) != 2 || maps[1].Width() != 4 {
+		t.Errorf("text map not read correctly: %+v", maps[1])
+	}
+}
+
+func TestBuiltinMaps(t *testing.T) {
+	if len(Maps()) != 5 {
+		t.Fatalf("expected 5 built-in maps, got %d", len(Maps()))
+	}
+	for _, m := range Maps() {
+		if len(m.Spawns) != 4 {
+			t.Errorf("map %s: expected 4 spawn points, got %d", m.Name, len(m.Spawns))
+		}
+	}
+	if FindMap("Rooms") != GetMap(2) {
+		t.Errorf("FindMap didn't find the Rooms map")
+	}
+	if len(MapsForPlayers(5)) != 0 || len(MapsForPlayers(4)) != 5 {
+		t.Errorf("MapsForPlayers counted the spawn points wrongly")
+	}
+}
+
+// newMapArena sets up an arena on a text map, with a player at each spawn
+// point steered by the given controllers.
+func newMapArena(t *testing.T, text string, controllers ...PlayerController) *Arena {
+	t.Helper()
+	m, err := ReadMap("test", strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ReadMap failed: %v", err)
+	}
+	return NewArenaFromMap(m, m.NewPlayers(controllers), NewRand(1))
+}
+
+func TestReadMapSpecialTiles(t *testing.T) {
+	m, err := ReadMap("tiles", strings.NewReader("gocycle map\nwrap\ngrid\n1.*\n#.1\n"))
+	if err != nil {
+		t.Fatalf("ReadMap failed: %v", err)
+	}
+	if !m.Wrap {
+		t.Errorf("expected the map to wrap")
+	}
+	if m.Grid[0][0] != Portal || m.Grid[1][2] != Portal || m.Grid[0][2] != SpeedPad {
+		t.Errorf("squares not read correctly: %v", m.Grid)
+	}
+	expectedPortals := map[Vector]Vector{{X: 0, Y: 0}: {X: 2, Y: 1}, {X: 2, Y: 1}: {X: 0, Y: 0}}
+	if !reflect.DeepEqual(m.Portals, expectedPortals) {
+		t.Errorf("portals: got %v, want %v", m.Portals, expectedPortals)
+	}
+
+	if _, err := ReadMap("bad", strings.NewReader("gocycle map\ngrid\n1..\n#.#\n")); err == nil {
+		t.Errorf("expected an error for a portal with one end")
+	}
+}
+
+func TestWrapAround(t *testing.T) {
+	arena := newMapArena(t, "gocycle map\nwrap\ngrid\n#.#\n.>.\n#.#\n", &forcedController{Dir: Right})
+	p := arena.Players[0]
+
+	arena.Update()
+	arena.Update() // Off the right edge and back in on the left
+
+	if !p.IsAlive || p.Position != (Vector{X: 0, Y: 1}) {
+		t.Errorf("expected player at (0, 1) after wrapping, got %v (alive %v)", p.Position, p.IsAlive)
+	}
+
+	arena.Update() // Into its own trail
+	if p.IsAlive {
+		t.Errorf("expected player to hit its own trail after wrapping all the way around")
+	}
+}
+
+func TestPortal(t *testing.T) {
+	text := `gocycle map
+grid
+#######
+#>1####
+#####1.
+#######
+`
+	arena := newMapArena(t, text, &forcedController{Dir: Right})
+	p := arena.Players[0]
+
+	arena.Update()
+
+	// In at (2, 1), out of (5, 2) and on to the next square
+	if !p.IsAlive || p.Position != (Vector{X: 6, Y: 2}) {
+		t.Fatalf("expected player at (6, 2) after the portal, got %v (alive %v)", p.Position, p.IsAlive)
+	}
+	if arena.Grid[1][2] != Portal || arena.Grid[2][5] != Portal {
+		t.Errorf("portals should not become part of the trail")
+	}
+	if arena.Grid[2][6] != Square(1) || arena.Grid[1][1] != Square(1) {
+		t.Errorf("trail not drawn on both sides of the portal")
+	}
+}
+
+func TestSpeedPad(t *testing.T) {
+	arena := newMapArena(t, "gocycle map\ngrid\n#######\n#>*...#\n#######\n", &forcedController{Dir: Right})
+	p := arena.Players[0]
+
+	arena.Update() // Onto the pad
+	if !p.Boosted {
+		t.Fatalf("expected player to be boosted on the speed pad")
+	}
+
+	arena.Update() // Two squares at once
+	expectedPath := []Vector{{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 3, Y: 1}, {X: 4, Y: 1}}
+	if p.Position != (Vector{X: 4, Y: 1}) || !reflect.DeepEqual(p.Path, expectedPath) || p.Boosted {
+		t.Errorf("boosted move went wrong: position %v, path %v, boosted %v", p.Position, p.Path, p.Boosted)
+	}
+
+	arena.Update()
+	arena.Update() // Into the wall
+	if p.IsAlive {
+		t.Fatalf("expected player to hit the wall")
+	}
+	if arena.Grid[1][2] != SpeedPad {
+		t.Errorf("speed pad should come back when the trail is cleared, got %v", arena.Grid[1][2])
+	}
+}
+
+func TestSpeedPadHeadOnCollision(t *testing.T) {
+	// The boosted player drives through the square the other player moves
+	// to, though they end up on different squares
+	text := `gocycle map
+grid
+#######
+#>*...#
+###.###
+###^###
+#######
+`
+	arena := newMapArena(t, text, &forcedController{Dir: Right}, &forcedController{Dir: Up})
+
+	arena.Update()
+	if !arena.Players[0].IsAlive || !arena.Players[1].IsAlive {
+		t.Fatalf("expected both players to survive the first move")
+	}
+	arena.Update()
+
+	if arena.Players[0].IsAlive || arena.Players[1].IsAlive {
+		t.Errorf("expected both players to die")
+	}
+}
+
+func TestFindClosestAssignmentsThroughPortal(t *testing.T) {
+	// Player 1 is next to a portal leading into player 2's corridor, so it
+	// reaches the near end of that corridor first
+	text := `gocycle map
+spawn 1 1 R
+spawn 8 2 L
+grid
+##########
+#.1#######
+#####1...#
+##########
+`
+	arena := newMapArena(t, text, &mockController{}, &mockController{})
+	distances, assignments := arena.findClosestAssignments()
+
+	if distances[2][6] != 1 || assignments[2][6] != 1 {
+		t.Errorf("square (6, 2): got distance %d owner %d, want distance 1 owner 1", distances[2][6], assignments[2][6])
+	}
+	if assignments[2][7] != 2 {
+		t.Errorf("square (7, 2): got owner %d, want 2", assignments[2][7])
+	}
+}
+
+func TestMinimaxAvoidsBoostedCrash(t *testing.T) {
+	// Going right only moves one square without the boost, so it would be
+	// safe, but the boost carries the player into the wall
+	text := "gocycle map\ngrid\n#####\n#*.##\n#...#\n#...#\n#####\n"
+	m, err := ReadMap("test", strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPlayer(1, Vector{X: 1, Y: 1}, Right, &mockController{})
+	p.Boosted = true
+	arena := NewArenaFromMap(m, []*Player{p}, NewRand(1))
+
+	mac := &MinimaxAreaController{MaxDepth: 2}
+	if moves := mac.getSafeMoves(arena, 1); !reflect.DeepEqual(moves, []Vector{Down}) {
+		t.Errorf("expected only Down to be safe, got %v", moves)
+	}
+}
@@ -0,0 +1,536 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// netplay.go lets players on other computers join a game over TCP. The
+// computer that hosts the game runs the arena; each other computer only
+// sends its player's direction, and gets back the directions every player
+// took on each tick. Since a round plays out exactly the same given its
+// seed and every tick's directions, as for a replay, the clients can play
+// the ticks on their own copy of the arena and stay in step with the host.
+//
+// The protocol is one message per line. A client starts with
+//
+//	hello <name>
+//
+// and then sends
+//
+//	move <tick> <D>
+//
+// with the direction (U, D, L or R) it wants on each tick of a round,
+// numbered from 0. The host sends
+//
+//	lobby <name> <name> ...
+//
+// whenever someone joins or leaves, then for each round
+//
+//	round <seat> <info>
+//
+// followed by the header of the round's replay file up to its "ticks" line,
+// and then
+//
+//	tick <tick> <directions>
+//
+// after each tick, with a direction character for each player as in a
+// replay file. The seat is the index of the client's player, or -1 if it
+// is only watching, and the info is whatever the host wants to tell the
+// clients about the round, without spaces.
+
+// The host doesn't wait for moves: if a client's move for a tick hasn't
+// arrived by the time the tick is played, its player carries straight on,
+// and the move is ignored when it turns up.
+
+const (
+	netDialTimeout = 5 * time.Second
+	// how many messages the host keeps for a client that is slow to read
+	// them before it gives up on the client
+	netQueueSize = 256
+)
+
+// Host accepts clients and sends them the rounds it plays.
+type Host struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	clients []*hostClient
+	// the next tick to be played in the current round
+	tick int
+}
+
+type hostClient struct {
+	name string
+	conn net.Conn
+	// messages waiting to be written to conn
+	out chan string
+	// closed when the client is disconnected
+	done chan struct{}
+	// moves received for the current round, by tick
+	moves     map[int]Vector
+	connected bool
+}
+
+// write sends the client's messages in order, so that a slow client
+// doesn't hold up the host, and closes the connection once the client is
+// disconnected.
+func (c *hostClient) write() {
+	defer c.conn.Close()
+	for {
+		select {
+		case message := <-c.out:
+			if !c.writeMessage(message) {
+				return
+			}
+		case <-c.done:
+			// Send what was queued before the disconnect
+			for {
+				select {
+				case message := <-c.out:
+					if !c.writeMessage(message) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *hostClient) writeMessage(message string) bool {
+	c.conn.SetWriteDeadline(time.Now().Add(netDialTimeout))
+	_, err := c.conn.Write([]byte(message))
+	return err == nil
+}
+
+// Listen starts hosting on the given address, such as ":7777". Clients
+// can join until the host is closed.
+func Listen(addr string) (*Host, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	h := &Host{listener: listener}
+	go h.accept()
+	return h, nil
+}
+
+// Addr is the address the host is listening on.
+func (h *Host) Addr() string {
+	return h.listener.Addr().String()
+}
+
+func (h *Host) accept() {
+	for {
+		conn, err := h.listener.Accept()
+		if err != nil {
+			// The listener was closed
+			return
+		}
+		go h.serve(conn)
+	}
+}
+
+// serve reads the messages from one client until it disconnects.
+func (h *Host) serve(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	if !scanner.Scan() {
+		conn.Close()
+		return
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) != 2 || fields[0] != "hello" {
+		conn.Close()
+		return
+	}
+
+	c := &hostClient{
+		name:      fields[1],
+		conn:      conn,
+		out:       make(chan string, netQueueSize),
+		done:      make(chan struct{}),
+		moves:     map[int]Vector{},
+		connected: true,
+	}
+	go c.write()
+	h.mu.Lock()
+	h.clients = append(h.clients, c)
+	h.mu.Unlock()
+	h.sendLobby()
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[0] != "move" || len(fields[2]) != 1 {
+			continue
+		}
+		tick, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		dir, err := parseDirection(fields[2][0])
+		if err != nil {
+			continue
+		}
+		h.mu.Lock()
+		// A move for a tick that has already been played is too late
+		if tick >= h.tick {
+			c.moves[tick] = dir
+		}
+		h.mu.Unlock()
+	}
+
+	h.mu.Lock()
+	h.disconnectLocked(c)
+	h.mu.Unlock()
+	h.sendLobby()
+}
+
+// disconnectLocked tells the client's writer to stop, which closes the
+// connection once the messages already queued have been sent.
+func (h *Host) disconnectLocked(c *hostClient) {
+	if c.connected {
+		c.connected = false
+		close(c.done)
+	}
+}
+
+// send queues a message for a client. A client that has fallen too far
+// behind is disconnected. It must be called without h.mu held.
+func (h *Host) send(c *hostClient, message string) {
+	select {
+	case c.out <- message:
+		return
+	default:
+	}
+	h.mu.Lock()
+	wasConnected := c.connected
+	h.disconnectLocked(c)
+	h.mu.Unlock()
+	if wasConnected {
+		// Don't wait for the writer to get through the queue
+		c.conn.Close()
+		h.sendLobby()
+	}
+}
+
+func (h *Host) sendLobby() {
+	h.mu.Lock()
+	message := "lobby"
+	for _, name := range h.clientNamesLocked() {
+		message += " " + name
+	}
+	message += "\n"
+	clients := h.connectedLocked()
+	h.mu.Unlock()
+	for _, c := range clients {
+		h.send(c, message)
+	}
+}
+
+func (h *Host) clientNamesLocked() []string {
+	names := []string{}
+	for _, c := range h.clients {
+		if c.connected {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// Clients returns the names of the connected clients, in the order they
+// joined. A client's number in the other methods is its index in this list
+// when the round starts.
+func (h *Host) Clients() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.clientNamesLocked()
+}
+
+// connectedLocked returns the connected clients, in the order they joined.
+func (h *Host) connectedLocked() []*hostClient {
+	result := []*hostClient{}
+	for _, c := range h.clients {
+		if c.connected {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// StartRound tells the clients about a new round, before any ticks have
+// been played. seats[i] is the index of the player that client i steers,
+// or -1 if it only watches.
+func (h *Host) StartRound(r *Replay, seats []int, info string) error {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	if err := r.writeHeader(bw); err != nil {
+		return err
+	}
+	bw.Flush()
+
+	h.mu.Lock()
+	h.tick = 0
+	// Clients that left are no longer listed, so drop them
+	h.clients = h.connectedLocked()
+	clients := h.clients
+	for _, c := range clients {
+		c.moves = map[int]Vector{}
+	}
+	h.mu.Unlock()
+
+	for i, c := range clients {
+		seat := -1
+		if i < len(seats) {
+			seat = seats[i]
+		}
+		h.send(c, fmt.Sprintf("round %d %s\n%s", seat, info, buf.String()))
+	}
+	return nil
+}
+
+// SendTick tells the clients the directions every player took on the tick
+// just played, as recorded in the replay.
+func (h *Host) SendTick(dirs []Vector) error {
+	line := make([]byte, len(dirs))
+	if err := formatTick(line, dirs); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	message := fmt.Sprintf("tick %d %s\n", h.tick, line)
+	h.tick++
+	clients := h.connectedLocked()
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		h.send(c, message)
+	}
+	return nil
+}
+
+// Controller returns a controller that steers a player with the moves
+// sent by a client.
+func (h *Host) Controller(client int) PlayerController {
+	return &RemoteController{host: h, client: client}
+}
+
+// Close stops accepting clients and disconnects the ones there are.
+func (h *Host) Close() error {
+	err := h.listener.Close()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range h.clients {
+		h.disconnectLocked(c)
+	}
+	return err
+}
+
+// RemoteController steers a player with the moves a client sends to the
+// host. If the move for a tick is late, or the client has gone, the player
+// carries on in the same direction.
+type RemoteController struct {
+	host   *Host
+	client int
+}
+
+func (rc *RemoteController) GetDirection(arena *Arena, playerID int) Vector {
+	h := rc.host
+	current := arena.Players[playerID-1].Direction
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if rc.client >= len(h.clients) {
+		return current
+	}
+	c := h.clients[rc.client]
+	dir, ok := c.moves[h.tick]
+	for tick := range c.moves {
+		if tick <= h.tick {
+			delete(c.moves, tick)
+		}
+	}
+	if !ok || IsOpposite(current, dir) {
+		return current
+	}
+	return dir
+}
+
+// NetMessageKind says what a NetMessage is about.
+type NetMessageKind int
+
+const (
+	// The clients connected to the host have changed
+	NetLobby NetMessageKind = iota
+	// A new round is starting
+	NetRoundStart
+	// A tick of the round has been played
+	NetTick
+	// The connection to the host has closed
+	NetClosed
+)
+
+// NetMessage is something the host has told a client.
+type NetMessage struct {
+	Kind NetMessageKind
+	// For NetLobby, the names of the connected clients
+	Names []string
+	// For NetRoundStart, the new round
+	Round *NetRound
+	// For NetTick, the tick number and every player's direction
+	Tick int
+	Dirs []Vector
+	// For NetClosed, why the connection closed, or nil if the host closed it
+	Err error
+}
+
+// NetRound is a round being played on the host, as a client sees it.
+type NetRound struct {
+	// The round so far, with every tick received
+	Replay *Replay
+	// The client's copy of the arena
+	Arena *Arena
+	// Index of the client's player, or -1 if it is only watching
+	Seat int
+	// Whatever the host said about the round
+	Info string
+}
+
+// Apply plays the next tick of the round on the client's arena.
+func (nr *NetRound) Apply(tick int, dirs []Vector) error {
+	if tick != len(nr.Replay.Ticks) {
+		return fmt.Errorf("got tick %d, expected %d", tick, len(nr.Replay.Ticks))
+	}
+	if len(dirs) != len(nr.Arena.Players) {
+		return fmt.Errorf("tick %d has %d directions for %d players", tick, len(dirs), len(nr.Arena.Players))
+	}
+	nr.Replay.Ticks = append(nr.Replay.Ticks, dirs)
+	nr.Arena.Update()
+	return nil
+}
+
+// Client is a connection to a host.
+type Client struct {
+	conn     net.Conn
+	messages chan NetMessage
+	// guards writes to conn
+	mu sync.Mutex
+}
+
+// Join connects to a host. The name, which can't contain spaces, is shown
+// to the host and the other clients.
+func Join(addr, name string) (*Client, error) {
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return nil, fmt.Errorf("invalid name %q", name)
+	}
+	conn, err := net.DialTimeout("tcp", addr, netDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, messages: make(chan NetMessage, 64)}
+	if err := c.send("hello %s\n", name); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go c.receive()
+	return c, nil
+}
+
+func (c *Client) send(format string, args ...any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := fmt.Fprintf(c.conn, format, args...)
+	return err
+}
+
+// Messages delivers what the host sends, ending with a NetClosed message
+// when the connection closes.
+func (c *Client) Messages() <-chan NetMessage {
+	return c.messages
+}
+
+// SendMove asks for the client's player to go in a direction on a tick.
+// If the host has already played the tick, the move is ignored.
+func (c *Client) SendMove(tick int, dir Vector) error {
+	ch, err := directionChar(dir)
+	if err != nil {
+		return err
+	}
+	return c.send("move %d %c\n", tick, ch)
+}
+
+// Close disconnects from the host.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) receive() {
+	defer close(c.messages)
+	scanner := bufio.NewScanner(c.conn)
+	var err error
+	for err == nil && scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) >= 1 && fields[0] == "lobby":
+			c.messages <- NetMessage{Kind: NetLobby, Names: fields[1:]}
+		case len(fields) >= 2 && fields[0] == "round":
+			var round *NetRound
+			round, err = readNetRound(fields[1:], scanner)
+			if err == nil {
+				c.messages <- NetMessage{Kind: NetRoundStart, Round: round}
+			}
+		case len(fields) == 3 && fields[0] == "tick":
+			var tick int
+			var dirs []Vector
+			tick, err = strconv.Atoi(fields[1])
+			if err == nil {
+				dirs, err = parseTick(fields[2])
+			}
+			if err == nil {
+				c.messages <- NetMessage{Kind: NetTick, Tick: tick, Dirs: dirs}
+			}
+		default:
+			err = fmt.Errorf("unexpected message %q", scanner.Text())
+		}
+	}
+	if err == nil {
+		err = scanner.Err()
+	}
+	c.conn.Close()
+	c.messages <- NetMessage{Kind: NetClosed, Err: err}
+}
+
+// readNetRound reads the replay header that follows a "round" message.
+func readNetRound(args []string, scanner *bufio.Scanner) (*NetRound, error) {
+	seat, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid seat %q", args[0])
+	}
+	info := ""
+	if len(args) > 1 {
+		info = args[1]
+	}
+
+	var header strings.Builder
+	for scanner.Scan() {
+		header.WriteString(scanner.Text() + "\n")
+		if scanner.Text() == "ticks" {
+			break
+		}
+	}
+	replay, err := ReadReplay(strings.NewReader(header.String()))
+	if err != nil {
+		return nil, err
+	}
+	if seat >= len(replay.Starts) {
+		return nil, fmt.Errorf("seat %d, but only %d players", seat, len(replay.Starts))
+	}
+	return &NetRound{Replay: replay, Arena: replay.NewArena(), Seat: seat, Info: info}, nil
+}
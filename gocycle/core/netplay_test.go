@@ -0,0 +1,259 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitFor polls until cond is true, failing the test if it takes too long.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// hasMove reports whether the host has a move from its first client for
+// the tick.
+func hasMove(h *Host, tick int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.clients) == 0 {
+		return false
+	}
+	_, ok := h.clients[0].moves[tick]
+	return ok
+}
+
+// nextMessage waits for a message of the given kind, skipping lobby updates.
+func nextMessage(t *testing.T, c *Client, kind NetMessageKind) NetMessage {
+	t.Helper()
+	for {
+		select {
+		case msg := <-c.Messages():
+			if msg.Kind == kind {
+				return msg
+			}
+			if msg.Kind != NetLobby {
+				t.Fatalf("expected message kind %d, got %+v", kind, msg)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for message kind %d", kind)
+		}
+	}
+}
+
+// startNetRound hosts a round on map m where player 1 is steered by the
+// given controller and player 2 by the first client to join.
+func startNetRound(t *testing.T, h *Host, m *Map, local PlayerController) (*Arena, *Replay) {
+	t.Helper()
+	waitFor(t, "a client to join", func() bool { return len(h.Clients()) == 1 })
+	players := m.NewPlayers([]PlayerController{local, h.Controller(0)})
+	arena := NewArenaFromMap(m, players, NewRand(3))
+	replay := NewReplay(m, 3, players, []string{"host", "client"})
+	arena.Record(replay)
+	if err := h.StartRound(replay, []int{1}, "info"); err != nil {
+		t.Fatal(err)
+	}
+	return arena, replay
+}
+
+func TestRemoteControllerLateMove(t *testing.T) {
+	h, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	c, err := Join(h.Addr(), "late")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	arena, replay := startNetRound(t, h, GetMap(0), &forcedController{Dir: Down})
+	round := nextMessage(t, c, NetRoundStart).Round
+	if round.Seat != 1 || round.Info != "info" || round.Replay.Map != GetMap(0) {
+		t.Fatalf("unexpected round %+v", round)
+	}
+	p := arena.Players[1]
+	start := p.Direction
+
+	// On time
+	c.SendMove(0, start.TurnLeft())
+	waitFor(t, "move 0", func() bool { return hasMove(h, 0) })
+	arena.Update()
+	h.SendTick(replay.Ticks[0])
+	if p.Direction != start.TurnLeft() {
+		t.Errorf("expected the move for tick 0 to be used")
+	}
+
+	// Too late for tick 0, and nothing for tick 1; the move for tick 2
+	// arrives after the late one, as they are sent in order
+	c.SendMove(0, start)
+	c.SendMove(2, start)
+	waitFor(t, "move 2", func() bool { return hasMove(h, 2) })
+	if hasMove(h, 0) {
+		t.Errorf("the late move should have been ignored")
+	}
+	arena.Update()
+	h.SendTick(replay.Ticks[1])
+	if p.Direction != start.TurnLeft() {
+		t.Errorf("expected the player to carry on when its move is missing, got %v", p.Direction)
+	}
+	arena.Update()
+	h.SendTick(replay.Ticks[2])
+	if p.Direction != start {
+		t.Errorf("expected the move for tick 2 to be used")
+	}
+
+	// The client sees the same round
+	for tick := range 3 {
+		msg := nextMessage(t, c, NetTick)
+		if err := round.Apply(msg.Tick, msg.Dirs); err != nil {
+			t.Fatalf("tick %d: %v", tick, err)
+		}
+	}
+	for i, p := range round.Arena.Players {
+		if !reflect.DeepEqual(p.Path, arena.Players[i].Path) {
+			t.Errorf("player %d: client path %v, host path %v", p.ID, p.Path, arena.Players[i].Path)
+		}
+	}
+}
+
+func TestHostDropsSlowClient(t *testing.T) {
+	h, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	// A client that joins and then never reads what the host sends
+	conn, err := net.Dial("tcp", h.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "hello slow\n")
+	waitFor(t, "the client to join", func() bool { return len(h.Clients()) == 1 })
+
+	// Big enough ticks to fill the socket buffers and then the queue
+	dirs := make([]Vector, 10000)
+	for i := range dirs {
+		dirs[i] = Up
+	}
+	start := time.Now()
+	for range 1000 {
+		if err := h.SendTick(dirs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= netDialTimeout {
+		t.Errorf("sending took %v; the host waited for the slow client", elapsed)
+	}
+	waitFor(t, "the client to be dropped", func() bool { return len(h.Clients()) == 0 })
+}
+
+// TestNetworkTwoProcesses plays a round against a client in another
+// process, which runs TestNetworkClientProcess, and checks that both ends
+// saw the same round.
+func TestNetworkTwoProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts another process")
+	}
+	h, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	out := filepath.Join(t.TempDir(), "client.replay")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestNetworkClientProcess$")
+	cmd.Env = append(os.Environ(), "GOCYCLE_NET_HOST="+h.Addr(), "GOCYCLE_NET_OUT="+out)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	arena, replay := startNetRound(t, h, GetMap(0), &WallHuggerController{})
+	for tick := 0; arena.Players[0].IsAlive && arena.Players[1].IsAlive; tick++ {
+		// Give the client a moment, but play on without its move if it is late
+		deadline := time.Now().Add(200 * time.Millisecond)
+		for !hasMove(h, tick) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		arena.Update()
+		if err := h.SendTick(replay.Ticks[tick]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("client process failed: %v\n%s", err, output.String())
+	}
+	clientReplay, err := LoadReplay(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(clientReplay.Ticks, replay.Ticks) {
+		t.Errorf("client saw different ticks from the host")
+	}
+	clientArena := clientReplay.NewArena()
+	for range clientReplay.Ticks {
+		clientArena.Update()
+	}
+	for i, p := range clientArena.Players {
+		if !reflect.DeepEqual(p.Path, arena.Players[i].Path) {
+			t.Errorf("player %d: paths differ between host and client", p.ID)
+		}
+	}
+}
+
+// TestNetworkClientProcess is the client for TestNetworkTwoProcesses. It
+// does nothing when run normally.
+func TestNetworkClientProcess(t *testing.T) {
+	addr := os.Getenv("GOCYCLE_NET_HOST")
+	if addr == "" {
+		t.Skip("only run by TestNetworkTwoProcesses")
+	}
+	c, err := Join(addr, "process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	controller := &RandomAvoidingController{}
+	round := nextMessage(t, c, NetRoundStart).Round
+	me := round.Seat + 1
+	c.SendMove(0, controller.GetDirection(round.Arena, me))
+	for {
+		msg := nextMessage(t, c, NetTick)
+		if err := round.Apply(msg.Tick, msg.Dirs); err != nil {
+			t.Fatal(err)
+		}
+		alive := 0
+		for _, p := range round.Arena.Players {
+			if p.IsAlive {
+				alive++
+			}
+		}
+		if alive <= 1 {
+			break
+		}
+		c.SendMove(msg.Tick+1, controller.GetDirection(round.Arena, me))
+	}
+	if err := round.Replay.Save(os.Getenv("GOCYCLE_NET_OUT")); err != nil {
+		t.Fatal(err)
+	}
+}
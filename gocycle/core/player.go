@@ -1,9 +1,13 @@
 package core
 
 import (
-	"math/rand"
+	"math/rand/v2"
+	"slices"
 )
 
+// PlayerController chooses a player's direction each tick. Controllers
+// that make random choices must draw them from arena.Rand(), so that a
+// round can be replayed from its seed.
 type PlayerController interface {
 	GetDirection(arena *Arena, playerID int) Vector
 }
@@ -14,6 +18,12 @@ type Player struct {
 	Position  Vector
 	Direction Vector
 	Path      []Vector
+	// Set when the player is on a speed pad, so its next move is two squares
+	Boosted bool
+	// Ticks left of the speed and ghost items, and every item picked up
+	SpeedTicks int
+	GhostTicks int
+	Collected  []ItemKind
 
 	Controller PlayerController
 }
@@ -74,7 +84,7 @@ type RandomController struct{}
 func (rc *RandomController) GetDirection(arena *Arena, playerID int) Vector {
 	dirs := []Vector{Up, Down, Left, Right}
 	for {
-		dir := dirs[rand.Intn(len(dirs))]
+		dir := dirs[arena.Rand().IntN(len(dirs))]
 		// don't back into ourselves
 		if !IsOpposite(arena.Players[playerID-1].Direction, dir) {
 			return dir
@@ -90,8 +100,7 @@ func (rc *RandomAvoidingController) GetDirection(arena *Arena, playerID int) Vec
 
 	safeDirs := []Vector{}
 	for _, dir := range dirs {
-		nextPos := arena.Players[playerID-1].Position.Add(dir)
-		if arena.isCollision(nextPos) {
+		if !arena.canMove(arena.Players[playerID-1], dir) {
 			continue
 		}
 		if isPossiblePlayerCollision(arena, playerID, dir) {
@@ -103,11 +112,11 @@ func (rc *RandomAvoidingController) GetDirection(arena *Arena, playerID int) Vec
 
 	if len(safeDirs) == 0 {
 		// Going to die, just pick anything
-		return dirs[rand.Intn(len(dirs))]
+		return dirs[arena.Rand().IntN(len(dirs))]
 	}
 
 	// Pick a safe direction
-	return safeDirs[rand.Intn(len(safeDirs))]
+	return safeDirs[arena.Rand().IntN(len(safeDirs))]
 }
 
 // isPossiblePlayerCollision checks if moving in a direction that may
@@ -115,8 +124,8 @@ func (rc *RandomAvoidingController) GetDirection(arena *Arena, playerID int) Vec
 func isPossiblePlayerCollision(arena *Arena, playerID int, nextDir Vector) bool {
 	player := arena.Players[playerID-1]
 
-	// Calculate the current player's proposed next position
-	nextPos := player.Position.Add(nextDir)
+	// Calculate the squares the current player would drive over
+	nextSquares := arena.moveSquares(player, nextDir)
 
 	// Check against all other alive players
 	for _, otherPlayer := range arena.Players {
@@ -128,8 +137,7 @@ func isPossiblePlayerCollision(arena *Arena, playerID int, nextDir Vector) bool
 		// Avoid moving into a square where the other player might move the
 		// next turn
 		for _, dir := range []Vector{Up, Down, Left, Right} {
-			otherNextPos := otherPlayer.Position.Add(dir)
-			if nextPos.Equals(otherNextPos) {
+			if sharesSquare(nextSquares, arena.moveSquares(otherPlayer, dir)) {
 				return true
 			}
 		}
@@ -141,51 +149,48 @@ type RandomTurnerController struct {
 	TurnProb float64
 }
 
-func getRandomDir(m map[Vector]struct{}) Vector {
-	keys := []Vector{}
-	for k, _ := range m {
-		keys = append(keys, k)
-	}
-	return keys[rand.Intn(len(keys))]
+func getRandomDir(rng *rand.Rand, dirs []Vector) Vector {
+	return dirs[rng.IntN(len(dirs))]
 }
 
 func (rt *RandomTurnerController) GetDirection(arena *Arena, playerID int) Vector {
 	player := arena.Players[playerID-1]
+	rng := arena.Rand()
 
+	// Slices rather than sets, so the choices are always made in the same order
 	dirs := []Vector{Up, Down, Left, Right}
-	safeDirs := make(map[Vector]struct{})
-	maybeSafeDirs := make(map[Vector]struct{})
+	safeDirs := []Vector{}
+	maybeSafeDirs := []Vector{}
 	for _, dir := range dirs {
-		nextPos := player.Position.Add(dir)
-		if arena.isCollision(nextPos) {
+		if !arena.canMove(player, dir) {
 			continue
 		}
 
 		if isPossiblePlayerCollision(arena, playerID, dir) {
-			maybeSafeDirs[dir] = struct{}{}
+			maybeSafeDirs = append(maybeSafeDirs, dir)
 		} else {
-			safeDirs[dir] = struct{}{}
+			safeDirs = append(safeDirs, dir)
 		}
 	}
 
 	// Occasionally move randomly
-	if rand.Float64() < rt.TurnProb && len(safeDirs) > 0 {
-		return getRandomDir(safeDirs)
+	if rng.Float64() < rt.TurnProb && len(safeDirs) > 0 {
+		return getRandomDir(rng, safeDirs)
 	}
 
 	// Try to go forward
-	if _, ok := safeDirs[player.Direction]; ok {
+	if slices.Contains(safeDirs, player.Direction) {
 		return player.Direction
 	}
 
 	if len(safeDirs) > 0 {
 		// Pick any random safe direction
-		return getRandomDir(safeDirs)
+		return getRandomDir(rng, safeDirs)
 	}
 
 	if len(maybeSafeDirs) > 0 {
 		// Pick any random maybe-safe direction
-		return getRandomDir(maybeSafeDirs)
+		return getRandomDir(rng, maybeSafeDirs)
 	}
 
 	// Player is doomed, just go forward
@@ -193,7 +198,8 @@ func (rt *RandomTurnerController) GetDirection(arena *Arena, playerID int) Vecto
 }
 
 // AreaController is a computer player that chooses the direction
-// that maximizes its controlled area (Voronoi score).
+// that maximizes its controlled area (Voronoi score), counting items as
+// extra area.
 type AreaController struct{}
 
 func (ac *AreaController) GetDirection(arena *Arena, playerID int) Vector {
@@ -208,15 +214,14 @@ func (ac *AreaController) GetDirection(arena *Arena, playerID int) Vector {
 			continue
 		}
 
-		nextPos := player.Position.Add(dir)
-
 		// 2. Check for immediate collision with walls/paths
-		if arena.isCollision(nextPos) {
+		if !arena.canMove(player, dir) {
 			continue
 		}
 		if isPossiblePlayerCollision(arena, playerID, dir) {
 			continue
 		}
+		squares := arena.moveSquares(player, dir)
 
 		// --- Core AI Logic: Simulate the move and check the score ---
 
@@ -232,10 +237,11 @@ func (ac *AreaController) GetDirection(arena *Arena, playerID int) Vector {
 
 		// Find the player in the sandbox and update its state for the simulation
 		sandboxPlayer := sandboxArena.Players[playerID-1]
-		sandboxPlayer.Position = nextPos
+		sandboxPlayer.Position = squares[len(squares)-1]
+		sandboxPlayer.Boosted = arena.tileAt(sandboxPlayer.Position) == SpeedPad
 
 		// Calculate the new controlled area score *after* the simulated move
-		scores := sandboxArena.ComputePlayerScores()
+		scores := sandboxArena.ComputePlayerValues()
 		currentScore := scores[playerID]
 
 		// Check if this move is better than the current best
@@ -261,17 +267,17 @@ func (wh *WallHuggerController) GetDirection(arena *Arena, playerID int) Vector
 	player := arena.Players[playerID-1]
 	if len(player.Path) < 2 {
 		dirs := []Vector{Up, Down, Left, Right}
-		return dirs[rand.Intn(len(dirs))]
+		return dirs[arena.Rand().IntN(len(dirs))]
 	}
 
 	dir := player.Direction
 	leftDir := dir.TurnLeft()
 	toLeftLoc := player.Position.Add(leftDir)
-	toBackLeftLoc := toLeftLoc.Subtract(dir)
+	toBackLeftLoc := arena.wrap(toLeftLoc.Subtract(dir))
 
 	rightDir := dir.TurnRight()
 	toRightLoc := player.Position.Add(rightDir)
-	toBackRightLoc := toRightLoc.Subtract(dir)
+	toBackRightLoc := arena.wrap(toRightLoc.Subtract(dir))
 
 	dirs := []Vector{}
 	if arena.isCollision(toBackLeftLoc) {
@@ -291,8 +297,7 @@ func (wh *WallHuggerController) GetDirection(arena *Arena, playerID int) Vector
 		dirs = append(dirs, leftDir)
 	}
 	for _, dir := range dirs {
-		nextPos := player.Position.Add(dir)
-		if !arena.isCollision(nextPos) {
+		if arena.canMove(player, dir) {
 			return dir
 		}
 	}
@@ -305,6 +310,9 @@ func (wh *WallHuggerController) GetDirection(arena *Arena, playerID int) Vector
 // applies a limited-depth MaxN search.
 type MinimaxAreaController struct {
 	MaxDepth int
+
+	// positions searched, for the benchmarks
+	nodes int
 }
 
 func (mac *MinimaxAreaController) GetDirection(arena *Arena, playerID int) Vector {
@@ -319,6 +327,7 @@ func (mac *MinimaxAreaController) GetDirection(arena *Arena, playerID int) Vecto
 // maxNSearch is the core recursive MaxN (Multi-Player Minimax) search.
 // It returns the max possible score for the target player and the move to achieve it.
 func (mac *MinimaxAreaController) maxNSearch(arena *Arena, depth int, targetPlayerID int) (int, Vector) {
+	mac.nodes++
 	player := arena.Players[targetPlayerID-1]
 
 	// --- Base Case: Depth 0 or Game Over ---
@@ -326,7 +335,7 @@ func (mac *MinimaxAreaController) maxNSearch(arena *Arena, depth int, targetPlay
 	isTargetAlive := player.IsAlive
 
 	if depth == 0 || numAlive <= 1 || !isTargetAlive {
-		scores := arena.ComputePlayerScores()
+		scores := arena.ComputePlayerValues()
 		// Return score and player's current direction (as a placeholder)
 		return scores[targetPlayerID], player.Direction
 	}
@@ -339,7 +348,7 @@ func (mac *MinimaxAreaController) maxNSearch(arena *Arena, depth int, targetPlay
 	if len(targetPlayerMoves) == 0 {
 		// Target player is trapped, forced to move in a death-inducing direction
 		// We'll treat this as a dead end and return the current score.
-		scores := arena.ComputePlayerScores()
+		scores := arena.ComputePlayerValues()
 		return scores[targetPlayerID], player.Direction
 	}
 
@@ -394,10 +403,9 @@ func (mac *MinimaxAreaController) getSafeMoves(arena *Arena, playerID int) []Vec
 			continue
 		}
 
-		nextPos := player.Position.Add(dir)
-
-		// 2. Check for immediate collision with walls/paths
-		if arena.isCollision(nextPos) {
+		// 2. Check for immediate collision with walls/paths, including
+		// the second square of a boosted move
+		if !arena.canMove(player, dir) {
 			continue
 		}
 
@@ -452,79 +460,10 @@ func (mac *MinimaxAreaController) applySimultaneousMoves(currentArena *Arena, ta
 		}
 	}
 
-	// 3. Run the Update logic on the sandbox (copied from Arena.Update)
-
-	newPositions := make(map[int]Vector)
-	collidedIDs := make(map[int]bool)
-
-	for _, p := range sandboxArena.Players {
-		if !p.IsAlive {
-			continue
-		}
-
-		nextDir, ok := allMoves[p.ID]
-		if !ok {
-			// Should not happen if all living players are in allMoves map
-			continue
-		}
-
-		p.Direction = nextDir
-		nextPos := p.Position.Add(p.Direction)
-		newPositions[p.ID] = nextPos
-
-		// Check for collision with walls or paths
-		if sandboxArena.isCollision(nextPos) {
-			collidedIDs[p.ID] = true
-		}
-	}
-
-	// Resolve simultaneous head-on collisions and apply movement
-	for id, pos := range newPositions {
-		if collidedIDs[id] {
-			continue
-		}
-
-		for otherID, otherPos := range newPositions {
-			if id == otherID {
-				continue
-			}
-			if pos.Equals(otherPos) {
-				collidedIDs[id] = true
-				collidedIDs[otherID] = true
-			}
-		}
-	}
-
-	// Finalize State Changes
-	playersToMove := make(map[int]*Player)
-
-	for _, p := range sandboxArena.Players {
-		if !p.IsAlive {
-			continue
-		}
-
-		if collidedIDs[p.ID] {
-			// Player dies and their trail is cleared *before* they update their position.
-			sandboxArena.clearPath(p)
-			p.IsAlive = false
-		} else {
-			playersToMove[p.ID] = p
-		}
-	}
-
-	for _, p := range playersToMove {
-		oldPos := p.Position
-		p.Position = newPositions[p.ID]
-
-		// Draw the trail segment at the old position
-		sandboxArena.Grid[oldPos.Y][oldPos.X] = Square(p.ID)
-
-		// Mark the *new* head position on the simulated grid.
-		// This ensures subsequent simulation steps correctly treat this square as occupied.
-		sandboxArena.Grid[p.Position.Y][p.Position.X] = Square(p.ID)
-
-		p.Path = append(p.Path, p.Position)
-	}
+	// 3. Run the Update logic on the sandbox
+	sandboxArena.step(func(_ int, p *Player) Vector {
+		return allMoves[p.ID]
+	})
 
 	return sandboxArena
 }
@@ -0,0 +1,267 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Replay records a round: the map and starting positions, the seed, and
+// the direction every player chose on every tick. Playing the directions
+// back on the same map reproduces the round exactly.
+type Replay struct {
+	Map  *Map
+	Seed uint64
+	// The rules for items, or nil if there were none
+	Items *ItemRules
+	// Starting state of each player, indexed by ID - 1
+	Names           []string
+	Starts          []Vector
+	StartDirections []Vector
+	// Ticks[t][i] is the direction chosen by player i+1 on tick t, or the
+	// zero vector if the player was already dead
+	Ticks [][]Vector
+}
+
+// NewReplay starts a replay of a round with the given players, before
+// any ticks have been played.
+func NewReplay(m *Map, seed uint64, players []*Player, names []string) *Replay {
+	r := &Replay{
+		Map:   m,
+		Seed:  seed,
+		Names: append([]string{}, names...),
+	}
+	for _, p := range players {
+		r.Starts = append(r.Starts, p.Position)
+		r.StartDirections = append(r.StartDirections, p.Direction)
+	}
+	return r
+}
+
+// ReplayController plays back the directions recorded for one player.
+type ReplayController struct {
+	replay *Replay
+	index  int
+	tick   int
+}
+
+func (rc *ReplayController) GetDirection(arena *Arena, playerID int) Vector {
+	// Controllers are only asked for a direction while their player is
+	// alive, which is once per tick
+	tick := rc.tick
+	rc.tick++
+	if tick < len(rc.replay.Ticks) {
+		return rc.replay.Ticks[tick][rc.index]
+	}
+	return arena.Players[playerID-1].Direction
+}
+
+// NewArena sets up the round's arena with players that repeat the recorded
+// moves. Calling Update once per recorded tick replays the round.
+func (r *Replay) NewArena() *Arena {
+	players := make([]*Player, len(r.Starts))
+	for i := range r.Starts {
+		controller := &ReplayController{replay: r, index: i}
+		players[i] = NewPlayer(i+1, r.Starts[i], r.StartDirections[i], controller)
+	}
+	arena := NewArenaFromMap(r.Map, players, NewRand(r.Seed))
+	if r.Items != nil {
+		arena.EnableItems(*r.Items)
+	}
+	return arena
+}
+
+// directionChars maps each direction to the character used in replay files.
+var directionChars = map[Vector]byte{
+	Up:    'U',
+	Down:  'D',
+	Left:  'L',
+	Right: 'R',
+	{}:    '.',
+}
+
+func directionChar(dir Vector) (byte, error) {
+	c, ok := directionChars[dir]
+	if !ok {
+		return 0, fmt.Errorf("invalid direction %v", dir)
+	}
+	return c, nil
+}
+
+func parseDirection(c byte) (Vector, error) {
+	for dir, dc := range directionChars {
+		if dc == c {
+			return dir, nil
+		}
+	}
+	return Vector{}, fmt.Errorf("invalid direction %q", c)
+}
+
+const replayHeader = "gocycle replay 1"
+
+// Write saves the replay as text: a few header lines, one line per player,
+// then one line per tick with a character for each player's direction.
+func (r *Replay) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if err := r.writeHeader(bw); err != nil {
+		return err
+	}
+	line := make([]byte, len(r.Starts))
+	for _, tick := range r.Ticks {
+		if err := formatTick(line, tick); err != nil {
+			return err
+		}
+		bw.Write(line)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// writeHeader writes everything before the ticks, up to and including the
+// "ticks" line.
+func (r *Replay) writeHeader(bw *bufio.Writer) error {
+	fmt.Fprintln(bw, replayHeader)
+	fmt.Fprintf(bw, "map %s\n", r.Map.Name)
+	fmt.Fprintf(bw, "seed %d\n", r.Seed)
+	if i := r.Items; i != nil {
+		fmt.Fprintf(bw, "items %v %d %d %d %d\n", i.SpawnChance, i.MaxItems, i.SpeedTicks, i.GhostTicks, i.BombRadius)
+	}
+	for i := range r.Starts {
+		dir, err := directionChar(r.StartDirections[i])
+		if err != nil {
+			return err
+		}
+		name := ""
+		if i < len(r.Names) {
+			name = r.Names[i]
+		}
+		fmt.Fprintf(bw, "player %d %d %c %q\n", r.Starts[i].X, r.Starts[i].Y, dir, name)
+	}
+	fmt.Fprintln(bw, "ticks")
+	return nil
+}
+
+// formatTick writes the character for each player's direction into line.
+func formatTick(line []byte, tick []Vector) error {
+	for i, dir := range tick {
+		c, err := directionChar(dir)
+		if err != nil {
+			return err
+		}
+		line[i] = c
+	}
+	return nil
+}
+
+// parseTick reads a line of direction characters.
+func parseTick(line string) ([]Vector, error) {
+	tick := make([]Vector, len(line))
+	for i := range line {
+		dir, err := parseDirection(line[i])
+		if err != nil {
+			return nil, err
+		}
+		tick[i] = dir
+	}
+	return tick, nil
+}
+
+// ReadReplay parses a replay written by Write. The map it was played on
+// must be one of the available maps.
+func ReadReplay(rd io.Reader) (*Replay, error) {
+	scanner := bufio.NewScanner(rd)
+	if !scanner.Scan() || scanner.Text() != replayHeader {
+		return nil, fmt.Errorf("not a gocycle replay")
+	}
+
+	r := &Replay{}
+	inTicks := false
+	for lineNum := 2; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if inTicks {
+			if len(line) != len(r.Starts) {
+				return nil, fmt.Errorf("line %d: want %d directions, got %q", lineNum, len(r.Starts), line)
+			}
+			tick, err := parseTick(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNum, err)
+			}
+			r.Ticks = append(r.Ticks, tick)
+			continue
+		}
+
+		var err error
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) >= 2 && fields[0] == "map":
+			name := strings.TrimSpace(strings.TrimPrefix(line, "map"))
+			if r.Map = FindMap(name); r.Map == nil {
+				err = fmt.Errorf("unknown map %q", name)
+			}
+		case len(fields) == 2 && fields[0] == "seed":
+			_, err = fmt.Sscan(fields[1], &r.Seed)
+		case len(fields) == 6 && fields[0] == "items":
+			i := &ItemRules{}
+			_, err = fmt.Sscan(strings.Join(fields[1:], " "),
+				&i.SpawnChance, &i.MaxItems, &i.SpeedTicks, &i.GhostTicks, &i.BombRadius)
+			r.Items = i
+		case len(fields) >= 5 && fields[0] == "player":
+			// the name is quoted, as it may have spaces
+			var pos Vector
+			var dir Vector
+			var dirText, name string
+			_, err = fmt.Sscanf(line, "player %d %d %s %q", &pos.X, &pos.Y, &dirText, &name)
+			if err == nil && len(dirText) != 1 {
+				err = fmt.Errorf("bad direction %q", dirText)
+			}
+			if err == nil {
+				dir, err = parseDirection(dirText[0])
+			}
+			r.Starts = append(r.Starts, pos)
+			r.StartDirections = append(r.StartDirections, dir)
+			r.Names = append(r.Names, name)
+		case len(fields) == 1 && fields[0] == "ticks":
+			inTicks = true
+		default:
+			err = fmt.Errorf("unexpected %q", line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !inTicks {
+		return nil, fmt.Errorf("replay has no ticks section")
+	}
+	if r.Map == nil {
+		return nil, fmt.Errorf("replay doesn't say which map it was played on")
+	}
+	return r, nil
+}
+
+// Save writes the replay to a file.
+func (r *Replay) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadReplay reads a replay from a file.
+func LoadReplay(path string) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadReplay(f)
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestReplayReproducesRound(t *testing.T) {
+	players := newRandomPlayers()
+	names := []string{"Milo", "Sara Jane", "", "my_bot \"2\""}
+	scores, replay := SimulateRecordedRound(GetMap(1), players, names, 42, nil)
+
+	var buf bytes.Buffer
+	if err := replay.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	loaded, err := ReadReplay(&buf)
+	if err != nil {
+		t.Fatalf("ReadReplay failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, replay) {
+		t.Fatalf("replay changed when saved and loaded.\nGot: %+v\nWant: %+v", loaded, replay)
+	}
+
+	arena := loaded.NewArena()
+	for range loaded.Ticks {
+		arena.Update()
+	}
+	for i, p := range arena.Players {
+		if !reflect.DeepEqual(p.Path, players[i].Path) || p.IsAlive != players[i].IsAlive {
+			t.Errorf("player %d: replayed path differs from the original", p.ID)
+		}
+	}
+	if len(scores) != len(players) {
+		t.Errorf("expected a score for every player, got %v", scores)
+	}
+}
+
+func TestReplayWithItems(t *testing.T) {
+	players := newRandomPlayers()
+	// Lots of items, so the random players are sure to drive over some
+	rules := DefaultItemRules
+	rules.SpawnChance = 1
+	rules.MaxItems = 100
+	_, replay := SimulateRecordedRound(GetMap(0), players, nil, 7, &rules)
+
+	var buf bytes.Buffer
+	if err := replay.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	loaded, err := ReadReplay(&buf)
+	if err != nil {
+		t.Fatalf("ReadReplay failed: %v", err)
+	}
+	if loaded.Items == nil || *loaded.Items != rules {
+		t.Fatalf("item rules not saved: got %v, want %v", loaded.Items, rules)
+	}
+
+	arena := loaded.NewArena()
+	for range loaded.Ticks {
+		arena.Update()
+	}
+	collected := 0
+	for i, p := range arena.Players {
+		collected += len(p.Collected)
+		if !reflect.DeepEqual(p.Path, players[i].Path) || !reflect.DeepEqual(p.Collected, players[i].Collected) {
+			t.Errorf("player %d: replay differs from the original", p.ID)
+		}
+	}
+	if collected == 0 {
+		t.Errorf("expected some items to be picked up")
+	}
+}
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Scoring is a way of scoring a round.
+type Scoring int
+
+const (
+	// RankScoring gives 0, 2, 4, ... points in the order players are
+	// knocked out, with players knocked out on the same tick sharing the
+	// points between them.
+	RankScoring Scoring = iota
+	// LastAliveScoring gives 1 point to the last player left, or to those
+	// knocked out on the last tick if there is no one left, and 0 to the
+	// rest.
+	LastAliveScoring
+	// SurvivalScoring gives a point for every tick a player survives.
+	SurvivalScoring
+	// AreaScoring gives a point for every square a player could reach
+	// first on the last tick it was alive, as in ComputePlayerScores.
+	AreaScoring
+)
+
+var scoringNames = []string{"rank", "last-alive", "survival", "area"}
+
+func (s Scoring) String() string {
+	if s < 0 || int(s) >= len(scoringNames) {
+		return fmt.Sprintf("Scoring(%d)", int(s))
+	}
+	return scoringNames[s]
+}
+
+// ScoringNames returns the names of the ways of scoring, for help text.
+func ScoringNames() []string {
+	return append([]string{}, scoringNames...)
+}
+
+// ParseScoring finds the way of scoring with the given name.
+func ParseScoring(name string) (Scoring, error) {
+	for i, n := range scoringNames {
+		if n == name {
+			return Scoring(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown scoring %q, expected one of %s", name, strings.Join(scoringNames, ", "))
+}
+
+// ScoreRules say how a round is scored.
+type ScoreRules struct {
+	Scoring Scoring
+	// Teams[i] is the team of player i+1, or nil if everyone plays for
+	// themselves. A teammate's trail is as deadly as anyone's, but a team
+	// is only knocked out once all its players are, and every player gets
+	// their team's score.
+	Teams []int
+}
+
+// PairTeams splits numPlayers players into two teams, the first and third
+// players against the second and fourth, which start on opposite sides of
+// the maps. It returns nil if the players can't be split evenly into teams
+// of two or more.
+func PairTeams(numPlayers int) []int {
+	if numPlayers < 4 || numPlayers%2 != 0 {
+		return nil
+	}
+	teams := make([]int, numPlayers)
+	for i := range teams {
+		teams[i] = i % 2
+	}
+	return teams
+}
+
+// RoundScorer plays a round and keeps its score.
+type RoundScorer struct {
+	Arena *Arena
+	Rules ScoreRules
+	// Ticks played so far
+	Ticks int
+
+	// team of each player, indexed by ID - 1
+	teams []int
+	// score of each player by ID, or -1 while their team is still in
+	scores map[int]int
+	// ranks still to hand out, lowest first, for RankScoring
+	remainingRanks []int
+	// area of each player on the last tick it was alive, for AreaScoring
+	areas map[int]int
+}
+
+func NewRoundScorer(arena *Arena, rules ScoreRules) *RoundScorer {
+	s := &RoundScorer{
+		Arena:  arena,
+		Rules:  rules,
+		teams:  rules.Teams,
+		scores: make(map[int]int, len(arena.Players)),
+		areas:  map[int]int{},
+	}
+	if s.teams == nil {
+		// Everyone is on a team of their own
+		s.teams = make([]int, len(arena.Players))
+		for i := range s.teams {
+			s.teams[i] = i
+		}
+	}
+	for _, p := range arena.Players {
+		s.scores[p.ID] = -1
+	}
+	for rank := range len(s.teamsIn()) {
+		s.remainingRanks = append(s.remainingRanks, rank*2)
+	}
+	return s
+}
+
+// teamsIn returns the teams that still have a player alive, in order.
+func (s *RoundScorer) teamsIn() []int {
+	in := map[int]bool{}
+	for i, p := range s.Arena.Players {
+		if p.IsAlive {
+			in[s.teams[i]] = true
+		}
+	}
+	result := []int{}
+	for team := range in {
+		result = append(result, team)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// Over reports whether the round has finished, with one team or none left.
+func (s *RoundScorer) Over() bool {
+	return len(s.teamsIn()) <= 1
+}
+
+// Update plays one tick and scores the teams knocked out by it.
+func (s *RoundScorer) Update() {
+	if s.Rules.Scoring == AreaScoring {
+		s.recordAreas()
+	}
+	before := s.teamsIn()
+	s.Arena.Update()
+	after := s.teamsIn()
+
+	out := []int{}
+	for _, team := range before {
+		if !slices.Contains(after, team) {
+			out = append(out, team)
+		}
+	}
+	s.scoreTeams(out, len(after) == 0)
+	s.Ticks++
+}
+
+// Finish scores the teams left at the end of the round, and returns every
+// player's score by ID.
+func (s *RoundScorer) Finish() map[int]int {
+	if s.Rules.Scoring == AreaScoring {
+		s.recordAreas()
+	}
+	unscored := []int{}
+	for i, p := range s.Arena.Players {
+		if s.scores[p.ID] == -1 && !slices.Contains(unscored, s.teams[i]) {
+			unscored = append(unscored, s.teams[i])
+		}
+	}
+	s.scoreTeams(unscored, true)
+	return s.Scores()
+}
+
+// Scores returns the score of each player by ID so far, with -1 for
+// players whose team is still in.
+func (s *RoundScorer) Scores() map[int]int {
+	scores := make(map[int]int, len(s.scores))
+	for id, score := range s.scores {
+		scores[id] = score
+	}
+	return scores
+}
+
+func (s *RoundScorer) recordAreas() {
+	areas := s.Arena.ComputePlayerScores()
+	for _, p := range s.Arena.Players {
+		if p.IsAlive {
+			s.areas[p.ID] = areas[p.ID]
+		}
+	}
+}
+
+// scoreTeams scores teams knocked out together, or left at the end. last
+// is set if no other team is left.
+func (s *RoundScorer) scoreTeams(teams []int, last bool) {
+	if len(teams) == 0 {
+		return
+	}
+	// Teams knocked out together share the ranks between them
+	rankScore := calculateAverageScore(s.remainingRanks, len(teams))
+	s.remainingRanks = s.remainingRanks[min(len(teams), len(s.remainingRanks)):]
+
+	for _, team := range teams {
+		score := 0
+		switch s.Rules.Scoring {
+		case RankScoring:
+			score = rankScore
+		case LastAliveScoring:
+			if last {
+				score = 1
+			}
+		case SurvivalScoring:
+			score = s.Ticks
+		case AreaScoring:
+			for i, p := range s.Arena.Players {
+				if s.teams[i] == team {
+					score += s.areas[p.ID]
+				}
+			}
+		}
+		for i, p := range s.Arena.Players {
+			if s.teams[i] == team {
+				s.scores[p.ID] = score
+			}
+		}
+	}
+}
+
+// calculateAverageScore finds the integer-averaged score for a group of tied players.
+// It sums the top 'num' ranks and divides by 'num' (rounded down).
+func calculateAverageScore(ranks []int, num int) int {
+	if num == 0 || len(ranks) == 0 {
+		return 0
+	}
+
+	if num > len(ranks) {
+		num = len(ranks)
+	}
+
+	sum := 0
+	for i := 0; i < num; i++ {
+		sum += ranks[i]
+	}
+
+	// The division must be integer division (floor) to match the game logic.
+	return sum / num
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// playScoredRound plays a round on the map in text to the end.
+func playScoredRound(t *testing.T, text string, rules ScoreRules, dirs ...Vector) (*RoundScorer, map[int]int) {
+	t.Helper()
+	controllers := []PlayerController{}
+	for _, dir := range dirs {
+		controllers = append(controllers, &forcedController{Dir: dir})
+	}
+	scorer := NewRoundScorer(newMapArena(t, text, controllers...), rules)
+	for !scorer.Over() {
+		scorer.Update()
+	}
+	return scorer, scorer.Finish()
+}
+
+func TestRoundScorer(t *testing.T) {
+	// Players 2 and 3 crash on the first tick, leaving player 1
+	text := "gocycle map\ngrid\n#######\n#>....#\n#>....#\n#>....#\n#######\n"
+	tests := []struct {
+		scoring  Scoring
+		expected map[int]int
+	}{
+		{RankScoring, map[int]int{1: 4, 2: 1, 3: 1}},
+		{LastAliveScoring, map[int]int{1: 1, 2: 0, 3: 0}},
+		{SurvivalScoring, map[int]int{1: 1, 2: 0, 3: 0}},
+	}
+	for _, tt := range tests {
+		_, scores := playScoredRound(t, text, ScoreRules{Scoring: tt.scoring}, Right, Up, Down)
+		if !reflect.DeepEqual(scores, tt.expected) {
+			t.Errorf("%v scoring: got %v, want %v", tt.scoring, scores, tt.expected)
+		}
+	}
+}
+
+func TestRoundScorerArea(t *testing.T) {
+	text := "gocycle map\ngrid\n#######\n#>....#\n#>....#\n#>....#\n#######\n"
+	before := newMapArena(t, text, &mockController{}, &mockController{}, &mockController{}).ComputePlayerScores()
+	scorer, scores := playScoredRound(t, text, ScoreRules{Scoring: AreaScoring}, Right, Up, Down)
+	after := scorer.Arena.ComputePlayerScores()
+	expected := map[int]int{1: after[1], 2: before[2], 3: before[3]}
+	if !reflect.DeepEqual(scores, expected) {
+		t.Errorf("got %v, want the area at death %v", scores, expected)
+	}
+}
+
+func TestRoundScorerTeams(t *testing.T) {
+	// Player 2 crashes into its teammate's trail and player 4 into the
+	// wall, but the round goes on until player 3 hits the wall too
+	text := "gocycle map\ngrid\n########\n#>.....#\n#>.....#\n#>...###\n#>.....#\n########\n"
+	rules := ScoreRules{Teams: []int{0, 0, 1, 1}}
+	tests := []struct {
+		scoring  Scoring
+		expected map[int]int
+	}{
+		{RankScoring, map[int]int{1: 2, 2: 2, 3: 0, 4: 0}},
+		{LastAliveScoring, map[int]int{1: 1, 2: 1, 3: 0, 4: 0}},
+		{SurvivalScoring, map[int]int{1: 4, 2: 4, 3: 3, 4: 3}},
+	}
+	for _, tt := range tests {
+		rules.Scoring = tt.scoring
+		scorer, scores := playScoredRound(t, text, rules, Right, Up, Right, Down)
+		if !reflect.DeepEqual(scores, tt.expected) {
+			t.Errorf("%v scoring: got %v, want %v", tt.scoring, scores, tt.expected)
+		}
+		if scorer.Ticks != 4 || !scorer.Arena.Players[0].IsAlive {
+			t.Errorf("expected the round to end after 4 ticks with player 1 alive")
+		}
+	}
+}
+
+func TestParseScoring(t *testing.T) {
+	for _, name := range ScoringNames() {
+		scoring, err := ParseScoring(name)
+		if err != nil || scoring.String() != name {
+			t.Errorf("%s: got %v, %v", name, scoring, err)
+		}
+	}
+	if _, err := ParseScoring("points"); err == nil {
+		t.Errorf("expected an error for an unknown scoring")
+	}
+	if teams := PairTeams(4); !slices.Equal(teams, []int{0, 1, 0, 1}) {
+		t.Errorf("PairTeams(4) = %v", teams)
+	}
+	if PairTeams(3) != nil {
+		t.Errorf("3 players can't be paired")
+	}
+}
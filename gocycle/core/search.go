@@ -0,0 +1,719 @@
+package core
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// search.go contains TreeSearchController, a computer player that looks
+// further ahead than MinimaxAreaController. While it shares space with its
+// opponents it searches their moves and its own as simultaneous moves,
+// assuming the worst reply each time. Once it is walled off from everyone
+// it stops worrying about them and searches for the longest path through
+// the space it has left.
+//
+// Positions are scored by area. While the players can still reach each
+// other that is the Voronoi area; once they are separated it is an
+// estimate of how many squares each can actually fill, which allows for
+// "chambers" that can only be entered by a one-square doorway (an
+// articulation point) and so can't be filled as well as everything else.
+
+const (
+	// score for a position where the player has crashed
+	searchLossScore = -1_000_000
+	// a search may look at this many opponents' moves; others are assumed
+	// to stay where they are
+	maxSearchedOpponents = 2
+	// used when the controller is given neither a depth nor a time limit
+	defaultSearchDepth  = 8
+	defaultSearchBudget = 20 * time.Millisecond
+)
+
+// TreeSearchController searches deeper each time until it runs out of
+// depth or time. If TimeBudget is set the moves it chooses depend on how
+// fast the computer is; with only MaxDepth set it always plays the same.
+type TreeSearchController struct {
+	// Deepest search, in ticks; 0 for no limit
+	MaxDepth int
+	// How long to think each tick; 0 for no limit
+	TimeBudget time.Duration
+}
+
+func (tc *TreeSearchController) GetDirection(arena *Arena, playerID int) Vector {
+	maxDepth, budget := tc.MaxDepth, tc.TimeBudget
+	if maxDepth <= 0 && budget <= 0 {
+		maxDepth, budget = defaultSearchDepth, defaultSearchBudget
+	}
+	if maxDepth <= 0 {
+		maxDepth = math.MaxInt
+	}
+
+	// The search moves players about on one copy of the arena and undoes
+	// the moves afterwards, rather than copying the arena for every position
+	s := &treeSearch{
+		arena: arena.DeepCopy(),
+		me:    playerID - 1,
+	}
+	s.chambers = newChamberCounter(s.arena)
+	if budget > 0 {
+		s.deadline = time.Now().Add(budget)
+	}
+	player := s.arena.Players[s.me]
+
+	s.opponents = s.reachableOpponents()
+	isolated := len(s.opponents) == 0
+
+	bestDir := player.Direction
+	if moves := s.moves(s.me); len(moves) > 0 {
+		bestDir = moves[0]
+	}
+	for depth := 1; depth <= maxDepth; depth++ {
+		var dir Vector
+		if isolated {
+			dir, _ = s.fillRoot(depth)
+		} else {
+			dir, _ = s.searchRoot(depth, bestDir)
+		}
+		if s.stopped {
+			break
+		}
+		bestDir = dir
+		s.canStop = true
+		// Nothing further to find if the search didn't go as deep as asked
+		if !s.hitDepthLimit {
+			break
+		}
+		s.hitDepthLimit = false
+	}
+	return bestDir
+}
+
+type treeSearch struct {
+	arena *Arena
+	// index in Players of the player choosing a move
+	me int
+	// indices of the opponents whose moves are searched
+	opponents []int
+
+	// the search stops at the deadline, once it has finished searching one
+	// tick ahead
+	deadline time.Time
+	canStop  bool
+	stopped  bool
+	// set if some line was cut short by the depth limit, rather than ending
+	// in a crash
+	hitDepthLimit bool
+
+	// how many ticks into the search the current position is
+	ply int
+
+	// buffers for evaluate, kept between calls
+	dist, owner, from []int
+	queue             []voronoiItem
+	chambers          *chamberCounter
+}
+
+// timeUp checks the clock, and stops the search if time has run out.
+func (s *treeSearch) timeUp() bool {
+	if s.canStop && !s.stopped && !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.stopped = true
+	}
+	return s.stopped
+}
+
+// moves returns the directions a player can go without crashing, or just
+// straight on if it is bound to crash.
+func (s *treeSearch) moves(i int) []Vector {
+	p := s.arena.Players[i]
+	moves := []Vector{}
+	for _, dir := range []Vector{p.Direction, p.Direction.TurnLeft(), p.Direction.TurnRight()} {
+		if s.arena.canMove(p, dir) {
+			moves = append(moves, dir)
+		}
+	}
+	if len(moves) == 0 {
+		moves = append(moves, p.Direction)
+	}
+	return moves
+}
+
+// reachableOpponents returns up to maxSearchedOpponents living opponents
+// that can still reach the player's space, nearest first.
+func (s *treeSearch) reachableOpponents() []int {
+	a := s.arena
+	distances := s.distancesFrom(a.Players[s.me].Position)
+	type candidate struct{ index, dist int }
+	candidates := []candidate{}
+	for i, p := range a.Players {
+		if i == s.me || !p.IsAlive {
+			continue
+		}
+		// An opponent's head isn't open, so look at the squares next to it
+		best := math.MaxInt
+		for _, dir := range []Vector{Up, Down, Left, Right} {
+			pos := a.Next(p.Position, dir)
+			if d, ok := distances[pos]; ok && d < best {
+				best = d
+			}
+		}
+		if best < math.MaxInt {
+			candidates = append(candidates, candidate{i, best})
+		}
+	}
+
+	// Insertion sort by distance, then index, so the choice is repeatable
+	for i := 1; i < len(candidates); i++ {
+		for j := i; j > 0 && candidates[j].dist < candidates[j-1].dist; j-- {
+			candidates[j], candidates[j-1] = candidates[j-1], candidates[j]
+		}
+	}
+	result := []int{}
+	for _, c := range candidates[:min(len(candidates), maxSearchedOpponents)] {
+		result = append(result, c.index)
+	}
+	return result
+}
+
+// distancesFrom finds the number of steps from start to every open square
+// that can be reached from it.
+func (s *treeSearch) distancesFrom(start Vector) map[Vector]int {
+	a := s.arena
+	distances := map[Vector]int{start: 0}
+	queue := []Vector{start}
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		for _, dir := range []Vector{Up, Down, Left, Right} {
+			next := a.Next(pos, dir)
+			if _, seen := distances[next]; seen || a.isCollision(next) {
+				continue
+			}
+			distances[next] = distances[pos] + 1
+			queue = append(queue, next)
+		}
+	}
+	return distances
+}
+
+// --- Making and unmaking moves
+
+type playerState struct {
+	Position  Vector
+	Direction Vector
+	Boosted   bool
+	IsAlive   bool
+}
+
+type squareChange struct {
+	pos Vector
+	old Square
+}
+
+// undoInfo holds what is needed to take back one tick of moves.
+type undoInfo struct {
+	players []playerState
+	squares []squareChange
+}
+
+// makeMoves moves the players with the given indices in the given
+// directions, as Arena.step does, except that trails of crashed players
+// are left in place and no items are picked up.
+func (s *treeSearch) makeMoves(indices []int, dirs []Vector) undoInfo {
+	a := s.arena
+	undo := undoInfo{}
+	for _, i := range indices {
+		p := a.Players[i]
+		undo.players = append(undo.players, playerState{p.Position, p.Direction, p.Boosted, p.IsAlive})
+	}
+
+	// Dead players stay where they are
+	squares := make([][]Vector, len(indices))
+	collided := make([]bool, len(indices))
+	for k, i := range indices {
+		p := a.Players[i]
+		if !p.IsAlive {
+			continue
+		}
+		squares[k] = a.moveSquares(p, dirs[k])
+		collided[k] = !a.canMove(p, dirs[k])
+	}
+	for k := range indices {
+		for l := k + 1; l < len(indices); l++ {
+			if sharesSquare(squares[k], squares[l]) {
+				collided[k] = true
+				collided[l] = true
+			}
+		}
+	}
+
+	for k, i := range indices {
+		p := a.Players[i]
+		if !p.IsAlive {
+			continue
+		}
+		p.Direction = dirs[k]
+		if collided[k] {
+			p.IsAlive = false
+			continue
+		}
+		for _, pos := range squares[k] {
+			if !isPassable(a.Grid[pos.Y][pos.X]) {
+				continue
+			}
+			undo.squares = append(undo.squares, squareChange{pos, a.Grid[pos.Y][pos.X]})
+			a.Grid[pos.Y][pos.X] = Square(p.ID)
+		}
+		p.Position = squares[k][len(squares[k])-1]
+		p.Boosted = a.tileAt(p.Position) == SpeedPad
+	}
+	s.ply++
+	return undo
+}
+
+func (s *treeSearch) unmakeMoves(indices []int, undo undoInfo) {
+	a := s.arena
+	for k := len(undo.squares) - 1; k >= 0; k-- {
+		c := undo.squares[k]
+		a.Grid[c.pos.Y][c.pos.X] = c.old
+	}
+	for k, i := range indices {
+		p := a.Players[i]
+		st := undo.players[k]
+		p.Position, p.Direction, p.Boosted, p.IsAlive = st.Position, st.Direction, st.Boosted, st.IsAlive
+	}
+	s.ply--
+}
+
+// --- Searching while sharing space with opponents
+
+// searchRoot tries each of the player's moves to the given depth, trying
+// firstDir first, and returns the best.
+func (s *treeSearch) searchRoot(depth int, firstDir Vector) (Vector, int) {
+	moves := orderFirst(s.moves(s.me), firstDir)
+	bestDir, bestScore := moves[0], math.MinInt
+	for _, dir := range moves {
+		score := s.worstReply(dir, depth, bestScore, math.MaxInt)
+		if s.stopped {
+			break
+		}
+		if score > bestScore {
+			bestDir, bestScore = dir, score
+		}
+	}
+	return bestDir, bestScore
+}
+
+// worstReply scores the player moving in dir as the worst it can be over
+// the opponents' moves made at the same time. It stops early once the
+// score can't be more than alpha.
+func (s *treeSearch) worstReply(dir Vector, depth, alpha, beta int) int {
+	indices := append([]int{s.me}, s.opponents...)
+	worst := math.MaxInt
+	s.forEachReply(func(replies []Vector) bool {
+		dirs := append([]Vector{dir}, replies...)
+		undo := s.makeMoves(indices, dirs)
+		score := s.search(depth-1, alpha, min(beta, worst))
+		s.unmakeMoves(indices, undo)
+		worst = min(worst, score)
+		return worst > alpha && !s.stopped
+	})
+	return worst
+}
+
+// forEachReply calls f with each combination of the searched opponents'
+// moves, until f returns false.
+func (s *treeSearch) forEachReply(f func(replies []Vector) bool) {
+	options := make([][]Vector, len(s.opponents))
+	for k, i := range s.opponents {
+		if s.arena.Players[i].IsAlive {
+			options[k] = s.moves(i)
+		} else {
+			// A dead player's move doesn't matter; this one does nothing
+			options[k] = []Vector{s.arena.Players[i].Direction}
+		}
+	}
+
+	replies := make([]Vector, len(options))
+	var choose func(k int) bool
+	choose = func(k int) bool {
+		if k == len(options) {
+			return f(replies)
+		}
+		for _, dir := range options[k] {
+			replies[k] = dir
+			if !choose(k + 1) {
+				return false
+			}
+		}
+		return true
+	}
+	choose(0)
+}
+
+// search is an alpha-beta search over ticks of simultaneous moves, where
+// the opponents are assumed to choose the reply that is worst for the
+// player.
+func (s *treeSearch) search(depth, alpha, beta int) int {
+	if s.timeUp() {
+		return 0
+	}
+	me := s.arena.Players[s.me]
+	if !me.IsAlive {
+		return s.crashScore()
+	}
+	if depth == 0 {
+		s.hitDepthLimit = true
+		return s.evaluate()
+	}
+	if !s.anyOpponentAlive() {
+		return s.evaluate()
+	}
+
+	best := math.MinInt
+	for _, dir := range s.moves(s.me) {
+		score := s.worstReply(dir, depth, max(alpha, best), beta)
+		if s.stopped {
+			return 0
+		}
+		best = max(best, score)
+		if best >= beta {
+			break
+		}
+	}
+	return best
+}
+
+// crashScore scores a position where the player has crashed. Players that
+// crash on the same tick share their places, so crashing along with every
+// other player is a draw, and taking some of them along is better than
+// crashing alone. Crashing later is better than crashing sooner.
+func (s *treeSearch) crashScore() int {
+	survivors := 0
+	for i, p := range s.arena.Players {
+		if i != s.me && p.IsAlive {
+			survivors++
+		}
+	}
+	if survivors == 0 {
+		return 0
+	}
+	for _, i := range s.opponents {
+		if !s.arena.Players[i].IsAlive {
+			return searchLossScore/2 + s.ply
+		}
+	}
+	return searchLossScore + s.ply
+}
+
+func (s *treeSearch) anyOpponentAlive() bool {
+	for _, i := range s.opponents {
+		if s.arena.Players[i].IsAlive {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate scores a position for the player as its area less the best of
+// its searched opponents' areas.
+func (s *treeSearch) evaluate() int {
+	a := s.arena
+	me := a.Players[s.me]
+	scores, contact := s.voronoi()
+	if !contact {
+		// Nobody can get in the player's way any more, so count the
+		// squares each player can actually fill
+		best := 0
+		for _, i := range s.opponents {
+			if p := a.Players[i]; p.IsAlive {
+				best = max(best, s.chambers.estimate(p.Position))
+			}
+		}
+		return s.chambers.estimate(me.Position) - best
+	}
+
+	best := 0
+	for _, i := range s.opponents {
+		if a.Players[i].IsAlive {
+			best = max(best, scores[i])
+		}
+	}
+	return scores[s.me] - best
+}
+
+type voronoiItem struct {
+	pos  Vector
+	from int
+}
+
+// voronoi works out the same values as ComputePlayerValues, indexed by
+// player index rather than ID, and whether any living opponent can reach
+// a square the player can reach. It is called for every position the
+// search scores, so it reuses its buffers and ignores boosts.
+func (s *treeSearch) voronoi() ([]int, bool) {
+	a := s.arena
+	if s.dist == nil {
+		s.dist = make([]int, a.Width*a.Height)
+		s.owner = make([]int, a.Width*a.Height)
+		s.from = make([]int, a.Width*a.Height)
+	}
+	for i := range s.dist {
+		s.dist[i] = -1
+	}
+
+	// Each square records the player whose search reached it first (from)
+	// and which player owns it, or -1 if two players reach it together
+	queue := s.queue[:0]
+	for i, p := range a.Players {
+		if p.IsAlive {
+			k := p.Position.Y*a.Width + p.Position.X
+			s.dist[k], s.owner[k], s.from[k] = 0, i, i
+			queue = append(queue, voronoiItem{p.Position, i})
+		}
+	}
+
+	contact := false
+	for head := 0; head < len(queue); head++ {
+		current := queue[head]
+		d := s.dist[current.pos.Y*a.Width+current.pos.X] + 1
+		for _, dir := range []Vector{Up, Down, Left, Right} {
+			next := a.Next(current.pos, dir)
+			if a.isCollision(next) {
+				continue
+			}
+			k := next.Y*a.Width + next.X
+			switch {
+			case s.dist[k] < 0:
+				s.dist[k], s.owner[k], s.from[k] = d, current.from, current.from
+				queue = append(queue, voronoiItem{next, current.from})
+				continue
+			case s.dist[k] == d && s.owner[k] != current.from:
+				s.owner[k] = -1
+			}
+			if s.from[k] != current.from && (s.from[k] == s.me || current.from == s.me) {
+				contact = true
+			}
+		}
+	}
+	s.queue = queue
+
+	scores := make([]int, len(a.Players))
+	for k, d := range s.dist {
+		if d >= 0 && s.owner[k] >= 0 {
+			scores[s.owner[k]]++
+		}
+	}
+	for i, p := range a.Players {
+		for _, kind := range p.Collected {
+			scores[i] += ItemValues[kind]
+		}
+	}
+	for pos, kind := range a.Items {
+		if k := pos.Y*a.Width + pos.X; s.dist[k] >= 0 && s.owner[k] >= 0 {
+			scores[s.owner[k]] += ItemValues[kind] / 2
+		}
+	}
+	return scores, contact
+}
+
+// --- Filling space alone
+
+// fillRoot searches for the longest path for the player when no opponent
+// can reach it. Where moves are equally good it keeps close to the walls,
+// which wastes the least space.
+func (s *treeSearch) fillRoot(depth int) (Vector, int) {
+	moves := s.moves(s.me)
+	sort.SliceStable(moves, func(i, j int) bool {
+		return s.openNeighbours(moves[i]) < s.openNeighbours(moves[j])
+	})
+	bestDir, bestScore := moves[0], math.MinInt
+	for _, dir := range moves {
+		score := s.fillMove(dir, depth)
+		if s.stopped {
+			break
+		}
+		if score > bestScore {
+			bestDir, bestScore = dir, score
+		}
+	}
+	return bestDir, bestScore
+}
+
+// fillMove is the length of the longest path found by moving in dir and
+// then searching depth-1 more moves.
+func (s *treeSearch) fillMove(dir Vector, depth int) int {
+	me := []int{s.me}
+	undo := s.makeMoves(me, []Vector{dir})
+	defer s.unmakeMoves(me, undo)
+	if !s.arena.Players[s.me].IsAlive {
+		return 0
+	}
+	return len(undo.squares) + s.fill(depth-1)
+}
+
+func (s *treeSearch) fill(depth int) int {
+	if s.timeUp() {
+		return 0
+	}
+	p := s.arena.Players[s.me]
+	if depth == 0 {
+		s.hitDepthLimit = true
+		return s.chambers.estimate(p.Position)
+	}
+	best := 0
+	for _, dir := range s.moves(s.me) {
+		best = max(best, s.fillMove(dir, depth))
+		if s.stopped {
+			return 0
+		}
+	}
+	return best
+}
+
+// openNeighbours counts the open squares next to the one the player would
+// move to in direction dir.
+func (s *treeSearch) openNeighbours(dir Vector) int {
+	a := s.arena
+	pos := a.Next(a.Players[s.me].Position, dir)
+	count := 0
+	for _, d := range []Vector{Up, Down, Left, Right} {
+		if !a.isCollision(a.Next(pos, d)) {
+			count++
+		}
+	}
+	return count
+}
+
+// orderFirst moves dir to the front of dirs, if it is there.
+func orderFirst(dirs []Vector, dir Vector) []Vector {
+	for i, d := range dirs {
+		if d == dir {
+			dirs[0], dirs[i] = dirs[i], dirs[0]
+			break
+		}
+	}
+	return dirs
+}
+
+// --- Chamber estimate
+
+// fillEstimate estimates how many squares a player at start can drive
+// over before running out of space. Where a region can only be reached
+// through a single square (an articulation point), the player can go into
+// only one of the regions beyond it and not come back, so only the best
+// of those is counted. Other branches are assumed to be visited on the way.
+func fillEstimate(a *Arena, start Vector) int {
+	return newChamberCounter(a).estimate(start)
+}
+
+// chamberCounter runs the depth-first search for fillEstimate, using
+// Tarjan's articulation point method: a child whose subtree has no edge
+// back above a square is cut off from the rest by that square. Squares are
+// numbered y*Width+x.
+type chamberCounter struct {
+	arena *Arena
+	// order in which each square was visited, or -1 if it hasn't been
+	disc []int
+	// earliest visited square reachable from each square's subtree
+	low  []int
+	time int
+	// how many squares of each checkerboard color have been visited
+	colors [2]int
+}
+
+func newChamberCounter(a *Arena) *chamberCounter {
+	return &chamberCounter{
+		arena: a,
+		disc:  make([]int, a.Width*a.Height),
+		low:   make([]int, a.Width*a.Height),
+	}
+}
+
+func (c *chamberCounter) estimate(start Vector) int {
+	for i := range c.disc {
+		c.disc[i] = -1
+	}
+	a := c.arena
+	k := start.Y*a.Width + start.X
+	c.disc[k] = 0
+	c.low[k] = 0
+	c.time = 1
+
+	// The head itself isn't counted, and leaving it down one branch means
+	// not coming back for the others
+	best := 0
+	for _, dir := range []Vector{Up, Down, Left, Right} {
+		next := a.Next(start, dir)
+		if a.isCollision(next) || c.disc[next.Y*a.Width+next.X] >= 0 {
+			continue
+		}
+		before := c.colors
+		fill, _ := c.visit(next, start)
+		if c.checkerboard() {
+			// Each move goes from a square of one color to the other, so
+			// the path can only have one more square of the color it
+			// starts on than of the other color
+			first := c.colors[squareColor(next)] - before[squareColor(next)]
+			second := c.colors[1-squareColor(next)] - before[1-squareColor(next)]
+			longest := 2 * min(first, second)
+			if first > second {
+				longest++
+			}
+			fill = min(fill, longest)
+		}
+		best = max(best, fill)
+	}
+	return best
+}
+
+// squareColor is the color of the square on a checkerboard.
+func squareColor(pos Vector) int {
+	return (pos.X + pos.Y) % 2
+}
+
+// checkerboard reports whether every move goes between squares of
+// different colors, which isn't so if a portal or an odd-sized wrapping
+// arena gets in the way.
+func (c *chamberCounter) checkerboard() bool {
+	a := c.arena
+	return len(a.Portals) == 0 && (!a.Wrap || (a.Width%2 == 0 && a.Height%2 == 0))
+}
+
+// visit returns the estimated fill starting at pos, and the number of
+// squares in its subtree.
+func (c *chamberCounter) visit(pos, parent Vector) (fill, size int) {
+	w := c.arena.Width
+	k := pos.Y*w + pos.X
+	c.disc[k] = c.time
+	c.low[k] = c.time
+	c.time++
+	c.colors[squareColor(pos)]++
+
+	size = 1
+	passing := 0 // squares in branches that can be visited and left again
+	bestChamber := 0
+	for _, dir := range []Vector{Up, Down, Left, Right} {
+		next := c.arena.Next(pos, dir)
+		if c.arena.isCollision(next) {
+			continue
+		}
+		nk := next.Y*w + next.X
+		if c.disc[nk] >= 0 {
+			if next != parent {
+				c.low[k] = min(c.low[k], c.disc[nk])
+			}
+			continue
+		}
+		childFill, childSize := c.visit(next, pos)
+		size += childSize
+		c.low[k] = min(c.low[k], c.low[nk])
+		if c.low[nk] >= c.disc[k] {
+			// pos is the only way in to the child's chamber
+			bestChamber = max(bestChamber, childFill)
+		} else {
+			passing += childSize
+		}
+	}
+	return 1 + passing + bestChamber, size
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFillEstimateChambers(t *testing.T) {
+	// From the corridor the player can fill the spur at the top right or the
+	// room below, not both, and only one side of the room
+	arena := newMapArena(t, "gocycle map\ngrid\n#######\n#>....#\n####.##\n#.....#\n#######\n", &mockController{})
+	if got := fillEstimate(arena, Vector{X: 1, Y: 1}); got != 8 {
+		t.Errorf("corridor and chambers: got %d, want 8", got)
+	}
+
+	// Every square of a loop can be filled
+	arena = newMapArena(t, "gocycle map\ngrid\n#####\n#>..#\n#...#\n#####\n", &mockController{})
+	if got := fillEstimate(arena, Vector{X: 1, Y: 1}); got != 5 {
+		t.Errorf("loop: got %d, want 5", got)
+	}
+}
+
+func TestTreeSearchFillsLargerSide(t *testing.T) {
+	arena := newMapArena(t, "gocycle map\ngrid\n#########\n#..v....#\n#########\n", &mockController{})
+	tc := &TreeSearchController{MaxDepth: 2}
+	if dir := tc.GetDirection(arena, 1); dir != Right {
+		t.Errorf("expected to turn towards the larger side, got %v", dir)
+	}
+}
+
+func TestTreeSearchAvoidsHeadOn(t *testing.T) {
+	// The opponent might move into the square between them, so going
+	// Right risks a head-on crash
+	text := "gocycle map\ngrid\n#######\n#.....#\n#.....#\n#>.<..#\n#.....#\n#.....#\n#######\n"
+	arena := newMapArena(t, text, &mockController{}, &mockController{})
+	tc := &TreeSearchController{MaxDepth: 2}
+	if dir := tc.GetDirection(arena, 1); dir == Right {
+		t.Errorf("expected to avoid the square next to the opponent")
+	}
+}
+
+func TestTreeSearchUnmakeMoves(t *testing.T) {
+	arena := newMapArena(t, "gocycle map\ngrid\n######\n#>.*.#\n#..<.#\n######\n", &mockController{}, &mockController{})
+	arena.Players[0].Boosted = true
+	before := arena.DeepCopy()
+
+	s := &treeSearch{arena: arena, me: 0, opponents: []int{1}}
+	indices := []int{0, 1}
+	undo1 := s.makeMoves(indices, []Vector{Right, Left})
+	undo2 := s.makeMoves(indices, []Vector{Down, Left})
+	if arena.Players[0].IsAlive {
+		t.Errorf("expected player 1 to crash into player 2's trail")
+	}
+	s.unmakeMoves(indices, undo2)
+	s.unmakeMoves(indices, undo1)
+
+	if !reflect.DeepEqual(arena.Grid, before.Grid) {
+		t.Errorf("grid not restored.\nGot: %v\nWant: %v", arena.Grid, before.Grid)
+	}
+	for i, p := range arena.Players {
+		b := before.Players[i]
+		if p.Position != b.Position || p.Direction != b.Direction || p.Boosted != b.Boosted || !p.IsAlive {
+			t.Errorf("player %d not restored: got %+v, want %+v", p.ID, p, b)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+)
+
+// settings.go holds the choices made on the game's match setup screen,
+// which are saved to a file so they are kept between sessions.
+
+const (
+	// ArenaInTurn plays the rounds on the maps in turn.
+	ArenaInTurn = ""
+	// ArenaRandom plays a round on a map chosen at random.
+	ArenaRandom = "random"
+
+	MinRounds     = 1
+	MaxRounds     = 9
+	MinTickMillis = 30
+	MaxTickMillis = 300
+)
+
+// KeyBindings are the keys a human player steers with, by their names in
+// ebiten, such as "ArrowUp" or "W".
+type KeyBindings struct {
+	Up    string `json:"up"`
+	Down  string `json:"down"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// Keys returns pointers to the four keys, in the order Up, Down, Left,
+// Right, so they can be changed in turn.
+func (k *KeyBindings) Keys() []*string {
+	return []*string{&k.Up, &k.Down, &k.Left, &k.Right}
+}
+
+// MatchSettings say how a match is played.
+type MatchSettings struct {
+	Rounds int `json:"rounds"`
+	// Time between ticks
+	TickMillis int `json:"tick_millis"`
+	// The map for each round, by name, or ArenaInTurn or ArenaRandom. It
+	// has an entry for every round, and may have more.
+	Arenas []string `json:"arenas"`
+	// Controller, from ControllerFactories, to play each computer
+	// character instead of its own, by character name
+	Controllers map[string]string `json:"controllers"`
+	// Keys for the first and second human players
+	Keys [2]KeyBindings `json:"keys"`
+}
+
+// DefaultMatchSettings returns the settings used until others are saved.
+func DefaultMatchSettings() MatchSettings {
+	return MatchSettings{
+		Rounds:      5,
+		TickMillis:  100,
+		Arenas:      make([]string, MaxRounds),
+		Controllers: map[string]string{},
+		Keys: [2]KeyBindings{
+			{Up: "ArrowUp", Down: "ArrowDown", Left: "ArrowLeft", Right: "ArrowRight"},
+			{Up: "W", Down: "S", Left: "A", Right: "D"},
+		},
+	}
+}
+
+// SettingsPath returns where the settings are kept for this user.
+func SettingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gocycle", "settings.json"), nil
+}
+
+// LoadMatchSettings reads settings saved by Save. It returns the defaults
+// if there is no file yet. Anything missing from the file or no longer
+// valid, such as a map that has gone, is replaced by its default.
+func LoadMatchSettings(path string) (MatchSettings, error) {
+	s := DefaultMatchSettings()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return DefaultMatchSettings(), fmt.Errorf("%s: %v", path, err)
+	}
+	s.check()
+	return s, nil
+}
+
+// Save writes the settings as JSON, making the directory if need be.
+func (s *MatchSettings) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// check replaces anything that isn't valid with its default.
+func (s *MatchSettings) check() {
+	defaults := DefaultMatchSettings()
+	s.Rounds = min(max(s.Rounds, MinRounds), MaxRounds)
+	s.TickMillis = min(max(s.TickMillis, MinTickMillis), MaxTickMillis)
+
+	for len(s.Arenas) < MaxRounds {
+		s.Arenas = append(s.Arenas, ArenaInTurn)
+	}
+	for i, name := range s.Arenas {
+		if name != ArenaInTurn && name != ArenaRandom && FindMap(name) == nil {
+			s.Arenas[i] = ArenaInTurn
+		}
+	}
+
+	if s.Controllers == nil {
+		s.Controllers = map[string]string{}
+	}
+	for char, name := range s.Controllers {
+		if _, ok := ControllerFactories[name]; !ok {
+			delete(s.Controllers, char)
+		}
+	}
+
+	for i := range s.Keys {
+		keys := s.Keys[i].Keys()
+		for j, key := range defaults.Keys[i].Keys() {
+			if *keys[j] == "" {
+				*keys[j] = *key
+			}
+		}
+	}
+}
+
+// ArenaForRound chooses the map for a round with the given number of
+// players, starting from round 0. A map that has too few spawn points is
+// replaced by the map that would be played in turn.
+func (s *MatchSettings) ArenaForRound(round, numPlayers int, rng *rand.Rand) *Map {
+	maps := MapsForPlayers(numPlayers)
+	choice := ArenaInTurn
+	if round < len(s.Arenas) {
+		choice = s.Arenas[round]
+	}
+	switch choice {
+	case ArenaInTurn:
+	case ArenaRandom:
+		return maps[rng.IntN(len(maps))]
+	default:
+		for _, m := range maps {
+			if m.Name == choice {
+				return m
+			}
+		}
+	}
+	return maps[round%len(maps)]
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestMatchSettingsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gocycle", "settings.json")
+	s, err := LoadMatchSettings(path)
+	if err != nil {
+		t.Fatalf("LoadMatchSettings without a file failed: %v", err)
+	}
+	if !reflect.DeepEqual(s, DefaultMatchSettings()) {
+		t.Errorf("expected the defaults without a file, got %+v", s)
+	}
+
+	s.Rounds = 3
+	s.TickMillis = 60
+	s.Arenas[0] = ArenaRandom
+	s.Arenas[1] = GetMap(1).Name
+	s.Controllers["Biff"] = "WallHugger"
+	s.Keys[1].Up = "I"
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := LoadMatchSettings(path)
+	if err != nil {
+		t.Fatalf("LoadMatchSettings failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, s) {
+		t.Errorf("got %+v, want %+v", loaded, s)
+	}
+}
+
+func TestMatchSettingsCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	text := `{"rounds": 50, "tick_millis": 1, "arenas": ["Nowhere", "random"],
+		"controllers": {"Biff": "NoSuchController", "Milo": "AreaController"},
+		"keys": [{"up": "K"}]}`
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadMatchSettings(path)
+	if err != nil {
+		t.Fatalf("LoadMatchSettings failed: %v", err)
+	}
+	defaults := DefaultMatchSettings()
+	if s.Rounds != MaxRounds || s.TickMillis != MinTickMillis {
+		t.Errorf("expected rounds and speed to be brought into range, got %d and %d", s.Rounds, s.TickMillis)
+	}
+	if len(s.Arenas) != MaxRounds || s.Arenas[0] != ArenaInTurn || s.Arenas[1] != ArenaRandom {
+		t.Errorf("arenas: got %q", s.Arenas)
+	}
+	if !reflect.DeepEqual(s.Controllers, map[string]string{"Milo": "AreaController"}) {
+		t.Errorf("controllers: got %v", s.Controllers)
+	}
+	want := defaults.Keys
+	want[0].Up = "K"
+	if s.Keys != want {
+		t.Errorf("keys: got %+v, want %+v", s.Keys, want)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMatchSettings(path); err == nil {
+		t.Errorf("expected an error for a broken file")
+	}
+}
+
+func TestArenaForRound(t *testing.T) {
+	s := DefaultMatchSettings()
+	maps := MapsForPlayers(2)
+	s.Arenas[1] = maps[0].Name
+	s.Arenas[2] = ArenaRandom
+	rng := NewRand(1)
+	if m := s.ArenaForRound(0, 2, rng); m != maps[0] {
+		t.Errorf("round 1: got %s, want %s in turn", m.Name, maps[0].Name)
+	}
+	if m := s.ArenaForRound(1, 2, rng); m != maps[0] {
+		t.Errorf("round 2: got %s, want the chosen %s", m.Name, maps[0].Name)
+	}
+	if m := s.ArenaForRound(2, 2, rng); !slices.Contains(maps, m) {
+		t.Errorf("round 3: got %s, which has no room", m.Name)
+	}
+	// Past the rounds with settings, the maps are played in turn
+	if m := s.ArenaForRound(MaxRounds+1, 2, rng); m != maps[(MaxRounds+1)%len(maps)] {
+		t.Errorf("round %d: got %s", MaxRounds+2, m.Name)
+	}
+}
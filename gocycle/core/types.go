@@ -8,6 +8,12 @@ const (
 	Open Square = 0
 	// Wall (-1): The cell is permanently blocked by a border or internal obstacle.
 	Wall Square = -1
+	// SpeedPad (-2): An open square that makes a player who drives onto it
+	// move two squares on the next tick.
+	SpeedPad Square = -2
+	// Portal (-3): One end of a pair of portals. A player driving into it comes
+	// out of the other end, so players never stop on a portal.
+	Portal Square = -3
 	// Player paths are tracked by their positive Player ID (1, 2, 3...)
 )
 
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// weighted.go contains WeightedController, a computer player that looks
+// one move ahead and scores each move by a weighted sum of features of the
+// position it leads to. The weights are its Genome, which can be tuned by
+// the trainer in cmd/trainer rather than by hand.
+
+// Genome holds the weight of each feature WeightedController looks at.
+// Each feature is scaled to be between 0 and 1.
+type Genome struct {
+	// Share of the arena the player would reach before anyone else
+	Area float64 `json:"area"`
+	// How far the player could go straight on before hitting something,
+	// as a share of the arena's longer side
+	WallDistance float64 `json:"wall_distance"`
+	// 1 / (1 + the number of steps to the nearest opponent), or 0 if no
+	// opponent can be reached
+	OpponentProximity float64 `json:"opponent_proximity"`
+	// Share of the arena the player could still reach at all
+	FloodFill float64 `json:"flood_fill"`
+}
+
+// DefaultGenome is a reasonable hand-picked set of weights.
+var DefaultGenome = Genome{
+	Area:              1,
+	WallDistance:      0.05,
+	OpponentProximity: 0,
+	FloodFill:         0.5,
+}
+
+// Weights returns pointers to each of the genome's weights, so they can be
+// changed in turn.
+func (g *Genome) Weights() []*float64 {
+	return []*float64{&g.Area, &g.WallDistance, &g.OpponentProximity, &g.FloodFill}
+}
+
+// ScoredGenome is a genome and how well it did in training.
+type ScoredGenome struct {
+	Genome  Genome  `json:"genome"`
+	Fitness float64 `json:"fitness"`
+}
+
+// Checkpoint is a generation of genomes saved by the trainer, best first.
+type Checkpoint struct {
+	Generation int            `json:"generation"`
+	Seed       uint64         `json:"seed"`
+	Population []ScoredGenome `json:"population"`
+}
+
+// Save writes the checkpoint as JSON.
+func (c *Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadCheckpoint reads a checkpoint saved by Save.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Checkpoint{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(c.Population) == 0 {
+		return nil, fmt.Errorf("%s: no genomes", path)
+	}
+	return c, nil
+}
+
+// LoadGenome reads the best genome from a checkpoint.
+func LoadGenome(path string) (Genome, error) {
+	c, err := LoadCheckpoint(path)
+	if err != nil {
+		return Genome{}, err
+	}
+	return c.Population[0].Genome, nil
+}
+
+// WeightedController moves to the position its Genome scores highest.
+type WeightedController struct {
+	Genome Genome
+}
+
+func (wc *WeightedController) GetDirection(arena *Arena, playerID int) Vector {
+	s := &treeSearch{arena: arena.DeepCopy(), me: playerID - 1}
+	player := s.arena.Players[s.me]
+	f := newFeatureFinder(s.arena, s.me)
+
+	// Moves that might run into an opponent's head are tried only if there
+	// is nothing else
+	moves := s.moves(s.me)
+	safe := []Vector{}
+	for _, dir := range moves {
+		if !isPossiblePlayerCollision(arena, playerID, dir) {
+			safe = append(safe, dir)
+		}
+	}
+	if len(safe) > 0 {
+		moves = safe
+	}
+
+	me := []int{s.me}
+	bestDir, bestScore := moves[0], math.Inf(-1)
+	for _, dir := range moves {
+		undo := s.makeMoves(me, []Vector{dir})
+		if player.IsAlive {
+			if score := wc.score(f.features(s)); score > bestScore {
+				bestDir, bestScore = dir, score
+			}
+		}
+		s.unmakeMoves(me, undo)
+	}
+	return bestDir
+}
+
+func (wc *WeightedController) score(features Genome) float64 {
+	weights := wc.Genome.Weights()
+	score := 0.0
+	for i, value := range features.Weights() {
+		score += *weights[i] * *value
+	}
+	return score
+}
+
+// featureFinder works out the features of positions for one player,
+// keeping its buffers between positions.
+type featureFinder struct {
+	arena *Arena
+	me    int
+	// number of squares in the arena, to scale the areas by
+	size float64
+	// steps from the player's head to each square, or -1 if not reached
+	dist  []int
+	queue []Vector
+}
+
+func newFeatureFinder(a *Arena, me int) *featureFinder {
+	return &featureFinder{
+		arena: a,
+		me:    me,
+		size:  float64(a.Width * a.Height),
+		dist:  make([]int, a.Width*a.Height),
+	}
+}
+
+// features measures the arena as it is, with the player's move made. The
+// result holds the value of each feature in place of its weight.
+func (f *featureFinder) features(s *treeSearch) Genome {
+	a := f.arena
+	p := a.Players[f.me]
+	areas, _ := s.voronoi()
+
+	straight := 0
+	for pos := a.Next(p.Position, p.Direction); !a.isCollision(pos) && straight < a.Width*a.Height; pos = a.Next(pos, p.Direction) {
+		straight++
+	}
+
+	reachable, opponentDist := f.flood(p.Position)
+	proximity := 0.0
+	if opponentDist >= 0 {
+		proximity = 1 / (1 + float64(opponentDist))
+	}
+	return Genome{
+		Area:              float64(areas[f.me]) / f.size,
+		WallDistance:      min(float64(straight)/float64(max(a.Width, a.Height)), 1),
+		OpponentProximity: proximity,
+		FloodFill:         float64(reachable) / f.size,
+	}
+}
+
+// flood counts the open squares reachable from start, and finds the fewest
+// steps from start to a square next to a living opponent's head, or -1 if
+// there is none.
+func (f *featureFinder) flood(start Vector) (int, int) {
+	a := f.arena
+	for i := range f.dist {
+		f.dist[i] = -1
+	}
+	f.dist[start.Y*a.Width+start.X] = 0
+	queue := append(f.queue[:0], start)
+	opponentDist := -1
+	for head := 0; head < len(queue); head++ {
+		pos := queue[head]
+		d := f.dist[pos.Y*a.Width+pos.X]
+		for _, dir := range []Vector{Up, Down, Left, Right} {
+			next := a.Next(pos, dir)
+			if opponentDist < 0 && f.isOpponentHead(next) {
+				opponentDist = d
+			}
+			k := next.Y*a.Width + next.X
+			if a.isCollision(next) || f.dist[k] >= 0 {
+				continue
+			}
+			f.dist[k] = d + 1
+			queue = append(queue, next)
+		}
+	}
+	f.queue = queue
+	// The start is the player's own head, not a free square
+	return len(queue) - 1, opponentDist
+}
+
+func (f *featureFinder) isOpponentHead(pos Vector) bool {
+	for i, p := range f.arena.Players {
+		if i != f.me && p.IsAlive && p.Position == pos {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWeightedFeatures(t *testing.T) {
+	text := "gocycle map\ngrid\n########\n#>.....#\n#......#\n#.....<#\n########\n"
+	arena := newMapArena(t, text, &mockController{}, &mockController{})
+	s := &treeSearch{arena: arena, me: 0}
+	f := newFeatureFinder(arena, 0)
+	s.makeMoves([]int{0}, []Vector{Right})
+
+	features := f.features(s)
+	// From (2, 1), 15 squares are still open, 4 of them straight ahead, and
+	// it is 5 steps to the squares next to the opponent's head
+	expected := Genome{
+		WallDistance:      4.0 / 8,
+		OpponentProximity: 1.0 / 6,
+		FloodFill:         15.0 / 40,
+	}
+	if features.WallDistance != expected.WallDistance || features.OpponentProximity != expected.OpponentProximity ||
+		features.FloodFill != expected.FloodFill {
+		t.Errorf("got %+v, want %+v", features, expected)
+	}
+	if features.Area <= 0 || features.Area >= features.FloodFill {
+		t.Errorf("area %v should be a part of the reachable %v", features.Area, features.FloodFill)
+	}
+}
+
+func TestWeightedControllerPrefersSpace(t *testing.T) {
+	// Going up leads into a dead end
+	text := "gocycle map\ngrid\n#######\n##.####\n#>....#\n#.....#\n#.....#\n#######\n"
+	wc := &WeightedController{Genome: Genome{FloodFill: 1}}
+	if dir := wc.GetDirection(newMapArena(t, text, &mockController{}), 1); dir == Up {
+		t.Errorf("expected to stay out of the dead end")
+	}
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "best.json")
+	genome := Genome{Area: 1.5, WallDistance: -0.25, OpponentProximity: 0.125, FloodFill: 2}
+	c := &Checkpoint{Generation: 3, Seed: 9, Population: []ScoredGenome{{genome, 0.75}, {DefaultGenome, 0.5}}}
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadGenome(path)
+	if err != nil || loaded != genome {
+		t.Errorf("got %+v, %v, want %+v", loaded, err, genome)
+	}
+	if err := (&Checkpoint{}).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGenome(path); err == nil {
+		t.Errorf("expected an error for a checkpoint without genomes")
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"github.com/jonathanacross/gamedev/gocycle/core"
 	"image/color"
+	"log"
 	"math/rand/v2"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -20,11 +24,77 @@ type GameState interface {
 
 type Game struct {
 	State GameState
+
+	// If set, each round is saved as a replay file in this directory
+	RecordDir string
+	// Rules for power-up items, or nil to play without them
+	Items *core.ItemRules
+	// How rounds are scored, and whether four players play in two teams
+	Scoring core.Scoring
+	Teams   bool
+	// Replay of the most recent round played, for watching it back
+	LastReplay *core.Replay
+	// Characters who played in LastReplay
+	LastReplayChars []*CharData
+	// If set, this game is hosting a network game
+	Host *core.Host
+	// Choices made on the match setup screen, saved to SettingsPath if it
+	// is set
+	Settings     core.MatchSettings
+	SettingsPath string
 }
 
 func NewGame() *Game {
 	return &Game{
-		State: &TitleScreenState{},
+		State:    &TitleScreenState{},
+		Settings: core.DefaultMatchSettings(),
+	}
+}
+
+// saveSettings keeps the match settings for the next session.
+func (g *Game) saveSettings() {
+	if g.SettingsPath == "" {
+		return
+	}
+	if err := g.Settings.Save(g.SettingsPath); err != nil {
+		log.Printf("could not save settings: %v", err)
+	}
+}
+
+// newController makes the controller for a character, which for a
+// computer character may have been changed in the match settings.
+func (g *Game) newController(char *CharData) core.PlayerController {
+	name := g.Settings.Controllers[char.Name]
+	if char.ControllerType == ComputerPlayer && !char.FromCommandLine && name != "" {
+		if controller, err := core.NewController(name); err == nil {
+			return controller
+		}
+	}
+	return char.NewController()
+}
+
+// keysFor returns the keys the first (0) or second (1) human player steers
+// with, in the order of keyDirections. Keys that ebiten doesn't know are
+// replaced by the defaults.
+func (g *Game) keysFor(player int) [4]ebiten.Key {
+	defaults := core.DefaultMatchSettings().Keys[player]
+	defaultNames := defaults.Keys()
+	var keys [4]ebiten.Key
+	for i, name := range g.Settings.Keys[player].Keys() {
+		if err := keys[i].UnmarshalText([]byte(*name)); err != nil {
+			keys[i].UnmarshalText([]byte(*defaultNames[i]))
+		}
+	}
+	return keys
+}
+
+// steer gives a human player the directions of any of its keys just
+// pressed.
+func steer(hc *core.HumanController, keys [4]ebiten.Key) {
+	for i, key := range keys {
+		if inpututil.IsKeyJustPressed(key) {
+			hc.EnqueueDirection(keyDirections[i])
+		}
 	}
 }
 
@@ -82,7 +152,7 @@ type TitleScreenState struct{}
 
 func (gs *TitleScreenState) Update(g *Game) error {
 	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-		g.State = NewCharacterPickerState()
+		g.State = NewMatchSetupState(g)
 	}
 	return nil
 }
@@ -94,15 +164,51 @@ func (gs *TitleScreenState) Draw(g *Game, screen *ebiten.Image) {
 	drawTextAt(screen, "Press Space", ScreenWidth/2, 2*ScreenHeight/4, text.AlignCenter, color.White)
 }
 
+// ------------------- Match Setup State
+
+type MatchSetupState struct {
+	Setup *MatchSetup
+}
+
+func NewMatchSetupState(g *Game) *MatchSetupState {
+	return &MatchSetupState{
+		Setup: NewMatchSetup(&g.Settings),
+	}
+}
+
+func (gs *MatchSetupState) Update(g *Game) error {
+	if gs.Setup.WaitingForKey() {
+		gs.Setup.Update()
+		return nil
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeySpace):
+		g.saveSettings()
+		g.State = NewCharacterPickerState(g.Host)
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.saveSettings()
+		g.State = &TitleScreenState{}
+	default:
+		gs.Setup.Update()
+	}
+	return nil
+}
+
+func (gs *MatchSetupState) Draw(g *Game, screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	screen.DrawImage(GridImage, op)
+	gs.Setup.Draw(screen)
+}
+
 // ------------------- Character Picker State
 
 type CharacterPickerState struct {
 	Picker *CharacterPicker
 }
 
-func NewCharacterPickerState() *CharacterPickerState {
+func NewCharacterPickerState(host *core.Host) *CharacterPickerState {
 	return &CharacterPickerState{
-		Picker: NewCharacterPicker(),
+		Picker: NewCharacterPicker(host),
 	}
 }
 
@@ -121,7 +227,7 @@ func (gs *CharacterPickerState) Update(g *Game) error {
 			initialScores[char.ID] = 0
 		}
 
-		g.State = NewGamePlayState(selectedChars, 0, initialScores)
+		g.State = NewGamePlayState(g, selectedChars, 0, initialScores)
 	}
 
 	return nil
@@ -138,11 +244,28 @@ func (gs *CharacterPickerState) Draw(g *Game, screen *ebiten.Image) {
 type ScoreScreenState struct {
 	CharacterCards []*CharacterFrame
 	Scores         map[int]int // Key: character ID, Value: total score across rounds
+	Scoring        core.Scoring
+	Teams          map[int]int // Key: character ID, Value: team; nil if there were no teams
 }
 
-func NewScoreScreenState(chars []*CharData, scores map[int]int) *ScoreScreenState {
-	sort.Slice(chars, func(i, j int) bool {
-		return scores[chars[i].ID] > scores[chars[j].ID]
+// NewScoreScreenState shows the final scores of the characters, who played
+// in the given order under the given rules.
+func NewScoreScreenState(chars []*CharData, scores map[int]int, rules core.ScoreRules) *ScoreScreenState {
+	var teams map[int]int
+	if rules.Teams != nil {
+		teams = map[int]int{}
+		for i, char := range chars {
+			teams[char.ID] = rules.Teams[i]
+		}
+	}
+	// Teammates have the same score, so keep them together
+	chars = append([]*CharData{}, chars...)
+	sort.SliceStable(chars, func(i, j int) bool {
+		si, sj := scores[chars[i].ID], scores[chars[j].ID]
+		if si != sj {
+			return si > sj
+		}
+		return teams[chars[i].ID] < teams[chars[j].ID]
 	})
 
 	winnerScore := scores[chars[0].ID]
@@ -167,13 +290,33 @@ func NewScoreScreenState(chars []*CharData, scores map[int]int) *ScoreScreenStat
 	return &ScoreScreenState{
 		CharacterCards: cards,
 		Scores:         scores,
+		Scoring:        rules.Scoring,
+		Teams:          teams,
 	}
 }
 
+// teamLabel names a team for the screen.
+func teamLabel(team int) string {
+	return fmt.Sprintf("T%d", team+1)
+}
+
+// teamResult says which team won, given the characters ordered by score.
+func (gs *ScoreScreenState) teamResult() string {
+	first := gs.CharacterCards[0].CharData.ID
+	last := gs.CharacterCards[len(gs.CharacterCards)-1].CharData.ID
+	if gs.Scores[first] == gs.Scores[last] {
+		return "The teams tie"
+	}
+	return fmt.Sprintf("Team %d wins", gs.Teams[first]+1)
+}
+
 func (gs *ScoreScreenState) Update(g *Game) error {
 	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
 		g.State = &TitleScreenState{}
 	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) && g.LastReplay != nil {
+		g.State = NewReplayState(g.LastReplay, g.LastReplayChars, g.Settings.TickMillis, gs)
+	}
 
 	return nil
 }
@@ -189,9 +332,20 @@ func (gs *ScoreScreenState) Draw(g *Game, screen *ebiten.Image) {
 		scoreX := card.X + card.HitBox().Width()
 		scoreY := card.Y + card.HitBox().Height() + ScoreOffset
 		drawShadowedTextAt(screen, scoreText, scoreX, scoreY, text.AlignEnd, color.White)
+		if gs.Teams != nil {
+			drawShadowedTextAt(screen, teamLabel(gs.Teams[card.CharData.ID]), card.X, scoreY, text.AlignStart, color.White)
+		}
 	}
 
+	heading := fmt.Sprintf("Scoring: %s", gs.Scoring)
+	if gs.Teams != nil {
+		heading += " - " + gs.teamResult()
+	}
+	drawShadowedTextAt(screen, heading, ScreenWidth/2, 10, text.AlignCenter, color.White)
 	drawShadowedTextAt(screen, "Press Space", ScreenWidth/2, 3*ScreenHeight/5, text.AlignCenter, color.White)
+	if g.LastReplay != nil {
+		drawShadowedTextAt(screen, "R: watch the last round", ScreenWidth/2, 3*ScreenHeight/5+20, text.AlignCenter, color.White)
+	}
 }
 
 // ------------------- Game Play State
@@ -202,24 +356,26 @@ type GamePlayState struct {
 	ArenaTimeSpeedMs   int
 	HumanController1   *core.HumanController
 	HumanController2   *core.HumanController
+	HumanKeys          [2][4]ebiten.Key
+	Host               *core.Host // if hosting, clients are sent each tick
 	CharacterCards     []*CharacterFrame
+	Replay             *core.Replay
 	WaitingForStart    bool
 	WaitingForNewRound bool
 	EndRoundTimer      *Timer
 	Round              int
-	PreviousIsAlive    []bool      // Tracks status for score calculation
-	RemainingRanks     []int       // Scores pool for tie-breaking
+	Scorer             *core.RoundScorer
 	TotalScores        map[int]int // Key: character ID, Value: total score across rounds
 	RoundScores        map[int]int // Key: character ID, Value: score for this round
 }
 
-func NewGamePlayState(characters []*CharData, round int, prevTotalScores map[int]int) *GamePlayState {
+func NewGamePlayState(g *Game, characters []*CharData, round int, prevTotalScores map[int]int) *GamePlayState {
 	var human1 *core.HumanController
 	var human2 *core.HumanController
 
-	// Get starting Locations
+	// Only maps with room for everyone are played
 	numPlayers := len(characters)
-	arenaLocs := core.GetStartVectors(numPlayers)
+	arenaMap := g.Settings.ArenaForRound(round, numPlayers, core.NewRand(rand.Uint64()))
 	positionData := PositionDataByNumChars[numPlayers]
 
 	cards := []*CharacterFrame{}
@@ -228,17 +384,17 @@ func NewGamePlayState(characters []*CharData, round int, prevTotalScores map[int
 			positionData[i].CardX, positionData[i].CardY, CharacterNeutral, false))
 	}
 
-	players := []*core.Player{}
-
-	// Initial directions correspond to the GetStartVectors order: Top-Left=Right, Bottom-Right=Left, Bottom-Left=Up, Top-Right=Down
-	initialDirections := []core.Vector{core.Right, core.Left, core.Up, core.Down}
-
+	controllers := []core.PlayerController{}
+	// When hosting, seats[i] is the player steered by network client i
+	seats := []int{}
 	for i, char := range characters {
-		controllerInstance := char.NewController()
-
-		// Use the core.Vector from core.GetStartVectors
-		players = append(players, core.NewPlayer(i+1,
-			arenaLocs[i], initialDirections[i], controllerInstance))
+		if char.ControllerType == HumanSecondPlayer && g.Host != nil {
+			controllers = append(controllers, g.Host.Controller(len(seats)))
+			seats = append(seats, i)
+			continue
+		}
+		controllerInstance := g.newController(char)
+		controllers = append(controllers, controllerInstance)
 
 		// Check and assign Human controllers for input handling using the fresh instance
 		switch char.ControllerType {
@@ -248,11 +404,37 @@ func NewGamePlayState(characters []*CharData, round int, prevTotalScores map[int
 			human2 = controllerInstance.(*core.HumanController)
 		}
 	}
-	var arena = core.NewArenaFromGrid(core.GetGrid(round), players)
+	// Players start at the map's spawn points, in order
+	players := arenaMap.NewPlayers(controllers)
+
+	// Log the seed so an interesting round can be replayed with SimulateRound
+	seed := rand.Uint64()
+	log.Printf("round %d map %s seed %d", round, arenaMap.Name, seed)
+	var arena = core.NewArenaFromMap(arenaMap, players, core.NewRand(seed))
+	if g.Items != nil {
+		arena.EnableItems(*g.Items)
+	}
+
+	names := []string{}
+	for _, char := range characters {
+		names = append(names, char.Name)
+	}
+	replay := core.NewReplay(arenaMap, seed, players, names)
+	arena.Record(replay)
+	if g.Host != nil {
+		// The clients are told which characters are playing
+		ids := []string{}
+		for _, char := range characters {
+			ids = append(ids, strconv.Itoa(char.ID))
+		}
+		if err := g.Host.StartRound(replay, seats, strings.Join(ids, ",")); err != nil {
+			log.Printf("could not start network round: %v", err)
+		}
+	}
 
-	initialStatus := make([]bool, numPlayers)
-	for i := range numPlayers {
-		initialStatus[i] = players[i].IsAlive
+	rules := core.ScoreRules{Scoring: g.Scoring}
+	if g.Teams {
+		rules.Teams = core.PairTeams(numPlayers)
 	}
 
 	roundScores := make(map[int]int)
@@ -262,24 +444,21 @@ func NewGamePlayState(characters []*CharData, round int, prevTotalScores map[int
 		totalScores[char.ID] = prevTotalScores[char.ID]
 	}
 
-	initialRanks := []int{}
-	for rank := range numPlayers {
-		initialRanks = append(initialRanks, rank*2)
-	}
-
 	return &GamePlayState{
 		ArenaView:          NewArenaView(arena, characters),
-		ArenaTimer:         NewTimer(GameUpdateSpeedMillis * time.Millisecond),
-		ArenaTimeSpeedMs:   GameUpdateSpeedMillis,
+		ArenaTimer:         NewTimer(time.Duration(g.Settings.TickMillis) * time.Millisecond),
+		ArenaTimeSpeedMs:   g.Settings.TickMillis,
 		HumanController1:   human1,
 		HumanController2:   human2,
+		HumanKeys:          [2][4]ebiten.Key{g.keysFor(0), g.keysFor(1)},
+		Host:               g.Host,
 		CharacterCards:     cards,
+		Replay:             replay,
 		WaitingForStart:    true,
 		WaitingForNewRound: false,
 		EndRoundTimer:      NewTimer(2 * time.Second),
 		Round:              round,
-		PreviousIsAlive:    initialStatus,
-		RemainingRanks:     initialRanks,
+		Scorer:             core.NewRoundScorer(arena, rules),
 		RoundScores:        roundScores,
 		TotalScores:        totalScores,
 	}
@@ -306,10 +485,10 @@ func (gs *GamePlayState) Update(g *Game) error {
 			for k, v := range gs.TotalScores {
 				newTotals[k] = v + gs.RoundScores[k]
 			}
-			if nextRound < NumRounds {
-				g.State = NewGamePlayState(gs.ArenaView.Characters, nextRound, newTotals)
+			if nextRound < g.Settings.Rounds {
+				g.State = NewGamePlayState(g, gs.ArenaView.Characters, nextRound, newTotals)
 			} else {
-				g.State = NewScoreScreenState(gs.ArenaView.Characters, newTotals)
+				g.State = NewScoreScreenState(gs.ArenaView.Characters, newTotals, gs.Scorer.Rules)
 			}
 		}
 		return nil
@@ -318,32 +497,10 @@ func (gs *GamePlayState) Update(g *Game) error {
 	// Main game play
 
 	if gs.HumanController1 != nil {
-		if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
-			gs.HumanController1.EnqueueDirection(core.Up)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
-			gs.HumanController1.EnqueueDirection(core.Down)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
-			gs.HumanController1.EnqueueDirection(core.Left)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
-			gs.HumanController1.EnqueueDirection(core.Right)
-		}
+		steer(gs.HumanController1, gs.HumanKeys[0])
 	}
 	if gs.HumanController2 != nil {
-		if inpututil.IsKeyJustPressed(ebiten.KeyW) {
-			gs.HumanController2.EnqueueDirection(core.Up)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyS) {
-			gs.HumanController2.EnqueueDirection(core.Down)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyA) {
-			gs.HumanController2.EnqueueDirection(core.Left)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyD) {
-			gs.HumanController2.EnqueueDirection(core.Right)
-		}
+		steer(gs.HumanController2, gs.HumanKeys[1])
 	}
 
 	// Allow player to speed up the game if there are no active human players
@@ -358,24 +515,36 @@ func (gs *GamePlayState) Update(g *Game) error {
 		gs.handleArenaUpdate()
 
 		// Check end of round
-		numActivePlayers := 0
-		for _, p := range gs.ArenaView.Arena.Players {
-			if p.IsAlive {
-				numActivePlayers++
-			}
-		}
-
-		if numActivePlayers <= 1 {
+		if gs.Scorer.Over() {
 			// Score remaining player(s) and end the round
-			gs.scoreRemainingPlayers()
+			gs.setRoundScores(gs.Scorer.Finish())
+			core.CloseControllers(gs.ArenaView.Arena.Players)
 			gs.WaitingForNewRound = true
 			gs.EndRoundTimer.Reset()
+			gs.saveReplay(g)
 		}
 	}
 
 	return nil
 }
 
+// saveReplay keeps the finished round's replay, and writes it to a file
+// if recording is on.
+func (gs *GamePlayState) saveReplay(g *Game) {
+	g.LastReplay = gs.Replay
+	g.LastReplayChars = gs.ArenaView.Characters
+	if g.RecordDir == "" {
+		return
+	}
+	name := fmt.Sprintf("%s-round%d.replay", time.Now().Format("20060102-150405"), gs.Round+1)
+	path := filepath.Join(g.RecordDir, name)
+	if err := gs.Replay.Save(path); err != nil {
+		log.Printf("could not save replay: %v", err)
+		return
+	}
+	log.Printf("saved replay to %s", path)
+}
+
 func (gs *GamePlayState) AllHumanPlayersDead() bool {
 	hasHuman := false
 	humansAlive := false
@@ -394,117 +563,384 @@ func (gs *GamePlayState) AllHumanPlayersDead() bool {
 
 // handleArenaUpdate runs one game tick and updates scoring for dead players.
 func (gs *GamePlayState) handleArenaUpdate() {
-	gs.ArenaView.Arena.Update()
+	gs.Scorer.Update()
+	if gs.Host != nil {
+		if err := gs.Host.SendTick(gs.Replay.Ticks[len(gs.Replay.Ticks)-1]); err != nil {
+			log.Printf("could not send tick: %v", err)
+		}
+	}
 
-	// 1. Score players who died *this tick* using the core logic.
-	gs.scoreDiedPlayers()
+	// Score players who were knocked out this tick
+	gs.setRoundScores(gs.Scorer.Scores())
+}
 
-	// 2. Update previous status for the next tick.
+// setRoundScores translates the scores by player ID from the core scoring
+// into the UI's CharData-keyed map.
+func (gs *GamePlayState) setRoundScores(scoresByID map[int]int) {
 	for i, p := range gs.ArenaView.Arena.Players {
-		gs.PreviousIsAlive[i] = p.IsAlive
+		char := gs.ArenaView.Characters[i]
+		gs.RoundScores[char.ID] = scoresByID[p.ID]
 	}
 }
 
-// scoreDiedPlayers calls the core scoring function and translates the results back
-// to the UI's CharData-keyed map.
-func (gs *GamePlayState) scoreDiedPlayers() {
-	players := gs.ArenaView.Arena.Players
+func (gs *GamePlayState) Draw(g *Game, screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	screen.DrawImage(GridImage, op)
+	roundString := fmt.Sprintf("Round %d/%d", gs.Round+1, g.Settings.Rounds)
+	drawTextAt(screen, roundString, 90, 10, text.AlignStart, color.White)
+	gs.ArenaView.Draw(screen)
+	for _, card := range gs.CharacterCards {
+		card.Draw(screen)
 
-	// Create a temporary map to hold scores by Player ID for the core function
-	roundScoresByID := make(map[int]int)
-	for i, p := range players {
-		char := gs.ArenaView.Characters[i]
-		roundScoresByID[p.ID] = gs.RoundScores[char.ID]
+		// Draw the scores below each card
+		roundScore := gs.RoundScores[card.CharData.ID]
+		if roundScore >= 0 {
+			scoreText := fmt.Sprintf("%d", roundScore)
+			scoreX := card.X + card.HitBox().Width()
+			scoreY := card.Y + card.HitBox().Height() + ScoreOffset
+			drawShadowedTextAt(screen, scoreText, scoreX, scoreY, text.AlignEnd, color.White)
+		}
 	}
+	if teams := gs.Scorer.Rules.Teams; teams != nil {
+		for i, card := range gs.CharacterCards {
+			scoreY := card.Y + card.HitBox().Height() + ScoreOffset
+			drawShadowedTextAt(screen, teamLabel(teams[i]), card.X, scoreY, text.AlignStart, color.White)
+		}
+	}
+}
 
-	// Update roundScoresByID and gs.RemainingRanks
-	core.HandleScoreUpdate(players, gs.PreviousIsAlive, roundScoresByID, &gs.RemainingRanks)
+// ------------------- Replay State
 
-	// Apply the new scores back to the local UI-specific map and update card status
-	for i, p := range players {
-		char := gs.ArenaView.Characters[i]
-		newScore := roundScoresByID[p.ID]
-		gs.RoundScores[char.ID] = newScore
+const (
+	MinReplaySpeedMillis = 25
+	MaxReplaySpeedMillis = 1600
+)
+
+// ReplayState plays back a recorded round, with controls to pause, step
+// through it a tick at a time, and change the speed.
+type ReplayState struct {
+	Replay         *core.Replay
+	ArenaView      *ArenaView
+	CharacterCards []*CharacterFrame
+	Tick           int
+	Playing        bool
+	SpeedMs        int
+	// Time between ticks in the round itself, shown as normal speed
+	RoundSpeedMs int
+	Timer        *Timer
+	// State to return to when the replay is closed
+	Previous GameState
+}
+
+func NewReplayState(replay *core.Replay, characters []*CharData, tickMillis int, previous GameState) *ReplayState {
+	if characters == nil {
+		characters = charactersForReplay(replay)
+	}
+	positionData := PositionDataByNumChars[len(characters)]
+	cards := []*CharacterFrame{}
+	for i, char := range characters {
+		cards = append(cards, NewCharacterFrame(char,
+			positionData[i].CardX, positionData[i].CardY, CharacterNeutral, false))
+	}
+
+	return &ReplayState{
+		Replay:         replay,
+		ArenaView:      NewArenaView(replay.NewArena(), characters),
+		CharacterCards: cards,
+		Playing:        false,
+		SpeedMs:        tickMillis,
+		RoundSpeedMs:   tickMillis,
+		Timer:          NewTimer(time.Duration(tickMillis) * time.Millisecond),
+		Previous:       previous,
+	}
+}
+
+// charactersForReplay finds the characters named in a replay file, using
+// any character for players it doesn't recognize.
+func charactersForReplay(replay *core.Replay) []*CharData {
+	chars := []*CharData{}
+	for i := range replay.Starts {
+		char := &Characters[i%NumCharacters]
+		for j := range Characters {
+			if i < len(replay.Names) && Characters[j].Name == replay.Names[i] {
+				char = &Characters[j]
+				break
+			}
+		}
+		chars = append(chars, char)
 	}
+	return chars
 }
 
-// scoreRemainingPlayers scores the final winner(s) or the last tie group.
-func (gs *GamePlayState) scoreRemainingPlayers() {
-	players := gs.ArenaView.Arena.Players
+func (gs *ReplayState) restart() {
+	gs.ArenaView.Arena = gs.Replay.NewArena()
+	gs.Tick = 0
+}
 
-	// Create a temporary map to hold scores by Player ID for the core function
-	roundScoresByID := make(map[int]int)
-	for i, p := range players {
-		char := gs.ArenaView.Characters[i]
-		roundScoresByID[p.ID] = gs.RoundScores[char.ID]
+// step plays the next tick, pausing at the end of the round.
+func (gs *ReplayState) step() {
+	if gs.Tick >= len(gs.Replay.Ticks) {
+		gs.Playing = false
+		return
 	}
+	gs.ArenaView.Update()
+	gs.Tick++
+}
 
-	core.ScoreRemainingPlayers(players, roundScoresByID, gs.RemainingRanks)
+func (gs *ReplayState) setSpeed(ms int) {
+	gs.SpeedMs = min(max(ms, MinReplaySpeedMillis), MaxReplaySpeedMillis)
+	gs.Timer = NewTimer(time.Duration(gs.SpeedMs) * time.Millisecond)
+}
 
-	// Apply the final scores back to the local UI-specific map and update card status
-	for i, p := range players {
-		char := gs.ArenaView.Characters[i]
-		finalScore := roundScoresByID[p.ID]
-		if finalScore >= 0 && gs.RoundScores[char.ID] == -1 {
-			gs.RoundScores[char.ID] = finalScore
+func (gs *ReplayState) Update(g *Game) error {
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		if gs.Previous != nil {
+			g.State = gs.Previous
+		} else {
+			g.State = &TitleScreenState{}
+		}
+		return nil
+	case inpututil.IsKeyJustPressed(ebiten.KeySpace):
+		if gs.Tick >= len(gs.Replay.Ticks) {
+			gs.restart()
+		}
+		gs.Playing = !gs.Playing
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowRight):
+		gs.Playing = false
+		gs.step()
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowUp):
+		gs.setSpeed(gs.SpeedMs / 2)
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowDown):
+		gs.setSpeed(gs.SpeedMs * 2)
+	case inpututil.IsKeyJustPressed(ebiten.KeyR):
+		gs.restart()
+	}
+
+	if gs.Playing {
+		gs.Timer.Update()
+		if gs.Timer.IsReady() {
+			gs.Timer.Reset()
+			gs.step()
 		}
 	}
+	return nil
 }
 
-func (gs *GamePlayState) Draw(g *Game, screen *ebiten.Image) {
+func (gs *ReplayState) Draw(g *Game, screen *ebiten.Image) {
 	op := &ebiten.DrawImageOptions{}
 	screen.DrawImage(GridImage, op)
-	roundString := fmt.Sprintf("Round %d/%d", gs.Round+1, NumRounds)
-	drawTextAt(screen, roundString, 90, 10, text.AlignStart, color.White)
+	status := fmt.Sprintf("Replay %d/%d  x%.2g", gs.Tick, len(gs.Replay.Ticks),
+		float64(gs.RoundSpeedMs)/float64(gs.SpeedMs))
+	drawTextAt(screen, status, 90, 10, text.AlignStart, color.White)
 	gs.ArenaView.Draw(screen)
 	for _, card := range gs.CharacterCards {
 		card.Draw(screen)
+	}
 
-		// Draw the scores below each card
-		roundScore := gs.RoundScores[card.CharData.ID]
-		if roundScore >= 0 {
-			scoreText := fmt.Sprintf("%d", roundScore)
-			scoreX := card.X + card.HitBox().Width()
-			scoreY := card.Y + card.HitBox().Height() + ScoreOffset
-			drawShadowedTextAt(screen, scoreText, scoreX, scoreY, text.AlignEnd, color.White)
+	if !gs.Playing {
+		help := []string{"Space: play/pause", "Right: step", "Up/Down: speed", "R: restart", "Esc: back"}
+		for i, line := range help {
+			y := float64(ArenaOffsetY + 60 + 16*i)
+			drawShadowedTextAt(screen, line, ScreenWidth/2, y, text.AlignCenter, color.White)
+		}
+	}
+}
+
+// ------------------- Network Client State
+
+// NetClientState plays in a game hosted on another computer. The host runs
+// the game and says what every player did each tick; this sends the
+// player's turns and shows the rounds as they happen.
+type NetClientState struct {
+	Client         *core.Client
+	HostAddr       string
+	Lobby          []string
+	Round          *core.NetRound
+	ArenaView      *ArenaView
+	CharacterCards []*CharacterFrame
+	Human          *core.HumanController
+	// Tick of the round that a turn has been sent for, so only one turn is
+	// sent each tick
+	SentTick int
+	// Shown instead of the round number if something has gone wrong
+	Status string
+}
+
+func NewNetClientState(client *core.Client, hostAddr string) *NetClientState {
+	return &NetClientState{
+		Client:   client,
+		HostAddr: hostAddr,
+		SentTick: -1,
+	}
+}
+
+// charactersForNetRound finds the characters the host listed in the
+// round's info, or goes by the names in the replay if they aren't valid.
+func charactersForNetRound(round *core.NetRound) []*CharData {
+	chars := []*CharData{}
+	for _, field := range strings.Split(round.Info, ",") {
+		id, err := strconv.Atoi(field)
+		if err != nil || id < 1 || id > NumCharacters {
+			return charactersForReplay(round.Replay)
+		}
+		chars = append(chars, &Characters[id-1])
+	}
+	if len(chars) != len(round.Replay.Starts) {
+		return charactersForReplay(round.Replay)
+	}
+	return chars
+}
+
+func (gs *NetClientState) Update(g *Game) error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		gs.Client.Close()
+		g.State = &TitleScreenState{}
+		return nil
+	}
+
+	if gs.Round != nil && gs.Round.Seat >= 0 {
+		steer(gs.Human, g.keysFor(0))
+	}
+
+	for {
+		select {
+		case msg, ok := <-gs.Client.Messages():
+			if !ok {
+				gs.sendTurn()
+				return nil
+			}
+			gs.handleMessage(msg)
+			continue
+		default:
+		}
+		break
+	}
+	gs.sendTurn()
+	return nil
+}
+
+func (gs *NetClientState) handleMessage(msg core.NetMessage) {
+	switch msg.Kind {
+	case core.NetLobby:
+		gs.Lobby = msg.Names
+	case core.NetRoundStart:
+		chars := charactersForNetRound(msg.Round)
+		positionData := PositionDataByNumChars[len(chars)]
+		cards := []*CharacterFrame{}
+		for i, char := range chars {
+			cards = append(cards, NewCharacterFrame(char,
+				positionData[i].CardX, positionData[i].CardY, CharacterNeutral, false))
+		}
+		gs.Round = msg.Round
+		gs.ArenaView = NewArenaView(msg.Round.Arena, chars)
+		gs.CharacterCards = cards
+		gs.Human = core.NewHumanController()
+		gs.SentTick = -1
+	case core.NetTick:
+		if gs.Round == nil {
+			return
+		}
+		if err := gs.Round.Apply(msg.Tick, msg.Dirs); err != nil {
+			log.Printf("network game: %v", err)
+			gs.Status = "Out of step with the host"
+			gs.Client.Close()
+		}
+	case core.NetClosed:
+		if msg.Err != nil {
+			log.Printf("network game: %v", msg.Err)
 		}
+		gs.Status = "Disconnected"
+	}
+}
+
+// sendTurn sends the next turn the player has asked for, if one hasn't
+// been sent for this tick already. Going straight on needs no message,
+// since that is what the host does when it has none.
+func (gs *NetClientState) sendTurn() {
+	if gs.Round == nil || gs.Round.Seat < 0 || len(gs.Human.InputQueue) == 0 {
+		return
+	}
+	tick := len(gs.Round.Replay.Ticks)
+	if tick == gs.SentTick || !gs.Round.Arena.Players[gs.Round.Seat].IsAlive {
+		return
+	}
+	dir := gs.Human.GetDirection(gs.Round.Arena, gs.Round.Seat+1)
+	if err := gs.Client.SendMove(tick, dir); err != nil {
+		log.Printf("network game: %v", err)
+	}
+	gs.SentTick = tick
+}
+
+// roundOver reports whether the current round has finished.
+func (gs *NetClientState) roundOver() bool {
+	numActivePlayers := 0
+	for _, p := range gs.Round.Arena.Players {
+		if p.IsAlive {
+			numActivePlayers++
+		}
+	}
+	return numActivePlayers <= 1
+}
+
+func (gs *NetClientState) Draw(g *Game, screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	screen.DrawImage(GridImage, op)
+
+	status := gs.Status
+	if gs.Round == nil {
+		lines := []string{"Joined " + gs.HostAddr, "Waiting for the host to start", strings.Join(gs.Lobby, ", ")}
+		if status != "" {
+			lines = []string{status, "Esc: back"}
+		}
+		for i, line := range lines {
+			drawShadowedTextAt(screen, line, ScreenWidth/2, float64(ScreenHeight/3+20*i), text.AlignCenter, color.White)
+		}
+		return
+	}
+
+	if status == "" {
+		status = "Network game"
+	}
+	drawTextAt(screen, status, 90, 10, text.AlignStart, color.White)
+	gs.ArenaView.Draw(screen)
+	for _, card := range gs.CharacterCards {
+		card.Draw(screen)
+	}
+	if gs.roundOver() && gs.Status == "" {
+		drawShadowedTextAt(screen, "Waiting for the next round", ScreenWidth/2, 3*ScreenHeight/5, text.AlignCenter, color.White)
 	}
 }
 
 var PositionDataByNumChars = getPositionData()
 
 type PositionData struct {
-	ArenaLoc core.Vector
-	CardX    float64
-	CardY    float64
+	CardX float64
+	CardY float64
 }
 
-// Positions of where to put players in the arena and where to draw the player
-// cards.  This depends on the number of players.
+// Positions of where to draw the player cards.  This depends on the number
+// of players; where the players start comes from the map.
 func getPositionData() [][]PositionData {
-	lo := 12
-	// TODO: this should be 37, but there's a bug when players don't all
-	// start with even coordinates.
-	hi := 38
 	return [][]PositionData{
 		{},
 		{
-			{ArenaLoc: core.Vector{X: lo, Y: lo}, CardX: 10, CardY: 10},
+			{CardX: 10, CardY: 10},
 		},
 		{
-			{ArenaLoc: core.Vector{X: lo, Y: lo}, CardX: 10, CardY: 10},
-			{ArenaLoc: core.Vector{X: hi, Y: hi}, CardX: 300, CardY: 10},
+			{CardX: 10, CardY: 10},
+			{CardX: 300, CardY: 10},
 		},
 		{
-			{ArenaLoc: core.Vector{X: lo, Y: lo}, CardX: 10, CardY: 10},
-			{ArenaLoc: core.Vector{X: hi, Y: hi}, CardX: 300, CardY: 10},
-			{ArenaLoc: core.Vector{X: lo, Y: hi}, CardX: 10, CardY: 120},
+			{CardX: 10, CardY: 10},
+			{CardX: 300, CardY: 10},
+			{CardX: 10, CardY: 120},
 		},
 		{
-			{ArenaLoc: core.Vector{X: lo, Y: lo}, CardX: 10, CardY: 10},
-			{ArenaLoc: core.Vector{X: hi, Y: hi}, CardX: 300, CardY: 120},
-			{ArenaLoc: core.Vector{X: lo, Y: hi}, CardX: 10, CardY: 120},
-			{ArenaLoc: core.Vector{X: hi, Y: lo}, CardX: 300, CardY: 10},
+			{CardX: 10, CardY: 10},
+			{CardX: 300, CardY: 120},
+			{CardX: 10, CardY: 120},
+			{CardX: 300, CardY: 10},
 		},
 	}
 }
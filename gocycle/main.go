@@ -1,18 +1,22 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"io/fs"
 	"log"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/jonathanacross/gamedev/gocycle/core"
 )
 
 const (
 	ArenaOffsetX = 90
 	ArenaOffsetY = 30
-	SquareSize   = 4
-
-	GameUpdateSpeedMillis = 100
-	NumRounds             = 5
+	// Width and height of the space for the arena; maps of any size are
+	// scaled to fit
+	ArenaSize = 200
 
 	ScreenWidth  = 384
 	ScreenHeight = 240
@@ -25,7 +29,106 @@ const (
 )
 
 func main() {
+	recordDir := flag.String("record-dir", "", "Save a replay of each round to this directory.")
+	replayPath := flag.String("replay", "", "Watch a replay file instead of playing.")
+	mapDir := flag.String("maps", "maps", "Directory of extra arena maps (text or PNG files).")
+	items := flag.Bool("items", false, "Play with power-up items.")
+	scoring := flag.String("scoring", "rank", "How to score rounds: "+strings.Join(core.ScoringNames(), ", ")+".")
+	teams := flag.Bool("teams", false, "With four players, play 2v2: the first and third characters picked against the second and fourth.")
+	host := flag.String("host", "", "Host a network game on this address, such as :7777; Player 2 characters are played by whoever joins.")
+	join := flag.String("join", "", "Join the network game hosted at this address, such as 192.168.1.5:7777.")
+	name := flag.String("name", "Player", "Your name in a network game, without spaces.")
+	defaultSettings, err := core.SettingsPath()
+	if err != nil {
+		// Settings can still be changed; they just won't be kept
+		defaultSettings = ""
+	}
+	settingsPath := flag.String("settings", defaultSettings, "File the match settings are kept in between sessions; empty to not keep them.")
+	bots := []string{}
+	flag.Func("bot", "Have a computer character played by an external program, as name=command, such as \"Biff=python3 bot.py\"; may be repeated.", func(s string) error {
+		bots = append(bots, s)
+		return nil
+	})
+	genomes := []string{}
+	flag.Func("genome", "Have a computer character played with the best weights in a trainer checkpoint, as name=file, such as \"Elara=genomes/best.json\"; may be repeated.", func(s string) error {
+		genomes = append(genomes, s)
+		return nil
+	})
+	botTimeout := flag.Duration("bot-timeout", core.DefaultBotTimeout, "Time each external bot has to answer each tick.")
+	// Handled when the characters are loaded; declared so it is accepted here.
+	flag.Bool("swimsuits", false, "Use the alternate character images.")
+	flag.Parse()
+
+	// The built-in maps are enough to play, so carry on without the extra
+	// ones if they can't be loaded
+	if err := core.AddMaps(*mapDir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("could not load maps: %v", err)
+	}
+	for _, bot := range bots {
+		name, command, err := core.ParseBot(bot)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = UseController(name, func() core.PlayerController {
+			return core.NewExternalController(command, *botTimeout)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, spec := range genomes {
+		name, path, ok := strings.Cut(spec, "=")
+		if !ok {
+			log.Fatalf("invalid genome %q: expected name=file", spec)
+		}
+		genome, err := core.LoadGenome(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = UseController(name, func() core.PlayerController {
+			return &core.WeightedController{Genome: genome}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 	game := NewGame()
+	game.RecordDir = *recordDir
+	game.SettingsPath = *settingsPath
+	if *settingsPath != "" {
+		if game.Settings, err = core.LoadMatchSettings(*settingsPath); err != nil {
+			log.Printf("could not load settings: %v", err)
+		}
+	}
+	if *items {
+		game.Items = &core.DefaultItemRules
+	}
+	if game.Scoring, err = core.ParseScoring(*scoring); err != nil {
+		log.Fatal(err)
+	}
+	game.Teams = *teams
+	if *host != "" {
+		h, err := core.Listen(*host)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("hosting on %s", h.Addr())
+		game.Host = h
+	}
+	if *join != "" {
+		client, err := core.Join(*join, *name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game.State = NewNetClientState(client, *join)
+	}
+	if *replayPath != "" {
+		replay, err := core.LoadReplay(*replayPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game.State = NewReplayState(replay, nil, game.Settings.TickMillis, nil)
+	}
 	ebiten.SetWindowSize(3*ScreenWidth, 3*ScreenHeight)
 	ebiten.SetWindowTitle("GoCycle")
 	if err := ebiten.RunGame(game); err != nil {
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/jonathanacross/gamedev/gocycle/core"
+)
+
+const (
+	SetupStartX      = 40
+	SetupStartY      = 28
+	SetupRowHeight   = 16
+	SetupVisibleRows = 11
+	// Change in the time between ticks for each press
+	SetupTickStepMillis = 10
+)
+
+var (
+	SetupSelectedColor = color.RGBA{248, 243, 79, 255}
+	SetupFixedColor    = color.RGBA{160, 160, 160, 255}
+)
+
+// keyDirections are the directions steered by a player's keys, in the
+// order of core.KeyBindings.Keys.
+var keyDirections = [4]core.Vector{core.Up, core.Down, core.Left, core.Right}
+
+var directionNames = [4]string{"up", "down", "left", "right"}
+
+// setupRow is one line of the match setup screen.
+type setupRow struct {
+	Label string
+	// Called with -1 or 1 when left or right is pressed, if set
+	Change func(delta int)
+	// Called when Enter is pressed, if set
+	Action func()
+}
+
+// MatchSetup lets the players change the match settings with the
+// keyboard: up and down pick a line, left and right change it, and Enter
+// sets a key.
+type MatchSetup struct {
+	Settings *core.MatchSettings
+	Cursor   int
+	// First line shown, when there are more than fit on the screen
+	Top int
+	// If set, the next key pressed is stored here
+	KeyTarget *string
+}
+
+func NewMatchSetup(settings *core.MatchSettings) *MatchSetup {
+	return &MatchSetup{Settings: settings}
+}
+
+// WaitingForKey reports whether the next key pressed is to be bound.
+func (ms *MatchSetup) WaitingForKey() bool {
+	return ms.KeyTarget != nil
+}
+
+// rows lists the lines of the screen, which depend on the settings, such
+// as one arena line for each round.
+func (ms *MatchSetup) rows() []setupRow {
+	s := ms.Settings
+	rows := []setupRow{
+		{
+			Label: fmt.Sprintf("Rounds: %d", s.Rounds),
+			Change: func(delta int) {
+				s.Rounds = min(max(s.Rounds+delta, core.MinRounds), core.MaxRounds)
+			},
+		},
+		{
+			Label: fmt.Sprintf("Tick speed: %d ms", s.TickMillis),
+			Change: func(delta int) {
+				s.TickMillis = min(max(s.TickMillis+delta*SetupTickStepMillis, core.MinTickMillis), core.MaxTickMillis)
+			},
+		},
+	}
+
+	arenas := []string{core.ArenaInTurn, core.ArenaRandom}
+	for _, m := range core.Maps() {
+		arenas = append(arenas, m.Name)
+	}
+	for round := range s.Rounds {
+		rows = append(rows, setupRow{
+			Label: fmt.Sprintf("Round %d arena: %s", round+1, arenaLabel(s.Arenas[round])),
+			Change: func(delta int) {
+				s.Arenas[round] = cycleOption(arenas, s.Arenas[round], delta)
+			},
+		})
+	}
+
+	controllers := append([]string{""}, core.ControllerNames()...)
+	for i := range Characters {
+		char := &Characters[i]
+		if char.ControllerType != ComputerPlayer {
+			continue
+		}
+		if char.FromCommandLine {
+			rows = append(rows, setupRow{Label: fmt.Sprintf("%s: set on the command line", char.Name)})
+			continue
+		}
+		name := char.Name
+		label := s.Controllers[name]
+		if label == "" {
+			label = "Own"
+		}
+		rows = append(rows, setupRow{
+			Label: fmt.Sprintf("%s: %s", name, label),
+			Change: func(delta int) {
+				s.Controllers[name] = cycleOption(controllers, s.Controllers[name], delta)
+				if s.Controllers[name] == "" {
+					delete(s.Controllers, name)
+				}
+			},
+		})
+	}
+
+	for player := range s.Keys {
+		for i, key := range s.Keys[player].Keys() {
+			rows = append(rows, setupRow{
+				Label:  fmt.Sprintf("Player %d %s: %s", player+1, directionNames[i], *key),
+				Action: func() { ms.KeyTarget = key },
+			})
+		}
+	}
+
+	rows = append(rows, setupRow{
+		Label: "Restore the defaults",
+		Action: func() {
+			*s = core.DefaultMatchSettings()
+		},
+	})
+	return rows
+}
+
+func arenaLabel(arena string) string {
+	switch arena {
+	case core.ArenaInTurn:
+		return "In turn"
+	case core.ArenaRandom:
+		return "Random"
+	}
+	return arena
+}
+
+// cycleOption returns the option delta places after current, going round
+// from the end to the start.
+func cycleOption(options []string, current string, delta int) string {
+	i := 0
+	for j, option := range options {
+		if option == current {
+			i = j
+		}
+	}
+	n := len(options)
+	return options[((i+delta)%n+n)%n]
+}
+
+func (ms *MatchSetup) Update() {
+	if ms.KeyTarget != nil {
+		keys := inpututil.AppendJustPressedKeys(nil)
+		if len(keys) == 0 {
+			return
+		}
+		if keys[0] != ebiten.KeyEscape {
+			*ms.KeyTarget = keys[0].String()
+		}
+		ms.KeyTarget = nil
+		return
+	}
+
+	rows := ms.rows()
+	row := rows[min(ms.Cursor, len(rows)-1)]
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowUp):
+		ms.Cursor--
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowDown):
+		ms.Cursor++
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) && row.Change != nil:
+		row.Change(-1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) && row.Change != nil:
+		row.Change(1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) && row.Action != nil:
+		row.Action()
+	}
+
+	// The number of lines changes with the number of rounds
+	numRows := len(ms.rows())
+	ms.Cursor = min(max(ms.Cursor, 0), numRows-1)
+	ms.Top = min(max(ms.Top, ms.Cursor-SetupVisibleRows+1), ms.Cursor)
+	ms.Top = max(min(ms.Top, numRows-SetupVisibleRows), 0)
+}
+
+func (ms *MatchSetup) Draw(screen *ebiten.Image) {
+	drawShadowedTextAt(screen, "Match setup", ScreenWidth/2, 5, text.AlignCenter, color.White)
+	rows := ms.rows()
+	for i := ms.Top; i < min(ms.Top+SetupVisibleRows, len(rows)); i++ {
+		row := rows[i]
+		y := float64(SetupStartY + (i-ms.Top)*SetupRowHeight)
+		c := color.Color(color.White)
+		if row.Change == nil && row.Action == nil {
+			c = SetupFixedColor
+		}
+		if i == ms.Cursor {
+			c = SetupSelectedColor
+			drawShadowedTextAt(screen, ">", SetupStartX-12, y, text.AlignStart, c)
+		}
+		drawShadowedTextAt(screen, row.Label, SetupStartX, y, text.AlignStart, c)
+	}
+
+	help := "Arrows: change  Enter: set  Space: play"
+	if ms.KeyTarget != nil {
+		help = "Press the new key, or Esc to cancel"
+	}
+	drawShadowedTextAt(screen, help, ScreenWidth/2, 215, text.AlignCenter, color.White)
+}
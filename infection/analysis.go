@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Analyzer searches a position in the background so the UI can show how
+// the game is going and suggest moves.  Results are reported as each depth
+// finishes, so they improve the longer the position stays on the board.
+type Analyzer struct {
+	// stop analysing a position after this long
+	timeLimit time.Duration
+
+	mu        sync.Mutex
+	board     *Board
+	result    SearchResult
+	hasResult bool
+	cancel    context.CancelFunc
+	// incremented each time the position changes, so a search that was
+	// stopped can't report results for the old position
+	generation int
+}
+
+func NewAnalyzer(timeLimit time.Duration) *Analyzer {
+	return &Analyzer{timeLimit: timeLimit}
+}
+
+// Analyze starts searching the given position, unless it is already the
+// one being analysed.  The analyzer keeps its own copy of the board.
+func (a *Analyzer) Analyze(board *Board) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.board != nil && *a.board == *board {
+		return
+	}
+	a.stopLocked()
+	a.board = board.Copy()
+	if _, over := board.IsGameOver(); over {
+		a.result = SearchResult{score: evaluate(board)}
+		a.hasResult = true
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeLimit)
+	a.cancel = cancel
+	generation := a.generation
+	snapshot := board.Copy()
+	go func() {
+		defer cancel()
+		IterativeSearchWithInfo(ctx, snapshot, func(r SearchResult) {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			if a.generation == generation {
+				a.result = r
+				a.hasResult = true
+			}
+		})
+	}()
+}
+
+// Stop abandons the current analysis, for example to leave the CPU free
+// for an engine that is choosing a move.
+func (a *Analyzer) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stopLocked()
+	a.board = nil
+}
+
+func (a *Analyzer) stopLocked() {
+	if a.cancel != nil {
+		a.cancel()
+		a.cancel = nil
+	}
+	a.generation++
+	a.hasResult = false
+}
+
+// Result returns the latest result for the given position.  It returns
+// false if that position isn't being analysed or no depth has finished yet.
+func (a *Analyzer) Result(board *Board) (SearchResult, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.board == nil || *a.board != *board || !a.hasResult {
+		return SearchResult{}, false
+	}
+	return a.result, true
+}
+
+// WhiteScore converts a score for the player to move into one for White,
+// which is how the analysis is shown.
+func WhiteScore(score int, playerToMove Player) int {
+	if playerToMove == Black {
+		return -score
+	}
+	return score
+}
+
+// FormatScore writes a score from White's point of view as a piece
+// difference such as "+3", or as a forced win for one side.
+func FormatScore(whiteScore int) string {
+	switch {
+	case whiteScore >= WinScore:
+		return "White wins"
+	case whiteScore <= -WinScore:
+		return "Black wins"
+	}
+	return fmt.Sprintf("%+d", whiteScore)
+}
+
+// FormatLine writes a sequence of moves played from board.
+func FormatLine(board *Board, moves []Move) string {
+	b := board.Copy()
+	parts := []string{}
+	for _, m := range moves {
+		parts = append(parts, b.FormatMove(m))
+		b.Move(m)
+	}
+	return strings.Join(parts, " ")
+}
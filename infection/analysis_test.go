@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnalyzerFindsCapture(t *testing.T) {
+	board, err := NewBoardFromText(`
+		o . . . . . x
+		. o . . . . .
+		. . x x x . .
+		. . x . x . .
+		. . x x x . .
+		. . . . . . .
+		x . . . . . o`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	a := NewAnalyzer(time.Second)
+	a.Analyze(board)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		result, ok := a.Result(board)
+		if ok && result.depth >= 2 {
+			if got := board.FormatMove(result.bestMove); got != "b6d4" {
+				t.Errorf("want best move b6d4, got %s", got)
+			}
+			if len(result.pv) == 0 || result.pv[0] != result.bestMove {
+				t.Errorf("line %v should start with the best move", result.pv)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no analysis result")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	other := NewBoard(StandardGeometry)
+	if _, ok := a.Result(other); ok {
+		t.Errorf("expected no result for a position that isn't being analysed")
+	}
+	a.Stop()
+	if _, ok := a.Result(board); ok {
+		t.Errorf("expected no result after Stop")
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	tests := []struct {
+		score int
+		want  string
+	}{
+		{0, "+0"},
+		{3, "+3"},
+		{-5, "-5"},
+		{WinScore + 4, "White wins"},
+		{-WinScore - 4, "Black wins"},
+	}
+	for _, tc := range tests {
+		if got := FormatScore(tc.score); got != tc.want {
+			t.Errorf("FormatScore(%d): want %q, got %q", tc.score, tc.want, got)
+		}
+	}
+	if got := WhiteScore(3, Black); got != -3 {
+		t.Errorf("WhiteScore(3, Black): want -3, got %d", got)
+	}
+}
+
+func TestFormatLine(t *testing.T) {
+	board, _ := NewBoardFromFEN(StartFEN)
+	moves := []Move{}
+	b := board.Copy()
+	for _, s := range []string{"f1", "g7e7", "b6"} {
+		m, err := b.ParseMove(s)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", s, err)
+		}
+		moves = append(moves, m)
+		b.Move(m)
+	}
+	if got := FormatLine(board, moves); got != "f1 g7e7 b6" {
+		t.Errorf("want %q, got %q", "f1 g7e7 b6", got)
+	}
+}
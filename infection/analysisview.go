@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	avFontSize = 16
+	avBarY     = 52
+	avBarH     = 14
+	// longest line of moves shown
+	avMaxPVMoves = 8
+	// a score this many pieces ahead fills about three quarters of the bar
+	avBarScale = 10
+)
+
+// AnalysisView shows the Analyzer's opinion of the current position: the
+// score in text and as a bar, the expected line of play, and the number
+// of positions searched.
+type AnalysisView struct {
+	X          int
+	Y          int
+	width      int
+	whiteColor color.Color
+	blackColor color.Color
+}
+
+func NewAnalysisView(x, y, width int, whiteColor, blackColor color.Color) *AnalysisView {
+	return &AnalysisView{
+		X:          x,
+		Y:          y,
+		width:      width,
+		whiteColor: whiteColor,
+		blackColor: blackColor,
+	}
+}
+
+// evalBarFraction is how much of the bar is given to White.
+func evalBarFraction(whiteScore int) float64 {
+	switch {
+	case whiteScore >= WinScore:
+		return 1
+	case whiteScore <= -WinScore:
+		return 0
+	}
+	return 0.5 + 0.5*math.Tanh(float64(whiteScore)/avBarScale)
+}
+
+func (a *AnalysisView) drawText(screen *ebiten.Image, s string, x, y int) {
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(float64(x), float64(y))
+	op.ColorScale.ScaleWithColor(color.White)
+	text.Draw(screen, s, &text.GoTextFace{
+		Source: DisplayFont,
+		Size:   avFontSize,
+	}, op)
+}
+
+func (a *AnalysisView) Draw(screen *ebiten.Image, board *Board, result SearchResult, ok bool) {
+	fraction := 0.5
+	if !ok {
+		a.drawText(screen, "Analysing...", a.X, a.Y)
+	} else {
+		whiteScore := WhiteScore(result.score, board.playerToMove)
+		fraction = evalBarFraction(whiteScore)
+		a.drawText(screen, fmt.Sprintf("Eval %s   Depth %d   Nodes %d",
+			FormatScore(whiteScore), result.depth, result.numEvals), a.X, a.Y)
+		pv := result.pv[:min(len(result.pv), avMaxPVMoves)]
+		a.drawText(screen, "Line: "+FormatLine(board, pv), a.X, a.Y+avFontSize+4)
+	}
+
+	whiteWidth := float32(fraction * float64(a.width))
+	vector.DrawFilledRect(screen, float32(a.X), float32(a.Y+avBarY), whiteWidth, avBarH, a.whiteColor, false)
+	vector.DrawFilledRect(screen, float32(a.X)+whiteWidth, float32(a.Y+avBarY),
+		float32(a.width)-whiteWidth, avBarH, a.blackColor, false)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"math"
+	"math/bits"
 	"regexp"
 )
 
@@ -19,74 +21,158 @@ func (p Player) Other() Player {
 	return White
 }
 
-// BoardSize must be <= 8
-const BoardSize = 7
-const NumSquares = BoardSize * BoardSize
+// MaxBoardSize is the largest board whose squares fit in a BitBoard.
+const MaxBoardSize = 8
+const MaxSquares = MaxBoardSize * MaxBoardSize
+
+// DefaultBoardSize is the size of the standard Ataxx board.
+const DefaultBoardSize = 7
 
 type Board struct {
+	geom         *Geometry
 	white        BitBoard
 	black        BitBoard
 	empty        BitBoard
 	playerToMove Player
+
+	// number of moves since the last capture
+	halfmoveClock int
+	// starts at 1 and is incremented after each white move
+	fullmoveNumber int
+}
+
+// Geometry describes the shape of a board: its width and height, and
+// which squares can be played on.  Geometries are never modified after
+// creation, so they are shared between boards and copying a board is cheap.
+type Geometry struct {
+	size       int
+	numSquares int
+	onGame     BitBoard
+
+	// used for making moves (and generating moves)
+	adjacent [MaxSquares]BitBoard
+	jump     [MaxSquares]BitBoard
 }
 
-// used for making moves (and generating moves)
-var adjacentBitboards [NumSquares]BitBoard
-var jumpBitboards [NumSquares]BitBoard
-var onGameBitboard BitBoard
+// StandardGeometry is the 7x7 board with no blocked squares.
+var StandardGeometry = NewGeometry(DefaultBoardSize, BitBoard(0))
+
+// NewGeometry creates a size x size board, where squares set in blocked
+// cannot be played on.
+func NewGeometry(size int, blocked BitBoard) *Geometry {
+	if size < 2 || size > MaxBoardSize {
+		panic(fmt.Sprintf("board size %d not in range [2, %d]", size, MaxBoardSize))
+	}
+	g := Geometry{
+		size:       size,
+		numSquares: size * size,
+	}
+	for idx := range g.numSquares {
+		g.onGame = g.onGame.Set(SquareIndex(idx))
+	}
+	g.onGame &^= blocked
+	g.adjacent = g.genAdjacentBitboards()
+	g.jump = g.genJumpBitboards()
+	return &g
+}
 
-func init() {
-	adjacentBitboards = genAdjacentBitboards()
-	jumpBitboards = genJumpBitboards()
-	onGameBitboard = genOnGameBitboard()
+func (g *Geometry) Size() int {
+	return g.size
 }
 
-func GetIndex(row, col int) SquareIndex {
-	return SquareIndex(row*BoardSize + col)
+func (g *Geometry) NumSquares() int {
+	return g.numSquares
 }
 
-func IndexToRowCol(index SquareIndex) (row int, col int) {
-	return int(index) / BoardSize, int(index) % BoardSize
+func (g *Geometry) GetIndex(row, col int) SquareIndex {
+	return SquareIndex(row*g.size + col)
+}
+
+func (g *Geometry) IndexToRowCol(index SquareIndex) (row int, col int) {
+	return int(index) / g.size, int(index) % g.size
+}
+
+// IsOnGame returns true if the square is on the board and not blocked.
+func (g *Geometry) IsOnGame(index SquareIndex) bool {
+	return index >= 0 && int(index) < g.numSquares && g.onGame.Get(index)
+}
+
+func (b *Board) Geometry() *Geometry {
+	return b.geom
+}
+
+func (b *Board) Size() int {
+	return b.geom.size
+}
+
+func (b *Board) GetIndex(row, col int) SquareIndex {
+	return b.geom.GetIndex(row, col)
+}
+
+func (b *Board) IndexToRowCol(index SquareIndex) (row int, col int) {
+	return b.geom.IndexToRowCol(index)
 }
 
 func (b *Board) syncEmpty() {
-	b.empty = ^(b.white | b.black)
+	b.empty = b.geom.onGame &^ (b.white | b.black)
+}
+
+// startingSquare finds the square nearest to the given corner that is
+// not blocked, walking along the diagonal towards the center.
+func (g *Geometry) startingSquare(row, col int) SquareIndex {
+	dr, dc := 1, 1
+	if row > 0 {
+		dr = -1
+	}
+	if col > 0 {
+		dc = -1
+	}
+	for i := 0; i < g.size/2; i++ {
+		idx := g.GetIndex(row+i*dr, col+i*dc)
+		if g.onGame.Get(idx) {
+			return idx
+		}
+	}
+	return g.GetIndex(row, col)
 }
 
-func NewBoard() *Board {
+func NewBoard(geom *Geometry) *Board {
 	b := Board{
+		geom:         geom,
 		white:        BitBoard(0),
 		black:        BitBoard(0),
 		empty:        BitBoard(0),
 		playerToMove: White,
+
+		halfmoveClock:  0,
+		fullmoveNumber: 1,
 	}
 	// Set white/black pieces in opposite corners
-	b.white = b.white.Set(GetIndex(0, 0))
-	b.white = b.white.Set(GetIndex(BoardSize-1, BoardSize-1))
-	b.black = b.black.Set(GetIndex(0, BoardSize-1))
-	b.black = b.black.Set(GetIndex(BoardSize-1, 0))
+	last := geom.size - 1
+	b.white = b.white.Set(geom.startingSquare(0, 0))
+	b.white = b.white.Set(geom.startingSquare(last, last))
+	b.black = b.black.Set(geom.startingSquare(0, last))
+	b.black = b.black.Set(geom.startingSquare(last, 0))
 	b.syncEmpty()
 	return &b
 }
 
 func (b *Board) Copy() *Board {
-	copy := NewBoard()
-	copy.white = b.white
-	copy.black = b.black
-	copy.empty = b.empty
-	copy.playerToMove = b.playerToMove
-	return copy
+	copy := *b
+	return &copy
 }
 
 func (b *Board) String() string {
 	result := ""
-	for r := range BoardSize {
-		for c := range BoardSize {
-			idx := GetIndex(r, c)
+	for r := range b.Size() {
+		for c := range b.Size() {
+			idx := b.GetIndex(r, c)
 			if b.white.Get(idx) {
 				result += "o "
 			} else if b.black.Get(idx) {
 				result += "x "
+			} else if !b.geom.onGame.Get(idx) {
+				result += "# "
 			} else {
 				result += ". "
 			}
@@ -102,36 +188,28 @@ type Offset struct {
 	dy int
 }
 
-// location index to bitboard of adjacent squares
-func genOffsetBitboards(offsets []Offset) [NumSquares]BitBoard {
-	bitboards := [NumSquares]BitBoard{}
+// location index to bitboard of on-game squares at the given offsets
+func (g *Geometry) genOffsetBitboards(offsets []Offset) [MaxSquares]BitBoard {
+	bitboards := [MaxSquares]BitBoard{}
 
-	for idx := range NumSquares {
-		row, col := IndexToRowCol(SquareIndex(idx))
+	for idx := range g.numSquares {
+		row, col := g.IndexToRowCol(SquareIndex(idx))
 
 		bb := BitBoard(0)
 		for _, offset := range offsets {
 			new_row := row + offset.dy
 			new_col := col + offset.dx
-			if (new_row >= 0 && new_row < BoardSize) && (new_col >= 0 && new_col < BoardSize) {
-				new_idx := GetIndex(new_row, new_col)
+			if (new_row >= 0 && new_row < g.size) && (new_col >= 0 && new_col < g.size) {
+				new_idx := g.GetIndex(new_row, new_col)
 				bb = bb.Set(new_idx)
 			}
 		}
-		bitboards[idx] = bb
+		bitboards[idx] = bb & g.onGame
 	}
 	return bitboards
 }
 
-func genOnGameBitboard() BitBoard {
-	bb := BitBoard(0)
-	for idx := range NumSquares {
-		bb = bb.Set(SquareIndex(idx))
-	}
-	return bb
-}
-
-func genAdjacentBitboards() [NumSquares]BitBoard {
+func (g *Geometry) genAdjacentBitboards() [MaxSquares]BitBoard {
 	offsets := []Offset{
 		{-1, -1},
 		{-1, 0},
@@ -142,10 +220,10 @@ func genAdjacentBitboards() [NumSquares]BitBoard {
 		{1, 0},
 		{1, 1},
 	}
-	return genOffsetBitboards(offsets)
+	return g.genOffsetBitboards(offsets)
 }
 
-func genJumpBitboards() [NumSquares]BitBoard {
+func (g *Geometry) genJumpBitboards() [MaxSquares]BitBoard {
 	offsets := []Offset{
 		{-2, -2},
 		{-2, -1},
@@ -164,22 +242,27 @@ func genJumpBitboards() [NumSquares]BitBoard {
 		{2, 1},
 		{2, 2},
 	}
-	return genOffsetBitboards(offsets)
+	return g.genOffsetBitboards(offsets)
 }
 
 func (b *Board) Move(m Move) {
+	if b.playerToMove == White {
+		b.fullmoveNumber++
+	}
 	if m.pass {
+		b.halfmoveClock++
 		b.playerToMove = b.playerToMove.Other()
 		return
 	}
 
+	var infectedSquares BitBoard
 	if b.playerToMove == White {
 		b.white = b.white.Set(m.to)
 		if m.jump {
 			b.white = b.white.Clear(m.from)
 		}
 		// change adjacent black squares to white
-		infectedSquares := b.black & adjacentBitboards[m.to]
+		infectedSquares = b.black & b.geom.adjacent[m.to]
 		b.black &^= infectedSquares
 		b.white |= infectedSquares
 	} else {
@@ -188,11 +271,16 @@ func (b *Board) Move(m Move) {
 			b.black = b.black.Clear(m.from)
 		}
 		// change adjacent white squares to black
-		infectedSquares := b.white & adjacentBitboards[m.to]
+		infectedSquares = b.white & b.geom.adjacent[m.to]
 		b.white &^= infectedSquares
 		b.black |= infectedSquares
 	}
 
+	if infectedSquares != 0 {
+		b.halfmoveClock = 0
+	} else {
+		b.halfmoveClock++
+	}
 	b.syncEmpty()
 	b.playerToMove = b.playerToMove.Other()
 }
@@ -203,37 +291,187 @@ func (b *Board) Score() (whiteScore, blackScore int) {
 	return
 }
 
-func (b *Board) IsGameOver() bool {
-	// A game is over if one player has no pieces left
-	if b.white.GetNumSetBits() == 0 || b.black.GetNumSetBits() == 0 {
-		return true
+// EndReason says why a game finished.
+type EndReason int
+
+const (
+	NotOver EndReason = iota
+	// one player has no pieces left
+	NoPiecesLeft
+	// there are no empty squares left
+	BoardFull
+	// a player has no legal moves; the other player gets the empty squares
+	NoMovesLeft
+	// 100 moves in a row without a capture
+	FiftyMoveRule
+	// the same position came up a third time
+	Repetition
+)
+
+func (r EndReason) String() string {
+	switch r {
+	case NoPiecesLeft:
+		return "no pieces left"
+	case BoardFull:
+		return "board full"
+	case NoMovesLeft:
+		return "no moves left"
+	case FiftyMoveRule:
+		return "50 moves without a capture"
+	case Repetition:
+		return "threefold repetition"
+	}
+	return "not over"
+}
+
+// fiftyMoveLimit is the halfmove clock value at which the game is drawn.
+const fiftyMoveLimit = 100
+
+// Outcome is the result of a finished game.
+type Outcome struct {
+	reason EndReason
+	// final piece counts, including any empty squares given to the player
+	// who could still move
+	whiteScore int
+	blackScore int
+}
+
+// IsDraw is true if the game was drawn by rule or the piece counts are equal.
+func (o Outcome) IsDraw() bool {
+	return o.reason == FiftyMoveRule || o.reason == Repetition || o.whiteScore == o.blackScore
+}
+
+// PieceDiff is white's score minus black's, or 0 for a draw.
+func (o Outcome) PieceDiff() int {
+	if o.IsDraw() {
+		return 0
 	}
+	return o.whiteScore - o.blackScore
+}
 
-	// or if there are no spaces left to move
-	if b.empty&onGameBitboard == 0 {
-		return true
+// Result writes the outcome as "1-0", "0-1" or "1/2-1/2".
+func (o Outcome) Result() string {
+	switch {
+	case o.IsDraw():
+		return "1/2-1/2"
+	case o.whiteScore > o.blackScore:
+		return "1-0"
 	}
+	return "0-1"
+}
 
+// Headline gives the winner and final score, as in "White wins 30-19".
+func (o Outcome) Headline() string {
+	winner := "Black wins"
+	if o.IsDraw() {
+		winner = "Draw"
+	} else if o.whiteScore > o.blackScore {
+		winner = "White wins"
+	}
+	return fmt.Sprintf("%s %d-%d", winner, o.whiteScore, o.blackScore)
+}
+
+func (o Outcome) String() string {
+	return fmt.Sprintf("%s (%v)", o.Headline(), o.reason)
+}
+
+// canMove is true if any of the player's pieces can step or jump to an
+// empty square.
+func (b *Board) canMove(pieces BitBoard) bool {
+	for pieces != 0 {
+		idx := SquareIndex(bits.TrailingZeros64(uint64(pieces)))
+		if (b.geom.adjacent[idx]|b.geom.jump[idx])&b.empty != 0 {
+			return true
+		}
+		pieces = pieces.Clear(idx)
+	}
 	return false
 }
 
+// IsGameOver reports whether the game has finished, and if so how.  We
+// play the Ataxx rule that the game ends as soon as either player is
+// stuck, with the empty squares going to the other one, even though an
+// opponent's jump could later free the stuck player.  Repetition depends
+// on the moves played before, so it is checked by GameRecord instead.
+func (b *Board) IsGameOver() (Outcome, bool) {
+	w, k := b.Score()
+	outcome := Outcome{reason: NotOver, whiteScore: w, blackScore: k}
+	whiteCanMove := b.canMove(b.white)
+	blackCanMove := b.canMove(b.black)
+	switch {
+	case w == 0 || k == 0:
+		outcome.reason = NoPiecesLeft
+	case b.empty == 0:
+		outcome.reason = BoardFull
+	case !whiteCanMove || !blackCanMove:
+		outcome.reason = NoMovesLeft
+		empty := b.empty.GetNumSetBits()
+		if whiteCanMove {
+			outcome.whiteScore += empty
+		} else if blackCanMove {
+			outcome.blackScore += empty
+		}
+	case b.halfmoveClock >= fiftyMoveLimit:
+		outcome.reason = FiftyMoveRule
+	default:
+		return outcome, false
+	}
+	return outcome, true
+}
+
+// positionCounts counts how often each position has come up in a game,
+// for the threefold repetition rule.
+type positionCounts map[uint64]int
+
+// add records the board's position, returning true if it has now come up
+// three times.
+func (p positionCounts) add(b *Board) bool {
+	key := b.Hash()
+	p[key]++
+	return p[key] >= 3
+}
+
+// geometryFor returns a geometry with the given size and blocked squares,
+// sharing StandardGeometry when possible.
+func geometryFor(size int, blocked BitBoard) *Geometry {
+	if size == DefaultBoardSize && blocked == 0 {
+		return StandardGeometry
+	}
+	return NewGeometry(size, blocked)
+}
+
+// NewBoardFromText parses a square grid of 'o' (white), 'x' (black),
+// '.' (empty) and '#' (blocked) characters.  The board size is taken
+// from the number of squares.  White is to move; use NewBoardFromFEN to
+// read a position with black to move.
 func NewBoardFromText(text string) (*Board, error) {
-	reg := regexp.MustCompile(`[^xo.]+`)
+	reg := regexp.MustCompile(`[^xo.#]+`)
 	importantChars := reg.ReplaceAllString(text, "")
-	if len(importantChars) != NumSquares {
+	size := int(math.Sqrt(float64(len(importantChars))))
+	if size*size != len(importantChars) || size < 2 || size > MaxBoardSize {
 		return nil, fmt.Errorf("string representation has wrong number of characters")
 	}
 
-	b := Board{}
+	blocked := BitBoard(0)
+	for i, char := range importantChars {
+		if char == '#' {
+			blocked = blocked.Set(SquareIndex(i))
+		}
+	}
+
+	b := Board{
+		geom:           geometryFor(size, blocked),
+		playerToMove:   White,
+		fullmoveNumber: 1,
+	}
 	for i, char := range importantChars {
 		switch char {
 		case 'o':
 			b.white = b.white.Set(SquareIndex(i))
 		case 'x':
 			b.black = b.black.Set(SquareIndex(i))
-		default:
-			b.empty = b.empty.Set(SquareIndex(i))
 		}
 	}
+	b.syncEmpty()
 	return &b, nil
 }
@@ -6,28 +6,30 @@ import (
 )
 
 func TestNewBoard(t *testing.T) {
-	board := NewBoard()
+	board := NewBoard(StandardGeometry)
+	last := board.Size() - 1
 
 	// Check initial white pieces
-	if !board.white.Get(GetIndex(0, 0)) {
+	if !board.white.Get(board.GetIndex(0, 0)) {
 		t.Errorf("Expected white piece at (0,0)")
 	}
-	if !board.white.Get(GetIndex(BoardSize-1, BoardSize-1)) {
-		t.Errorf("Expected white piece at (%d,%d)", BoardSize-1, BoardSize-1)
+	if !board.white.Get(board.GetIndex(last, last)) {
+		t.Errorf("Expected white piece at (%d,%d)", last, last)
 	}
 
 	// Check initial black pieces
-	if !board.black.Get(GetIndex(0, BoardSize-1)) {
-		t.Errorf("Expected black piece at (0,%d)", BoardSize-1)
+	if !board.black.Get(board.GetIndex(0, last)) {
+		t.Errorf("Expected black piece at (0,%d)", last)
 	}
-	if !board.black.Get(GetIndex(BoardSize-1, 0)) {
-		t.Errorf("Expected black piece at (%d,0)", BoardSize-1)
+	if !board.black.Get(board.GetIndex(last, 0)) {
+		t.Errorf("Expected black piece at (%d,0)", last)
 	}
 
 	// Check empty squares
-	expectedEmptyCount := NumSquares - 4
+	numSquares := board.Geometry().NumSquares()
+	expectedEmptyCount := numSquares - 4
 	actualEmptyCount := 0
-	for i := 0; i < NumSquares; i++ {
+	for i := 0; i < numSquares; i++ {
 		if board.empty.Get(SquareIndex(i)) {
 			actualEmptyCount++
 		}
@@ -37,6 +39,121 @@ func TestNewBoard(t *testing.T) {
 	}
 }
 
+func TestNewBoardGeometries(t *testing.T) {
+	type TestCase struct {
+		name   string
+		geom   *Geometry
+		expect string
+	}
+
+	testCases := []TestCase{
+		{
+			name: "5x5",
+			geom: NewGeometry(5, BitBoard(0)),
+			expect: `
+				o . . . x
+				. . . . .
+				. . . . .
+				. . . . .
+				x . . . o`,
+		},
+		{
+			name: "8x8",
+			geom: NewGeometry(8, BitBoard(0)),
+			expect: `
+				o . . . . . . x
+				. . . . . . . .
+				. . . . . . . .
+				. . . . . . . .
+				. . . . . . . .
+				. . . . . . . .
+				. . . . . . . .
+				x . . . . . . o`,
+		},
+		{
+			name: "cross",
+			geom: CrossGeometry(7),
+			expect: `
+				o . . . . . x
+				. . . . . . .
+				. . . # . . .
+				. . # # # . .
+				. . . # . . .
+				. . . . . . .
+				x . . . . . o`,
+		},
+		{
+			name: "corners blocked",
+			geom: CornersBlockedGeometry(7),
+			expect: `
+				# . . . . . #
+				. o . . . x .
+				. . . . . . .
+				. . . . . . .
+				. . . . . . .
+				. x . . . o .
+				# . . . . . #`,
+		},
+	}
+
+	for _, tc := range testCases {
+		got := NewBoard(tc.geom)
+		want, err := NewBoardFromText(tc.expect)
+		if err != nil {
+			t.Fatalf("%s: Failed to create board from text: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: want\n%s, got\n%s", tc.name, want.String(), got.String())
+		}
+	}
+}
+
+func TestBlockedSquaresAreNotPlayable(t *testing.T) {
+	board, err := NewBoardFromText(`
+		o # . . . . x
+		# # . . . . .
+		. . . . . . .
+		. . . . . . .
+		. . . . . . .
+		. . . . . . .
+		x . . . . . o`)
+	if err != nil {
+		t.Fatalf("Failed to create board from text: %v", err)
+	}
+	blocked := []SquareIndex{board.GetIndex(0, 1), board.GetIndex(1, 0), board.GetIndex(1, 1)}
+	for _, move := range board.GetLegalMoves() {
+		for _, idx := range blocked {
+			if move.to == idx {
+				t.Errorf("move %v lands on blocked square", move)
+			}
+		}
+	}
+	if _, err := board.CreateMove(board.GetIndex(0, 0), board.GetIndex(1, 1)); err == nil {
+		t.Errorf("expected error creating move to blocked square")
+	}
+	if !board.empty.Get(board.GetIndex(2, 2)) || board.empty.Get(board.GetIndex(1, 1)) {
+		t.Errorf("blocked squares should not be empty")
+	}
+}
+
+func TestRandomHolesGeometryIsSymmetric(t *testing.T) {
+	for range 20 {
+		g := RandomHolesGeometry(7, 2)
+		for r := range g.Size() {
+			for c := range g.Size() {
+				mirrorR := g.Size() - 1 - r
+				mirrorC := g.Size() - 1 - c
+				if g.IsOnGame(g.GetIndex(r, c)) != g.IsOnGame(g.GetIndex(mirrorR, mirrorC)) {
+					t.Fatalf("geometry not symmetric at (%d,%d):\n%s", r, c, NewBoard(g).String())
+				}
+			}
+		}
+		if !g.IsOnGame(g.GetIndex(0, 0)) || !g.IsOnGame(g.GetIndex(1, 1)) {
+			t.Fatalf("starting squares blocked:\n%s", NewBoard(g).String())
+		}
+	}
+}
+
 func TestBoardMove(t *testing.T) {
 	type TestCase struct {
 		name       string
@@ -57,8 +174,8 @@ func TestBoardMove(t *testing.T) {
 				. . . . . . . 
 				x . . . . . o`,
 			move: Move{
-				from: GetIndex(0, 0),
-				to:   GetIndex(1, 1),
+				from: StandardGeometry.GetIndex(0, 0),
+				to:   StandardGeometry.GetIndex(1, 1),
 				jump: false,
 			},
 			expect: `
@@ -81,8 +198,8 @@ func TestBoardMove(t *testing.T) {
 				. . . . . . . 
 				x . . . . . o`,
 			move: Move{
-				from: GetIndex(0, 6),
-				to:   GetIndex(1, 5),
+				from: StandardGeometry.GetIndex(0, 6),
+				to:   StandardGeometry.GetIndex(1, 5),
 				jump: false,
 			},
 			expect: `
@@ -105,8 +222,8 @@ func TestBoardMove(t *testing.T) {
 				. . . . . . . 
 				x . . . . . o`,
 			move: Move{
-				from: GetIndex(1, 1),
-				to:   GetIndex(0, 3),
+				from: StandardGeometry.GetIndex(1, 1),
+				to:   StandardGeometry.GetIndex(0, 3),
 				jump: false,
 			},
 			expect: `
@@ -129,8 +246,8 @@ func TestBoardMove(t *testing.T) {
 				. . . . . . . 
 				x . . . . . o`,
 			move: Move{
-				from: GetIndex(0, 5),
-				to:   GetIndex(1, 3),
+				from: StandardGeometry.GetIndex(0, 5),
+				to:   StandardGeometry.GetIndex(1, 3),
 				jump: false,
 			},
 			expect: `
@@ -224,9 +341,73 @@ func TestGameOver(t *testing.T) {
 			t.Fatalf("%s: Failed to create inital board from text: %v", tc.name, err)
 		}
 		want := tc.want
-		got := board.IsGameOver()
+		_, got := board.IsGameOver()
 		if want != got {
 			t.Errorf("%s: want: %v, got %v", tc.name, want, got)
 		}
 	}
 }
+
+func TestGameOutcome(t *testing.T) {
+	type TestCase struct {
+		name       string
+		fen        string
+		wantReason EndReason
+		wantWhite  int
+		wantBlack  int
+		wantResult string
+	}
+
+	testCases := []TestCase{
+		{
+			name:       "in progress",
+			fen:        StartFEN,
+			wantReason: NotOver,
+		},
+		{
+			name:       "wiped out",
+			fen:        "o6/7/7/7/7/7/7 x 10 20",
+			wantReason: NoPiecesLeft,
+			wantWhite:  1,
+			wantBlack:  0,
+			wantResult: "1-0",
+		},
+		{
+			// black is walled in, so white gets the 16 empty squares
+			name:       "black stuck",
+			fen:        "xoo2/ooo2/ooo2/5/5 x 0 1",
+			wantReason: NoMovesLeft,
+			wantWhite:  24,
+			wantBlack:  1,
+			wantResult: "1-0",
+		},
+		{
+			name:       "fifty moves",
+			fen:        "x5o/7/7/7/7/7/o5x o 100 60",
+			wantReason: FiftyMoveRule,
+			wantWhite:  2,
+			wantBlack:  2,
+			wantResult: "1/2-1/2",
+		},
+	}
+
+	for _, tc := range testCases {
+		board, err := NewBoardFromFEN(tc.fen)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		}
+		outcome, over := board.IsGameOver()
+		if over != (tc.wantReason != NotOver) || outcome.reason != tc.wantReason {
+			t.Errorf("%s: want reason %v, got %v (over=%v)", tc.name, tc.wantReason, outcome.reason, over)
+		}
+		if !over {
+			continue
+		}
+		if outcome.whiteScore != tc.wantWhite || outcome.blackScore != tc.wantBlack {
+			t.Errorf("%s: want score %d-%d, got %d-%d", tc.name, tc.wantWhite, tc.wantBlack, outcome.whiteScore, outcome.blackScore)
+		}
+		if got := outcome.Result(); got != tc.wantResult {
+			t.Errorf("%s: want result %s, got %s", tc.name, tc.wantResult, got)
+		}
+	}
+}
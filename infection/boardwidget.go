@@ -2,11 +2,17 @@ package main
 
 import (
 	"image"
+	"image/color"
+	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
+var BlockedSquareColor = color.RGBA{5, 20, 15, 255}
+var HintColor = color.RGBA{80, 200, 255, 255}
+
 type DragInfo struct {
 	isDragging bool
 	startLoc   SquareIndex
@@ -77,19 +83,21 @@ func (g *BoardWidget) DrawComputerDragInfo(screen *ebiten.Image, gameBoard *Boar
 	if !d.isAnimating {
 		return
 	}
-	gameFromY, gameFromX := IndexToRowCol(d.move.from)
-	gameToY, gameToX := IndexToRowCol(d.move.to)
+	gameFromY, gameFromX := gameBoard.IndexToRowCol(d.move.from)
+	gameToY, gameToX := gameBoard.IndexToRowCol(d.move.to)
 
-	pixelFromX := float64(gameFromX*TileSize + g.bounds.Min.X)
-	pixelFromY := float64(gameFromY*TileSize + g.bounds.Min.Y)
-	pixelToX := float64(gameToX*TileSize + g.bounds.Min.X)
-	pixelToY := float64(gameToY*TileSize + g.bounds.Min.Y)
+	tileSize := g.tileSize(gameBoard)
+	pixelFromX := float64(gameFromX)*tileSize + float64(g.bounds.Min.X)
+	pixelFromY := float64(gameFromY)*tileSize + float64(g.bounds.Min.Y)
+	pixelToX := float64(gameToX)*tileSize + float64(g.bounds.Min.X)
+	pixelToY := float64(gameToY)*tileSize + float64(g.bounds.Min.Y)
 
 	percentDone := float64(d.currFrameCount) / float64(d.totalFrameCount)
 	x := (1-percentDone)*pixelFromX + percentDone*pixelToX
 	y := (1-percentDone)*pixelFromY + percentDone*pixelToY
 
 	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(tileSize/TileSize, tileSize/TileSize)
 	op.GeoM.Translate(x, y)
 	screen.DrawImage(d.image, op)
 }
@@ -99,17 +107,20 @@ type BoardWidget struct {
 	dragInfo         DragInfo
 	computerDragInfo *ComputerDragInfo
 	humanMove        *Move
+	// a move suggested to the human, drawn as outlined squares
+	hint *Move
 }
 
 func NewBoardWidget(x, y int) *BoardWidget {
 	widget := BoardWidget{
 		bounds: image.Rectangle{
 			Min: image.Point{X: x, Y: y},
-			Max: image.Point{X: x + BoardSize*TileSize, Y: y + BoardSize*TileSize},
+			Max: image.Point{X: x + BoardWidgetSize, Y: y + BoardWidgetSize},
 		},
 		dragInfo:         EmptyDragInfo(),
 		computerDragInfo: NewComputerDragInfo(),
 		humanMove:        nil,
+		hint:             nil,
 	}
 	return &widget
 }
@@ -118,21 +129,62 @@ func (g *BoardWidget) DoComputerMove(m Move, playerToMove Player) {
 	g.makeComputerDragInfo(m, playerToMove)
 }
 
+// CancelComputerMove stops animating the computer's move without making it.
+func (g *BoardWidget) CancelComputerMove() {
+	g.computerDragInfo.isAnimating = false
+}
+
+// SetHint highlights the from and to squares of a suggested move.
+func (g *BoardWidget) SetHint(m Move) {
+	g.hint = &m
+}
+
+func (g *BoardWidget) ClearHint() {
+	g.hint = nil
+}
+
+func (g *BoardWidget) drawHint(screen *ebiten.Image, gameBoard *Board) {
+	if g.hint == nil || g.hint.pass {
+		return
+	}
+	tileSize := g.tileSize(gameBoard)
+	for _, idx := range []SquareIndex{g.hint.from, g.hint.to} {
+		r, c := gameBoard.IndexToRowCol(idx)
+		x := float64(c)*tileSize + float64(g.bounds.Min.X)
+		y := float64(r)*tileSize + float64(g.bounds.Min.Y)
+		vector.StrokeRect(screen, float32(x+2), float32(y+2), float32(tileSize-4), float32(tileSize-4), 4, HintColor, false)
+	}
+}
+
+// tileSize is the width of one square in pixels.  The widget always has
+// the same size on screen, so larger boards get smaller squares.
+func (g *BoardWidget) tileSize(gameBoard *Board) float64 {
+	return float64(g.bounds.Dx()) / float64(gameBoard.Size())
+}
+
 func (g *BoardWidget) Draw(screen *ebiten.Image, gameBoard *Board) {
-	for r := 0; r < BoardSize; r++ {
-		for c := 0; c < BoardSize; c++ {
-			x := float64(c*TileSize + g.bounds.Min.X)
-			y := float64(r*TileSize + g.bounds.Min.Y)
+	tileSize := g.tileSize(gameBoard)
+	scale := tileSize / TileSize
+	for r := 0; r < gameBoard.Size(); r++ {
+		for c := 0; c < gameBoard.Size(); c++ {
+			x := float64(c)*tileSize + float64(g.bounds.Min.X)
+			y := float64(r)*tileSize + float64(g.bounds.Min.Y)
+
+			idx := gameBoard.GetIndex(r, c)
+			if !gameBoard.geom.IsOnGame(idx) {
+				vector.DrawFilledRect(screen, float32(x), float32(y), float32(tileSize), float32(tileSize), BlockedSquareColor, false)
+				continue
+			}
 
 			backgroundImge := Empty1Square
 			if (r+c)%2 == 0 {
 				backgroundImge = Empty2Square
 			}
 			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Scale(scale, scale)
 			op.GeoM.Translate(x, y)
 			screen.DrawImage(backgroundImge, op)
 
-			idx := GetIndex(r, c)
 			if gameBoard.white.Get(idx) {
 				screen.DrawImage(WhiteSquare, op)
 			} else if gameBoard.black.Get(idx) {
@@ -141,9 +193,12 @@ func (g *BoardWidget) Draw(screen *ebiten.Image, gameBoard *Board) {
 		}
 	}
 
+	g.drawHint(screen, gameBoard)
+
 	if g.dragInfo.isDragging {
 		op := &ebiten.DrawImageOptions{}
 		x, y := ebiten.CursorPosition()
+		op.GeoM.Scale(scale, scale)
 		op.GeoM.Translate(float64(x-g.dragInfo.offsetX), float64(y-g.dragInfo.offsetY))
 		screen.DrawImage(g.dragInfo.image, op)
 	}
@@ -153,22 +208,25 @@ func (g *BoardWidget) Draw(screen *ebiten.Image, gameBoard *Board) {
 	}
 }
 
-func (g *BoardWidget) pointToIndex(x, y int) SquareIndex {
-	sqX := (x - g.bounds.Min.X) / TileSize
-	sqY := (y - g.bounds.Min.Y) / TileSize
-	if sqX < 0 || sqX >= BoardSize || sqY < 0 || sqY >= BoardSize {
+func (g *BoardWidget) pointToIndex(gameBoard *Board, x, y int) SquareIndex {
+	tileSize := g.tileSize(gameBoard)
+	sqX := int(math.Floor(float64(x-g.bounds.Min.X) / tileSize))
+	sqY := int(math.Floor(float64(y-g.bounds.Min.Y) / tileSize))
+	if sqX < 0 || sqX >= gameBoard.Size() || sqY < 0 || sqY >= gameBoard.Size() {
 		return -1
 	}
-	return GetIndex(sqY, sqX)
+	return gameBoard.GetIndex(sqY, sqX)
 }
 
 func (g *BoardWidget) Update(gameBoard *Board) {
 	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
 		x, y := ebiten.CursorPosition()
-		index := g.pointToIndex(x, y)
+		index := g.pointToIndex(gameBoard, x, y)
 		if index >= 0 {
-			sqX := ((x-g.bounds.Min.X)/TileSize)*TileSize + g.bounds.Min.X
-			sqY := ((y-g.bounds.Min.Y)/TileSize)*TileSize + g.bounds.Min.Y
+			tileSize := g.tileSize(gameBoard)
+			row, col := gameBoard.IndexToRowCol(index)
+			sqX := int(float64(col)*tileSize) + g.bounds.Min.X
+			sqY := int(float64(row)*tileSize) + g.bounds.Min.Y
 			if gameBoard.playerToMove == White && gameBoard.white.Get(index) {
 				g.dragInfo = NewDragInfo(index, x-sqX, y-sqY, WhiteSquare)
 			} else if gameBoard.playerToMove == Black && gameBoard.black.Get(index) {
@@ -182,9 +240,9 @@ func (g *BoardWidget) Update(gameBoard *Board) {
 		x, y := ebiten.CursorPosition()
 		if g.dragInfo.isDragging {
 			startPos := g.dragInfo.startLoc
-			endPos := g.pointToIndex(x, y)
+			endPos := g.pointToIndex(gameBoard, x, y)
 			if endPos != -1 {
-				m, err := CreateMove(startPos, endPos)
+				m, err := gameBoard.CreateMove(startPos, endPos)
 				if err == nil {
 					g.humanMove = &m
 				}
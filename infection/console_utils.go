@@ -9,24 +9,26 @@ import (
 func DrawBoard(b *Board) string {
 	result := ""
 	result += "     "
-	for c := range BoardSize {
+	for c := range b.Size() {
 		result += fmt.Sprintf("%2d", c)
 	}
 	result += "\n"
 	result += "    +"
-	for range BoardSize {
+	for range b.Size() {
 		result += "--"
 	}
 	result += "\n"
-	for r := range BoardSize {
-		rowidx := GetIndex(r, 0)
+	for r := range b.Size() {
+		rowidx := b.GetIndex(r, 0)
 		result += fmt.Sprintf("%3d | ", rowidx)
-		for c := range BoardSize {
-			idx := GetIndex(r, c)
+		for c := range b.Size() {
+			idx := b.GetIndex(r, c)
 			if b.white.Get(idx) {
 				result += "o "
 			} else if b.black.Get(idx) {
 				result += "x "
+			} else if !b.geom.onGame.Get(idx) {
+				result += "# "
 			} else {
 				result += ". "
 			}
@@ -37,13 +39,15 @@ func DrawBoard(b *Board) string {
 	return result
 }
 
-func parseMove(input string) (Move, error) {
+// parseMove accepts either a move in Ataxx notation (e.g., "b2" or "a1a3")
+// or a pair of square indices as shown by DrawBoard (e.g., "0 16").
+func parseMove(b *Board, input string) (Move, error) {
 	var from, to int
-	_, err := fmt.Sscanf(input, "%d %d", from, to)
+	_, err := fmt.Sscanf(input, "%d %d", &from, &to)
 	if err != nil {
-		return Move{}, fmt.Errorf("invalid move format: %v", err)
+		return b.ParseMove(input)
 	}
-	return CreateMove(SquareIndex(from), SquareIndex(to))
+	return b.CreateMove(SquareIndex(from), SquareIndex(to))
 }
 
 func getMoveFromUser(b *Board) Move {
@@ -58,7 +62,7 @@ func getMoveFromUser(b *Board) Move {
 			os.Exit(1)
 		}
 
-		move, err = parseMove(input)
+		move, err = parseMove(b, input)
 		if err != nil {
 			fmt.Println(err)
 			continue
@@ -73,24 +77,3 @@ func getMoveFromUser(b *Board) Move {
 	}
 	return move
 }
-
-// func main() {
-// 	engines := map[Player]Engine{
-// 		White: &Human{},
-// 		Black: &GreedyEngine{},
-// 	}
-// 	b := NewBoard()
-// 	ctr := 0
-// 	for ctr < 49 {
-// 		ctr++
-// 		fmt.Println(DrawBoard(b))
-// 		if b.playerToMove == White {
-// 			fmt.Printf("White (o) move ")
-// 		} else {
-// 			fmt.Printf("Black (x) move ")
-// 		}
-// 		move := engines[b.playerToMove].GenMove(b)
-// 		fmt.Println(move)
-// 		b.Move(move)
-// 	}
-// }
@@ -1,16 +1,59 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"math"
 	"math/rand"
+	"runtime"
 	"time"
 )
 
+// An Engine chooses moves.  GenMove may be called from a goroutine other
+// than the one that owns the board, so the board passed in must be a copy
+// that won't change during the call.  Engines should return promptly once
+// ctx is cancelled; the move returned then may be ignored.
 type Engine interface {
-	GenMove(board *Board) Move
+	GenMove(ctx context.Context, board *Board) Move
 	RequiresHumanInput() bool
 }
 
+type namedEngine struct {
+	name   string
+	engine Engine
+}
+
+var namedEngines = []namedEngine{
+	{"Human", &HumanEngine{}},
+	{"Random", &RandomEngine{}},
+	{"Greedy", &GreedyEngine{}},
+	{"Minimax2", &MinimaxEngine{maxDepth: 2}},
+	{"Minimax3", &MinimaxEngine{maxDepth: 3}},
+	{"Minimax4", &MinimaxEngine{maxDepth: 4}},
+	{"Think1s", &IterativeDeepeningEngine{timeLimit: 1 * time.Second}},
+	{"Think3s", &IterativeDeepeningEngine{timeLimit: 3 * time.Second}},
+	{"Think10s", &IterativeDeepeningEngine{timeLimit: 10 * time.Second}},
+	{"MCTS1s", &MCTSEngine{timeLimit: 1 * time.Second, numThreads: runtime.NumCPU(), greedyPlayouts: true}},
+	{"MCTS3s", &MCTSEngine{timeLimit: 3 * time.Second, numThreads: runtime.NumCPU(), greedyPlayouts: true}},
+}
+
+// findEngine looks up one of the namedEngines by name.
+func findEngine(name string) (Engine, error) {
+	for _, ne := range namedEngines {
+		if ne.name == name {
+			return ne.engine, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown engine %q", name)
+}
+
+// A TimeLimitedEngine can be told how long to think about each move,
+// overriding its default.
+type TimeLimitedEngine interface {
+	Engine
+	GenMoveWithTime(ctx context.Context, board *Board, limit time.Duration) Move
+}
+
 func (b *Board) GetLegalMoves() []Move {
 	moves := []Move{}
 
@@ -22,7 +65,7 @@ func (b *Board) GetLegalMoves() []Move {
 	}
 
 	for _, from := range startingLocs {
-		stepLocs := (adjacentBitboards[from] & b.empty).GetSetBitIndices()
+		stepLocs := (b.geom.adjacent[from] & b.empty).GetSetBitIndices()
 		for _, to := range stepLocs {
 			moves = append(moves, Move{
 				from: from,
@@ -31,7 +74,7 @@ func (b *Board) GetLegalMoves() []Move {
 				pass: false,
 			})
 		}
-		jumpLocs := (jumpBitboards[from] & b.empty).GetSetBitIndices()
+		jumpLocs := (b.geom.jump[from] & b.empty).GetSetBitIndices()
 		for _, to := range jumpLocs {
 			moves = append(moves, Move{
 				from: from,
@@ -56,7 +99,7 @@ func (b *Board) GetLegalMoves() []Move {
 
 type RandomEngine struct{}
 
-func (e *RandomEngine) GenMove(board *Board) Move {
+func (e *RandomEngine) GenMove(ctx context.Context, board *Board) Move {
 	moves := board.GetLegalMoves()
 	idx := rand.Intn(len(moves))
 	return moves[idx]
@@ -76,7 +119,7 @@ func ScoreForPlayer(b *Board, p Player) int {
 	return blackScore - whiteScore
 }
 
-func (e *GreedyEngine) GenMove(board *Board) Move {
+func (e *GreedyEngine) GenMove(ctx context.Context, board *Board) Move {
 	moves := board.GetLegalMoves()
 	if len(moves) == 1 {
 		return moves[0]
@@ -111,8 +154,12 @@ type MinimaxEngine struct {
 
 // alpha represents the minimum score that white can guarantee.
 // Beta is the max score that black can guarantee
-func minimax(board *Board, depth int, alpha int, beta int, player Player) (bestMove Move, score int, numEvals int) {
-	if depth == 0 || board.IsGameOver() {
+// If ctx is cancelled the search stops early and the results are meaningless.
+func minimax(ctx context.Context, board *Board, depth int, alpha int, beta int, player Player) (bestMove Move, score int, numEvals int) {
+	if outcome, over := board.IsGameOver(); over {
+		return Move{}, outcome.PieceDiff(), 1
+	}
+	if depth == 0 || ctx.Err() != nil {
 		// fmt.Printf("depth 0, board = \n%s\n", board.String())
 		w, b := board.Score()
 		return Move{}, w - b, 1
@@ -128,7 +175,7 @@ func minimax(board *Board, depth int, alpha int, beta int, player Player) (bestM
 			child := board.Copy()
 			child.Move(move)
 			//fmt.Printf("%*s  move %v for player %v\n", 2*depth, "", move, player)
-			_, eval, evalCount := minimax(child, depth-1, alpha, beta, Black)
+			_, eval, evalCount := minimax(ctx, child, depth-1, alpha, beta, Black)
 			numEvals += evalCount
 			//fmt.Printf("%*s  eval = %d\n", 2*depth, "", eval)
 			if eval > maxEval {
@@ -153,7 +200,7 @@ func minimax(board *Board, depth int, alpha int, beta int, player Player) (bestM
 		for _, move := range moves {
 			child := board.Copy()
 			child.Move(move)
-			_, eval, evalCount := minimax(child, depth-1, alpha, beta, White)
+			_, eval, evalCount := minimax(ctx, child, depth-1, alpha, beta, White)
 			numEvals += evalCount
 			// fmt.Printf("%*s  move %v for player %v\n", 2*depth, "", move, player)
 			// fmt.Printf("%*s  eval = %d\n", 2*depth, "", eval)
@@ -174,8 +221,12 @@ func minimax(board *Board, depth int, alpha int, beta int, player Player) (bestM
 	}
 }
 
-func (e *MinimaxEngine) GenMove(board *Board) Move {
-	bestMove, _, _ := minimax(board, e.maxDepth, math.MinInt, math.MaxInt, board.playerToMove)
+func (e *MinimaxEngine) GenMove(ctx context.Context, board *Board) Move {
+	bestMove, _, _ := minimax(ctx, board, e.maxDepth, math.MinInt, math.MaxInt, board.playerToMove)
+	if ctx.Err() != nil {
+		// the search was cut short, so any legal move will do
+		return board.GetLegalMoves()[0]
+	}
 	// fmt.Printf("Minimax evaluated %d moves\n", numEvals)
 	// fmt.Printf("best move: %v, score = %v\n", bestMove, score)
 	return bestMove
@@ -187,10 +238,13 @@ func (e *MinimaxEngine) RequiresHumanInput() bool {
 
 type SlowEngine struct{}
 
-func (e *SlowEngine) GenMove(board *Board) Move {
+func (e *SlowEngine) GenMove(ctx context.Context, board *Board) Move {
 	dummyEngine := RandomEngine{}
-	time.Sleep(1 * time.Second)
-	return dummyEngine.GenMove(board)
+	select {
+	case <-time.After(1 * time.Second):
+	case <-ctx.Done():
+	}
+	return dummyEngine.GenMove(ctx, board)
 }
 
 func (e *SlowEngine) RequiresHumanInput() bool {
@@ -199,7 +253,7 @@ func (e *SlowEngine) RequiresHumanInput() bool {
 
 type HumanEngine struct{}
 
-func (e HumanEngine) GenMove(b *Board) Move {
+func (e HumanEngine) GenMove(ctx context.Context, b *Board) Move {
 	return getMoveFromUser(b)
 }
 
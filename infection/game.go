@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
 	"image/color"
+	"log"
+	"os"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 type GameState int
@@ -11,7 +17,7 @@ type GameState int
 // Layout
 const (
 	ScreenWidth  = 508
-	ScreenHeight = 680
+	ScreenHeight = 820
 	TileSize     = 64
 
 	Margin = 30
@@ -21,8 +27,9 @@ const (
 	Player2ViewX = 300
 	Player2ViewY = Margin
 
-	BoardWidgetX = Margin
-	BoardWidgetY = 135
+	BoardWidgetX    = Margin
+	BoardWidgetY    = 135
+	BoardWidgetSize = DefaultBoardSize * TileSize
 
 	NewGameButtonX      = Margin
 	NewGameButtonY      = 608
@@ -32,6 +39,28 @@ const (
 	SpinnerSize = 48
 	SpinnerX    = (ScreenWidth) / 2
 	SpinnerY    = 628
+
+	LayoutViewX = 330
+	LayoutViewY = 612
+
+	// undo, redo, save and load buttons
+	RecordButtonsX       = Margin
+	RecordButtonsY       = 664
+	RecordButtonWidth    = 100
+	RecordButtonHeight   = 40
+	RecordButtonsSpacing = 16
+
+	HintButtonX      = Margin
+	HintButtonY      = 720
+	HintButtonWidth  = RecordButtonWidth
+	HintButtonHeight = RecordButtonHeight
+
+	AnalysisViewX     = HintButtonX + HintButtonWidth + RecordButtonsSpacing
+	AnalysisViewY     = HintButtonY
+	AnalysisViewWidth = ScreenWidth - Margin - AnalysisViewX
+
+	// how long the analysis panel searches each position
+	AnalysisTime = 10 * time.Second
 )
 
 const (
@@ -40,39 +69,225 @@ const (
 	WaitingForHuman
 	ComputerThinking
 	AnimatingComputerMove
+	// Some moves have been undone; engines wait until they are redone, or
+	// a human makes a different move.
+	Reviewing
 	GameOver
 )
 
 type Game struct {
 	state         GameState
 	gameBoard     *Board
+	record        *GameRecord
 	boardWidget   *BoardWidget
 	spinner       *Spinner
 	newGameButton *Button
+	layoutView    *LayoutView
+	recordButtons []*Button
+	hintButton    *Button
+	analyzer      *Analyzer
+	analysisView  *AnalysisView
+	// if set, the analyzer's best move is highlighted for a human to move
+	showHint bool
+	// how the game ended, while in the GameOver state
+	outcome Outcome
 
 	player1View *PlayerView
 	player2View *PlayerView
+
+	// if set, each move is logged along with the resulting position
+	logMoves bool
+	// file used by the save and load buttons
+	recordPath string
+
+	// While in ComputerThinking, thinkingEngine is searching a copy of the
+	// board in the background and will send its move on thinkingResult.
+	// cancelThinking stops it early.
+	thinkingEngine Engine
+	thinkingResult chan Move
+	cancelThinking context.CancelFunc
 }
 
 func NewGame() *Game {
+	whiteColor := color.RGBA{255, 255, 0, 255}
+	blackColor := color.RGBA{255, 0, 0, 255}
 	g := Game{
 		state:         GameInProgress,
-		gameBoard:     NewBoard(),
+		gameBoard:     NewBoard(StandardGeometry),
 		boardWidget:   NewBoardWidget(BoardWidgetX, BoardWidgetY),
 		spinner:       NewSpinner(SpinnerX, SpinnerY, 0.03),
 		newGameButton: nil,
-		player1View:   NewPlayerView(Player1ViewX, Player1ViewY, 0, color.RGBA{255, 255, 0, 255}),
-		player2View:   NewPlayerView(Player2ViewX, Player2ViewY, 0, color.RGBA{255, 0, 0, 255}),
+		layoutView:    NewLayoutView(LayoutViewX, LayoutViewY),
+		player1View:   NewPlayerView(Player1ViewX, Player1ViewY, 0, whiteColor),
+		player2View:   NewPlayerView(Player2ViewX, Player2ViewY, 0, blackColor),
+		analyzer:      NewAnalyzer(AnalysisTime),
+		analysisView:  NewAnalysisView(AnalysisViewX, AnalysisViewY, AnalysisViewWidth, whiteColor, blackColor),
+		recordPath:    "infection.pgn",
 	}
 
 	g.newGameButton = NewButton(NewGameButtonX, NewGameButtonY, NewGameButtonWidth, NewGameButtonHeight,
 		GenerateButtonImage(NewGameButtonWidth, NewGameButtonHeight, "New Game", color.RGBA{55, 148, 110, 255}, color.RGBA{0, 0, 0, 255}),
 		GenerateButtonImage(NewGameButtonWidth, NewGameButtonHeight, "New Game", color.RGBA{153, 229, 80, 255}, color.RGBA{0, 0, 0, 255}),
-		func() { g.gameBoard = NewBoard() },
+		func() { g.SetBoard(NewBoard(g.layoutView.CreateGeometry())) },
+	)
+
+	recordActions := []struct {
+		label   string
+		onclick func()
+	}{
+		{"Undo", g.undo},
+		{"Redo", g.redo},
+		{"Save", g.save},
+		{"Load", g.load},
+	}
+	for i, action := range recordActions {
+		x := RecordButtonsX + i*(RecordButtonWidth+RecordButtonsSpacing)
+		g.recordButtons = append(g.recordButtons, NewButton(x, RecordButtonsY, RecordButtonWidth, RecordButtonHeight,
+			GenerateButtonImage(RecordButtonWidth, RecordButtonHeight, action.label, color.RGBA{55, 148, 110, 255}, color.RGBA{0, 0, 0, 255}),
+			GenerateButtonImage(RecordButtonWidth, RecordButtonHeight, action.label, color.RGBA{153, 229, 80, 255}, color.RGBA{0, 0, 0, 255}),
+			action.onclick,
+		))
+	}
+
+	g.hintButton = NewButton(HintButtonX, HintButtonY, HintButtonWidth, HintButtonHeight,
+		GenerateButtonImage(HintButtonWidth, HintButtonHeight, "Hint", color.RGBA{55, 148, 110, 255}, color.RGBA{0, 0, 0, 255}),
+		GenerateButtonImage(HintButtonWidth, HintButtonHeight, "Hint", color.RGBA{153, 229, 80, 255}, color.RGBA{0, 0, 0, 255}),
+		func() { g.showHint = g.getCurrentEngine().RequiresHumanInput() },
 	)
+
+	g.record = NewGameRecord(g.gameBoard, g.player1View.playerName, g.player2View.playerName)
 	return &g
 }
 
+// SetBoard replaces the current game with one starting from the given position.
+func (g *Game) SetBoard(board *Board) {
+	g.stopComputer()
+	g.gameBoard = board
+	g.record = NewGameRecord(board, g.player1View.playerName, g.player2View.playerName)
+	if g.logMoves {
+		log.Printf("new game: %s", board.FEN())
+	}
+}
+
+func (g *Game) makeMove(move Move) {
+	g.clearHint()
+	notation := g.gameBoard.FormatMove(move)
+	g.gameBoard.Move(move)
+	g.record.Add(move)
+	if g.logMoves {
+		log.Printf("%s  %s", notation, g.gameBoard.FEN())
+	}
+}
+
+// startThinking asks the engine for a move in the background.  The
+// engine gets its own copy of the board, so the game can carry on (or be
+// abandoned) while it thinks.
+func (g *Game) startThinking(engine Engine) {
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan Move, 1)
+	board := g.gameBoard.Copy()
+	go func() {
+		result <- engine.GenMove(ctx, board)
+	}()
+	g.thinkingEngine = engine
+	g.thinkingResult = result
+	g.cancelThinking = cancel
+	g.state = ComputerThinking
+}
+
+// stopThinking cancels the engine's search, if any.  Its move is ignored
+// when it arrives.
+func (g *Game) stopThinking() {
+	if g.cancelThinking != nil {
+		g.cancelThinking()
+	}
+	g.thinkingEngine = nil
+	g.thinkingResult = nil
+	g.cancelThinking = nil
+}
+
+// stopComputer abandons any move the computer is choosing or animating,
+// so the position can be changed.
+func (g *Game) stopComputer() {
+	g.clearHint()
+	g.stopThinking()
+	g.boardWidget.CancelComputerMove()
+	g.state = GameInProgress
+}
+
+func (g *Game) clearHint() {
+	g.showHint = false
+	g.boardWidget.ClearHint()
+}
+
+// updateAnalysis keeps the analyzer working on the current position, and
+// shows a hint once one has been asked for and the analyzer has a move.
+// Analysis pauses while an engine is choosing a move so they don't compete
+// for the CPU.
+func (g *Game) updateAnalysis() {
+	if g.state == ComputerThinking || g.state == AnimatingComputerMove {
+		g.analyzer.Stop()
+		return
+	}
+	g.analyzer.Analyze(g.gameBoard)
+	if !g.showHint {
+		return
+	}
+	if _, over := g.gameBoard.IsGameOver(); over {
+		return
+	}
+	if result, ok := g.analyzer.Result(g.gameBoard); ok {
+		g.boardWidget.SetHint(result.bestMove)
+	}
+}
+
+func (g *Game) undo() {
+	if !g.record.CanUndo() {
+		return
+	}
+	g.stopComputer()
+	g.record.Undo()
+	g.gameBoard = g.record.Board()
+}
+
+func (g *Game) redo() {
+	if !g.record.CanRedo() {
+		return
+	}
+	g.stopComputer()
+	g.record.Redo()
+	g.gameBoard = g.record.Board()
+}
+
+func (g *Game) save() {
+	g.record.whiteName = g.player1View.playerName
+	g.record.blackName = g.player2View.playerName
+	if err := os.WriteFile(g.recordPath, []byte(g.record.String()), 0644); err != nil {
+		log.Printf("could not save game: %v", err)
+		return
+	}
+	log.Printf("saved game to %s", g.recordPath)
+}
+
+// load reads a saved game and rewinds it to the start, so it can be
+// stepped through with redo.
+func (g *Game) load() {
+	data, err := os.ReadFile(g.recordPath)
+	if err != nil {
+		log.Printf("could not load game: %v", err)
+		return
+	}
+	record, err := ParseGameRecord(string(data))
+	if err != nil {
+		log.Printf("could not load game from %s: %v", g.recordPath, err)
+		return
+	}
+	record.GoToStart()
+	g.stopComputer()
+	g.record = record
+	g.gameBoard = record.Board()
+}
+
 func (g *Game) getCurrentEngine() Engine {
 	if g.gameBoard.playerToMove == White {
 		return g.player1View.GetEngine()
@@ -85,27 +300,29 @@ func (g *Game) Update() error {
 	g.player1View.Update()
 	g.player2View.Update()
 	g.newGameButton.Update()
+	g.layoutView.Update()
+	for _, b := range g.recordButtons {
+		b.Update()
+	}
+	g.hintButton.Update()
 
 	switch g.state {
 	case GameInProgress:
 		g.spinner.SetVisible(false)
-		if g.gameBoard.IsGameOver() {
+		if outcome, over := g.record.IsGameOver(); over {
+			g.outcome = outcome
 			g.state = GameOver
+			if g.logMoves {
+				log.Printf("game over: %v", outcome)
+			}
+		} else if !g.record.AtEnd() {
+			g.state = Reviewing
 		} else {
 			currEngine := g.getCurrentEngine()
 			if currEngine.RequiresHumanInput() {
 				g.state = WaitingForHuman
 			} else {
-				g.state = ComputerThinking
-				// Launch the goroutine to generate the move;
-				// not called in ComputerThinking state to avoid
-				// multiple goroutines being launched as Update is called repeatedly.
-				go func() {
-					currEngine := g.getCurrentEngine()
-					move := currEngine.GenMove(g.gameBoard)
-					g.boardWidget.DoComputerMove(move, g.gameBoard.playerToMove)
-					g.state = AnimatingComputerMove
-				}()
+				g.startThinking(currEngine)
 			}
 		}
 
@@ -116,27 +333,55 @@ func (g *Game) Update() error {
 			g.state = GameInProgress
 		} else if move, ok := g.boardWidget.GetAndClearHumanMove(); ok {
 			if valid, _ := move.IsValid(g.gameBoard); valid {
-				g.gameBoard.Move(move)
+				g.makeMove(move)
+			}
+			g.state = GameInProgress
+		}
+
+	case Reviewing:
+		// a human may branch off from the recorded game
+		if g.getCurrentEngine().RequiresHumanInput() {
+			g.boardWidget.Update(g.gameBoard)
+			if move, ok := g.boardWidget.GetAndClearHumanMove(); ok {
+				if valid, _ := move.IsValid(g.gameBoard); valid {
+					g.makeMove(move)
+				}
 			}
+		}
+		if g.record.AtEnd() {
 			g.state = GameInProgress
 		}
 
 	case ComputerThinking:
 		g.spinner.SetVisible(true)
+		if g.getCurrentEngine() != g.thinkingEngine {
+			// player switched the engine while it was thinking
+			g.stopThinking()
+			g.state = GameInProgress
+			break
+		}
+		select {
+		case move := <-g.thinkingResult:
+			g.stopThinking()
+			g.boardWidget.DoComputerMove(move, g.gameBoard.playerToMove)
+			g.state = AnimatingComputerMove
+		default:
+		}
 
 	case AnimatingComputerMove:
 		g.boardWidget.UpdateComputerDragInfo()
 		if move, ok := g.boardWidget.GetAndClearComputerMove(); ok {
-			g.gameBoard.Move(move)
+			g.makeMove(move)
 			g.state = GameInProgress
 		}
 
 	case GameOver:
-		if !g.gameBoard.IsGameOver() {
+		if _, over := g.record.IsGameOver(); !over {
 			g.state = GameInProgress
 		}
 
 	}
+	g.updateAnalysis()
 
 	return nil
 }
@@ -151,6 +396,36 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		g.spinner.Draw(screen)
 	}
 	g.newGameButton.Draw(screen)
+	g.layoutView.Draw(screen)
+	for _, b := range g.recordButtons {
+		b.Draw(screen)
+	}
+	g.hintButton.Draw(screen)
+	if g.state == GameOver {
+		g.drawOutcome(screen)
+	}
+	result, ok := g.analyzer.Result(g.gameBoard)
+	g.analysisView.Draw(screen, g.gameBoard, result, ok)
+}
+
+// drawOutcome shows the result and how the game ended across the board.
+func (g *Game) drawOutcome(screen *ebiten.Image) {
+	const height = 64
+	y := BoardWidgetY + (BoardWidgetSize-height)/2
+	vector.DrawFilledRect(screen, BoardWidgetX, float32(y), BoardWidgetSize, height, color.RGBA{10, 40, 30, 220}, false)
+
+	lines := []string{g.outcome.Headline(), g.outcome.reason.String()}
+	fontSize := float64(20)
+	for i, line := range lines {
+		op := &text.DrawOptions{}
+		op.GeoM.Translate(BoardWidgetX+BoardWidgetSize/2, float64(y+8)+float64(i)*(fontSize+4))
+		op.ColorScale.ScaleWithColor(color.White)
+		op.PrimaryAlign = text.AlignCenter
+		text.Draw(screen, line, &text.GoTextFace{
+			Source: DisplayFont,
+			Size:   fontSize,
+		}, op)
+	}
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
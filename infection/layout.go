@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math/rand"
+)
+
+type namedLayout struct {
+	name   string
+	create func() *Geometry
+}
+
+var namedLayouts = []namedLayout{
+	{"7x7", func() *Geometry { return StandardGeometry }},
+	{"5x5", func() *Geometry { return NewGeometry(5, BitBoard(0)) }},
+	{"6x6", func() *Geometry { return NewGeometry(6, BitBoard(0)) }},
+	{"8x8", func() *Geometry { return NewGeometry(8, BitBoard(0)) }},
+	{"Cross", func() *Geometry { return CrossGeometry(DefaultBoardSize) }},
+	{"Corners", func() *Geometry { return CornersBlockedGeometry(DefaultBoardSize) }},
+	{"Holes", func() *Geometry { return RandomHolesGeometry(DefaultBoardSize, 2) }},
+}
+
+// symmetricSquares returns the square at (row, col) together with its
+// mirror images across the horizontal and vertical center lines, so that
+// blocking them keeps the board fair for both players.
+func symmetricSquares(size, row, col int) BitBoard {
+	bb := BitBoard(0)
+	last := size - 1
+	for _, r := range []int{row, last - row} {
+		for _, c := range []int{col, last - col} {
+			bb = bb.Set(SquareIndex(r*size + c))
+		}
+	}
+	return bb
+}
+
+// CrossGeometry blocks a plus shape in the middle of the board.
+func CrossGeometry(size int) *Geometry {
+	mid := size / 2
+	blocked := BitBoard(0)
+	blocked |= symmetricSquares(size, mid, mid)
+	blocked |= symmetricSquares(size, mid-1, mid)
+	blocked |= symmetricSquares(size, mid, mid-1)
+	return NewGeometry(size, blocked)
+}
+
+// CornersBlockedGeometry blocks the four corner squares; the starting
+// pieces are then placed one square in along the diagonal.
+func CornersBlockedGeometry(size int) *Geometry {
+	return NewGeometry(size, symmetricSquares(size, 0, 0))
+}
+
+// RandomHolesGeometry blocks numHoles randomly chosen squares in the
+// top-left quadrant, mirrored into the other three quadrants.  The corners
+// and their diagonal neighbors are never blocked, so the starting pieces
+// can always move.
+func RandomHolesGeometry(size int, numHoles int) *Geometry {
+	half := (size + 1) / 2
+	candidates := [][2]int{}
+	for r := range half {
+		for c := range half {
+			if r == c && r <= 1 {
+				continue
+			}
+			candidates = append(candidates, [2]int{r, c})
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	blocked := BitBoard(0)
+	for _, rc := range candidates[:min(numHoles, len(candidates))] {
+		blocked |= symmetricSquares(size, rc[0], rc[1])
+	}
+	return NewGeometry(size, blocked)
+}
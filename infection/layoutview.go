@@ -0,0 +1,75 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+// LayoutView lets the user choose the board layout used for the next game.
+type LayoutView struct {
+	previousLayout *Button
+	nextLayout     *Button
+	layoutName     string
+	X              int
+	Y              int
+	index          int
+}
+
+const (
+	lvLabelWidth = 80
+	lvButtonSize = 32
+)
+
+func NewLayoutView(x, y int) *LayoutView {
+	lv := &LayoutView{
+		previousLayout: nil,
+		nextLayout:     nil,
+		layoutName:     namedLayouts[0].name,
+		X:              x,
+		Y:              y,
+		index:          0,
+	}
+	lv.previousLayout = NewButton(x, y, lvButtonSize, lvButtonSize,
+		LeftArrowIdleImage,
+		LeftArrowPressedImage,
+		func() { lv.SwitchLayout(-1) })
+	lv.nextLayout = NewButton(x+lvButtonSize+lvLabelWidth, y, lvButtonSize, lvButtonSize,
+		RightArrowIdleImage,
+		RightArrowPressedImage,
+		func() { lv.SwitchLayout(1) })
+	return lv
+}
+
+func (l *LayoutView) Update() {
+	l.previousLayout.Update()
+	l.nextLayout.Update()
+}
+
+func (l *LayoutView) Draw(screen *ebiten.Image) {
+	l.previousLayout.Draw(screen)
+	l.nextLayout.Draw(screen)
+
+	op := &text.DrawOptions{}
+	fontSize := float64(16)
+	op.GeoM.Translate(float64(l.X+lvButtonSize+lvLabelWidth/2), float64(l.Y+5))
+	op.ColorScale.ScaleWithColor(color.White)
+	op.LineSpacing = fontSize
+	op.PrimaryAlign = text.AlignCenter
+	text.Draw(screen, l.layoutName, &text.GoTextFace{
+		Source: DisplayFont,
+		Size:   fontSize,
+	}, op)
+}
+
+func (l *LayoutView) SwitchLayout(offset int) {
+	l.index = (l.index + offset + len(namedLayouts)) % len(namedLayouts)
+	l.layoutName = namedLayouts[l.index].name
+}
+
+// CreateGeometry makes the geometry for the selected layout.  Random
+// layouts give a different geometry on each call.
+func (l *LayoutView) CreateGeometry() *Geometry {
+	return namedLayouts[l.index].create()
+}
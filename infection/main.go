@@ -1,13 +1,75 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
 func main() {
+	uai := flag.Bool("uai", false, "run as a UAI engine on stdin/stdout instead of opening the game window")
+	uaiEngine := flag.String("uai-engine", "Think1s", "name of the engine that plays in -uai mode")
+	external := flag.String("engine", "", "command line of an external UAI engine to offer as an opponent")
+	externalTime := flag.Duration("engine-time", 1*time.Second, "time per move for the external engine")
+	fen := flag.String("fen", "", "position to start the first game from, in FEN")
+	logMoves := flag.Bool("log", false, "log each move and position in FEN")
+	recordPath := flag.String("record", "infection.pgn", "file used to save and load games")
+	tournament := flag.String("tournament", "", "comma-separated engines to play a round-robin tournament between, or \"all\"")
+	rounds := flag.Int("rounds", 1, "number of times each pair of engines plays each opening with each color")
+	randomOpenings := flag.Int("random-openings", 4, "number of random opening positions to add to the tournament")
+	parallel := flag.Int("parallel", runtime.NumCPU(), "number of tournament games to play at once")
+	flag.Parse()
+
+	if *uai {
+		engine, err := findEngine(*uaiEngine)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := RunUAI(engine, "Infection "+*uaiEngine, os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *external != "" {
+		args := strings.Fields(*external)
+		engine := NewExternalEngine(args[0], args[1:], *externalTime)
+		defer engine.Close()
+		namedEngines = append(namedEngines, namedEngine{"External", engine})
+	}
+
+	if *tournament != "" {
+		names := strings.Split(*tournament, ",")
+		if *tournament == "all" {
+			names = computerEngineNames()
+		}
+		config := TournamentConfig{
+			engineNames: names,
+			openings:    DefaultOpenings(*randomOpenings, 1),
+			numRounds:   *rounds,
+			parallelism: *parallel,
+		}
+		if err := RunTournament(config, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	game := NewGame()
+	game.logMoves = *logMoves
+	game.recordPath = *recordPath
+	if *fen != "" {
+		board, err := NewBoardFromFEN(*fen)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game.SetBoard(board)
+	}
 	ebiten.SetWindowSize(ScreenWidth, ScreenHeight)
 	ebiten.SetWindowTitle("Infection")
 	if err := ebiten.RunGame(game); err != nil {
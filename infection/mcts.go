@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// MCTSEngine chooses moves with Monte Carlo Tree Search using the UCT
+// formula.  Each thread grows its own tree from the root (root
+// parallelism) and the visit counts of the root moves are summed at the end.
+type MCTSEngine struct {
+	// stop after this many playouts in total, if > 0
+	iterations int
+	// stop after this much time, if > 0
+	timeLimit time.Duration
+	// number of trees to search in parallel
+	numThreads int
+	// if set, playouts prefer capturing moves instead of being uniformly random
+	greedyPlayouts bool
+}
+
+const (
+	// exploration constant for UCT
+	uctExploration = 1.0
+	// playouts longer than this are scored on the pieces each side has
+	maxPlayoutPlies = 200
+	// chance a greedy playout picks the best capturing move
+	greedyPlayoutProb = 0.75
+)
+
+type mctsNode struct {
+	move     Move
+	parent   *mctsNode
+	children []*mctsNode
+	// moves not yet expanded into children
+	untried []Move
+	// the player who made move, whose point of view wins is from
+	player Player
+	visits int
+	wins   float64
+}
+
+func newMCTSNode(board *Board, move Move, parent *mctsNode) *mctsNode {
+	n := &mctsNode{
+		move:   move,
+		parent: parent,
+		player: board.playerToMove.Other(),
+	}
+	if _, over := board.IsGameOver(); !over {
+		n.untried = board.GetLegalMoves()
+	}
+	return n
+}
+
+// selectChild picks the child with the highest UCT value.
+func (n *mctsNode) selectChild() *mctsNode {
+	logVisits := math.Log(float64(n.visits))
+	var best *mctsNode
+	bestValue := math.Inf(-1)
+	for _, c := range n.children {
+		value := c.wins/float64(c.visits) + uctExploration*math.Sqrt(logVisits/float64(c.visits))
+		if value > bestValue {
+			best = c
+			bestValue = value
+		}
+	}
+	return best
+}
+
+// mctsTree is one thread's search tree.
+type mctsTree struct {
+	root           *mctsNode
+	board          *Board
+	rng            *rand.Rand
+	greedyPlayouts bool
+}
+
+func newMCTSTree(board *Board, seed int64, greedyPlayouts bool) *mctsTree {
+	return &mctsTree{
+		root:           newMCTSNode(board, Move{}, nil),
+		board:          board,
+		rng:            rand.New(rand.NewSource(seed)),
+		greedyPlayouts: greedyPlayouts,
+	}
+}
+
+// iterate runs one round of selection, expansion, playout and backpropagation.
+func (t *mctsTree) iterate() {
+	node := t.root
+	board := *t.board
+
+	for len(node.untried) == 0 && len(node.children) > 0 {
+		node = node.selectChild()
+		board.Move(node.move)
+	}
+
+	if len(node.untried) > 0 {
+		i := t.rng.Intn(len(node.untried))
+		move := node.untried[i]
+		node.untried[i] = node.untried[len(node.untried)-1]
+		node.untried = node.untried[:len(node.untried)-1]
+		board.Move(move)
+		child := newMCTSNode(&board, move, node)
+		node.children = append(node.children, child)
+		node = child
+	}
+
+	winner, draw := t.playout(&board)
+	for ; node != nil; node = node.parent {
+		node.visits++
+		if draw {
+			node.wins += 0.5
+		} else if node.player == winner {
+			node.wins += 1
+		}
+	}
+}
+
+// playout plays the board out to the end, returning the winner.
+func (t *mctsTree) playout(board *Board) (winner Player, draw bool) {
+	for plies := 0; plies < maxPlayoutPlies; plies++ {
+		if _, over := board.IsGameOver(); over {
+			break
+		}
+		moves := board.GetLegalMoves()
+		move := moves[t.rng.Intn(len(moves))]
+		if t.greedyPlayouts && t.rng.Float64() < greedyPlayoutProb {
+			move = t.greedyMove(board, moves)
+		}
+		board.Move(move)
+	}
+	w, b := board.Score()
+	diff := w - b
+	if outcome, over := board.IsGameOver(); over {
+		diff = outcome.PieceDiff()
+	}
+	if diff == 0 {
+		return White, true
+	}
+	if diff > 0 {
+		return White, false
+	}
+	return Black, false
+}
+
+// greedyMove picks a move capturing the most pieces, preferring steps.
+// Ties are broken at random so playouts stay varied.
+func (t *mctsTree) greedyMove(board *Board, moves []Move) Move {
+	best := moves[0]
+	bestValue := -1
+	numTied := 0
+	for _, m := range moves {
+		value := 2 * board.numCaptures(m)
+		if !m.jump {
+			value++
+		}
+		if value > bestValue {
+			best, bestValue, numTied = m, value, 1
+		} else if value == bestValue {
+			numTied++
+			if t.rng.Intn(numTied) == 0 {
+				best = m
+			}
+		}
+	}
+	return best
+}
+
+// search grows trees from the board until the iteration budget is used
+// or ctx is done, and returns the root moves' total visit counts.
+func (e *MCTSEngine) search(ctx context.Context, board *Board) map[Move]int {
+	numThreads := max(e.numThreads, 1)
+	trees := make([]*mctsTree, numThreads)
+	seed := time.Now().UnixNano()
+	var wg sync.WaitGroup
+	for i := range numThreads {
+		trees[i] = newMCTSTree(board, seed+int64(i), e.greedyPlayouts)
+		// share the budget out, giving each tree at least one iteration
+		budget := e.iterations / numThreads
+		if i < e.iterations%numThreads {
+			budget++
+		}
+		budget = max(budget, 1)
+		wg.Add(1)
+		go func(tree *mctsTree, budget int) {
+			defer wg.Done()
+			for iter := 0; ; iter++ {
+				if e.iterations > 0 && iter >= budget {
+					return
+				}
+				// check for cancellation every few iterations, as it's slow
+				if iter%16 == 0 && ctx.Err() != nil {
+					return
+				}
+				tree.iterate()
+			}
+		}(trees[i], budget)
+	}
+	wg.Wait()
+
+	visits := map[Move]int{}
+	for _, tree := range trees {
+		for _, c := range tree.root.children {
+			visits[c.move] += c.visits
+		}
+	}
+	return visits
+}
+
+func (e *MCTSEngine) GenMoveWithTime(ctx context.Context, board *Board, limit time.Duration) Move {
+	moves := board.GetLegalMoves()
+	if len(moves) == 1 {
+		return moves[0]
+	}
+
+	if limit <= 0 && e.iterations <= 0 {
+		limit = defaultMoveTime
+	}
+	if limit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limit)
+		defer cancel()
+	}
+	visits := e.search(ctx, board)
+
+	// the most visited move is the most robust choice
+	bestMove := moves[0]
+	bestVisits := -1
+	for _, m := range moves {
+		if visits[m] > bestVisits {
+			bestMove = m
+			bestVisits = visits[m]
+		}
+	}
+	return bestMove
+}
+
+func (e *MCTSEngine) GenMove(ctx context.Context, board *Board) Move {
+	return e.GenMoveWithTime(ctx, board, e.timeLimit)
+}
+
+func (e *MCTSEngine) RequiresHumanInput() bool {
+	return false
+}
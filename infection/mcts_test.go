@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMCTSFindsCapture(t *testing.T) {
+	// a small board keeps the playouts short, so the search is reliable
+	board, err := NewBoardFromText(`
+		o . . . .
+		. x x x .
+		. x . x .
+		. x x x .
+		. . . . o`)
+	if err != nil {
+		t.Fatalf("Failed to create board from text: %v", err)
+	}
+	engine := MCTSEngine{iterations: 2000, numThreads: 2, greedyPlayouts: true}
+	move := engine.GenMove(context.Background(), board)
+	if want := board.GetIndex(2, 2); move.to != want {
+		t.Errorf("want move to %d, got %v", want, move)
+	}
+}
+
+func TestMCTSIterationBudget(t *testing.T) {
+	tests := []struct {
+		iterations, numThreads, want int
+	}{
+		{300, 3, 300},
+		{301, 3, 301},
+		// every thread does at least one playout
+		{2, 4, 4},
+	}
+	board := NewBoard(StandardGeometry)
+	for _, tt := range tests {
+		engine := MCTSEngine{iterations: tt.iterations, numThreads: tt.numThreads}
+		visits := engine.search(context.Background(), board)
+		total := 0
+		for _, v := range visits {
+			total += v
+		}
+		if total != tt.want {
+			t.Errorf("%d iterations on %d threads: want %d playouts, got %d",
+				tt.iterations, tt.numThreads, tt.want, total)
+		}
+	}
+}
+
+func TestMCTSTimeBudget(t *testing.T) {
+	board := NewBoard(StandardGeometry)
+	engine := MCTSEngine{timeLimit: 50 * time.Millisecond, numThreads: 2}
+	start := time.Now()
+	move := engine.GenMove(context.Background(), board)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("search took %v", elapsed)
+	}
+	if valid, msg := move.IsValid(board); !valid {
+		t.Errorf("illegal move %v: %s", move, msg)
+	}
+}
+
+func TestMCTSStopsWhenCancelled(t *testing.T) {
+	board := NewBoard(StandardGeometry)
+	engine := &MCTSEngine{timeLimit: time.Minute, numThreads: 2}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	move := engine.GenMove(ctx, board)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("search took %v after being cancelled", elapsed)
+	}
+	if valid, msg := move.IsValid(board); !valid {
+		t.Errorf("expected a legal move, got %v: %s", move, msg)
+	}
+}
@@ -16,18 +16,22 @@ type Move struct {
 
 // CreateMove attempts to create a valid Move from a start and end square.
 // It returns a Move and an error.
-func CreateMove(from, to SquareIndex) (Move, error) {
-	if from < 0 || from >= NumSquares {
+func (b *Board) CreateMove(from, to SquareIndex) (Move, error) {
+	return b.geom.CreateMove(from, to)
+}
+
+func (g *Geometry) CreateMove(from, to SquareIndex) (Move, error) {
+	if !g.IsOnGame(from) {
 		return Move{}, fmt.Errorf("from index %v out of bounds", from)
 	}
-	if to < 0 || to >= NumSquares {
+	if !g.IsOnGame(to) {
 		return Move{}, fmt.Errorf("to index %v out of bounds", to)
 	}
 
-	if adjacentBitboards[from].Get(to) {
+	if g.adjacent[from].Get(to) {
 		return Move{from: from, to: to, jump: false, pass: false}, nil
 	}
-	if jumpBitboards[from].Get(to) {
+	if g.jump[from].Get(to) {
 		return Move{from: from, to: to, jump: true, pass: false}, nil
 	}
 
@@ -70,7 +74,7 @@ func (m *Move) IsValid(b *Board) (bool, string) {
 	// This is already handled by the CreateMove function, so we can re-use that logic.
 	// We don't need to check again if we assume CreateMove has already been used.
 	// However, for a truly robust IsValid, we can add a check here.
-	if (m.jump && !jumpBitboards[m.from].Get(m.to)) || (!m.jump && !adjacentBitboards[m.from].Get(m.to)) {
+	if (m.jump && !b.geom.jump[m.from].Get(m.to)) || (!m.jump && !b.geom.adjacent[m.from].Get(m.to)) {
 		return false, "invalid move type (step or jump)"
 	}
 
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Positions and moves are written in the notation used by Ataxx engines.
+// Files are letters starting at 'a' on the left, ranks are numbers starting
+// at 1 on the bottom row.  White pieces are 'o' and black pieces are 'x',
+// matching NewBoardFromText.
+
+// StartFEN is the standard starting position used by UAI's "startpos".
+const StartFEN = "x5o/7/7/7/7/7/o5x x 0 1"
+
+// PassNotation is how a pass is written.
+const PassNotation = "0000"
+
+func (g *Geometry) SquareName(index SquareIndex) string {
+	row, col := g.IndexToRowCol(index)
+	return fmt.Sprintf("%c%d", 'a'+col, g.size-row)
+}
+
+func (g *Geometry) ParseSquare(s string) (SquareIndex, error) {
+	if len(s) < 2 {
+		return -1, fmt.Errorf("invalid square %q", s)
+	}
+	col := int(s[0] - 'a')
+	rank, err := strconv.Atoi(s[1:])
+	if err != nil || col < 0 || col >= g.size || rank < 1 || rank > g.size {
+		return -1, fmt.Errorf("invalid square %q", s)
+	}
+	return g.GetIndex(g.size-rank, col), nil
+}
+
+// Format writes a move as "b2" for a step, "a1a3" for a jump, or "0000"
+// for a pass.  Steps don't need a from square, since every step to the
+// same square gives the same position.
+func (m Move) Format(g *Geometry) string {
+	if m.pass {
+		return PassNotation
+	}
+	if !m.jump {
+		return g.SquareName(m.to)
+	}
+	return g.SquareName(m.from) + g.SquareName(m.to)
+}
+
+// ParseMove reads a move written by Move.Format.  A step may also be
+// written with its from square, as in "a1b2".  When a step is written as a
+// single square, the from square is unknown and is returned as -1.
+func (g *Geometry) ParseMove(s string) (Move, error) {
+	if s == PassNotation {
+		return Move{from: -1, to: -1, jump: false, pass: true}, nil
+	}
+
+	// Squares are a letter followed by digits; split before the second letter.
+	split := strings.IndexFunc(s[min(1, len(s)):], func(r rune) bool { return r >= 'a' && r <= 'z' })
+	if split < 0 {
+		to, err := g.ParseSquare(s)
+		if err != nil {
+			return Move{}, err
+		}
+		return Move{from: -1, to: to, jump: false, pass: false}, nil
+	}
+
+	from, err := g.ParseSquare(s[:split+1])
+	if err != nil {
+		return Move{}, err
+	}
+	to, err := g.ParseSquare(s[split+1:])
+	if err != nil {
+		return Move{}, err
+	}
+	return g.CreateMove(from, to)
+}
+
+// FormatMove writes a move made on this board; see Move.Format.
+func (b *Board) FormatMove(m Move) string {
+	return m.Format(b.geom)
+}
+
+// ParseMove reads a move in the notation of Move.Format and checks that
+// it is legal on this board.
+func (b *Board) ParseMove(s string) (Move, error) {
+	m, err := b.geom.ParseMove(s)
+	if err != nil {
+		return Move{}, err
+	}
+	if !m.pass && !m.jump && m.from < 0 {
+		m, err = b.findStep(s, m.to)
+		if err != nil {
+			return Move{}, err
+		}
+	}
+	if valid, msg := m.IsValid(b); !valid {
+		return Move{}, fmt.Errorf("illegal move %s: %s", s, msg)
+	}
+	return m, nil
+}
+
+// findStep finds a piece belonging to the player to move that can step to
+// the given square.  All such steps give the same position.
+func (b *Board) findStep(s string, to SquareIndex) (Move, error) {
+	pieces := b.white
+	if b.playerToMove == Black {
+		pieces = b.black
+	}
+	candidates := (b.geom.adjacent[to] & pieces).GetSetBitIndices()
+	if len(candidates) == 0 {
+		return Move{}, fmt.Errorf("illegal move %s: no piece can step there", s)
+	}
+	return Move{from: candidates[0], to: to, jump: false, pass: false}, nil
+}
+
+// FEN returns the position in Ataxx FEN: the rows from top to bottom
+// separated by '/', with runs of empty squares written as numbers and
+// blocked squares as '-', then the side to move, the number of moves
+// since the last capture, and the move number.  The board size is given
+// by the number of rows.
+func (b *Board) FEN() string {
+	rows := []string{}
+	for r := range b.Size() {
+		row := ""
+		numEmpty := 0
+		for c := range b.Size() {
+			idx := b.GetIndex(r, c)
+			piece := ""
+			if b.white.Get(idx) {
+				piece = "o"
+			} else if b.black.Get(idx) {
+				piece = "x"
+			} else if !b.geom.onGame.Get(idx) {
+				piece = "-"
+			} else {
+				numEmpty++
+				continue
+			}
+			if numEmpty > 0 {
+				row += strconv.Itoa(numEmpty)
+				numEmpty = 0
+			}
+			row += piece
+		}
+		if numEmpty > 0 {
+			row += strconv.Itoa(numEmpty)
+		}
+		rows = append(rows, row)
+	}
+
+	side := "o"
+	if b.playerToMove == Black {
+		side = "x"
+	}
+	return fmt.Sprintf("%s %s %d %d", strings.Join(rows, "/"), side, b.halfmoveClock, b.fullmoveNumber)
+}
+
+// NewBoardFromFEN parses a position written by FEN.  The move counters
+// are optional.
+func NewBoardFromFEN(fen string) (*Board, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 2 || len(fields) > 4 {
+		return nil, fmt.Errorf("invalid FEN %q: expected board, side to move and move counters", fen)
+	}
+
+	rows := strings.Split(fields[0], "/")
+	size := len(rows)
+	if size < 2 || size > MaxBoardSize {
+		return nil, fmt.Errorf("invalid FEN %q: board size %d not supported", fen, size)
+	}
+
+	var white, black, blocked BitBoard
+	for r, row := range rows {
+		c := 0
+		for _, ch := range row {
+			if ch >= '1' && ch <= '9' {
+				c += int(ch - '0')
+				continue
+			}
+			if c >= size {
+				return nil, fmt.Errorf("invalid FEN %q: row %d is too long", fen, r+1)
+			}
+			idx := SquareIndex(r*size + c)
+			switch ch {
+			case 'o':
+				white = white.Set(idx)
+			case 'x':
+				black = black.Set(idx)
+			case '-':
+				blocked = blocked.Set(idx)
+			default:
+				return nil, fmt.Errorf("invalid FEN %q: unexpected character %q", fen, ch)
+			}
+			c++
+		}
+		if c != size {
+			return nil, fmt.Errorf("invalid FEN %q: row %d has %d squares, want %d", fen, r+1, c, size)
+		}
+	}
+
+	b := Board{
+		geom:           geometryFor(size, blocked),
+		white:          white,
+		black:          black,
+		halfmoveClock:  0,
+		fullmoveNumber: 1,
+	}
+	b.syncEmpty()
+
+	switch fields[1] {
+	case "o":
+		b.playerToMove = White
+	case "x":
+		b.playerToMove = Black
+	default:
+		return nil, fmt.Errorf("invalid FEN %q: unknown side to move %q", fen, fields[1])
+	}
+
+	counters := []*int{&b.halfmoveClock, &b.fullmoveNumber}
+	for i, field := range fields[2:] {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid FEN %q: bad move counter %q", fen, field)
+		}
+		*counters[i] = n
+	}
+	return &b, nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFENRoundTrip(t *testing.T) {
+	fens := []string{
+		StartFEN,
+		"o5x/7/7/7/7/7/x5o o 0 1",
+		"x2o/4/4/o2x o 3 7",
+		"o6x/8/8/8/8/8/8/x6o x 0 1",
+		"o5x/7/3-3/2---2/3-3/7/x5o o 0 1",
+		"-5-/1o3x1/7/7/7/1x3o1/-5- x 12 30",
+	}
+	for _, fen := range fens {
+		board, err := NewBoardFromFEN(fen)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", fen, err)
+		}
+		if got := board.FEN(); got != fen {
+			t.Errorf("want %s, got %s", fen, got)
+		}
+	}
+}
+
+func TestFENMatchesBoard(t *testing.T) {
+	type TestCase struct {
+		name  string
+		board *Board
+		fen   string
+	}
+
+	testCases := []TestCase{
+		{"standard", NewBoard(StandardGeometry), "o5x/7/7/7/7/7/x5o o 0 1"},
+		{"5x5", NewBoard(NewGeometry(5, BitBoard(0))), "o3x/5/5/5/x3o o 0 1"},
+		{"cross", NewBoard(CrossGeometry(7)), "o5x/7/3-3/2---2/3-3/7/x5o o 0 1"},
+		{"corners blocked", NewBoard(CornersBlockedGeometry(7)), "-5-/1o3x1/7/7/7/1x3o1/-5- o 0 1"},
+	}
+	for _, tc := range testCases {
+		if got := tc.board.FEN(); got != tc.fen {
+			t.Errorf("%s: want %s, got %s", tc.name, tc.fen, got)
+		}
+		parsed, err := NewBoardFromFEN(tc.fen)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(parsed, tc.board) {
+			t.Errorf("%s: want\n%s, got\n%s", tc.name, tc.board.String(), parsed.String())
+		}
+	}
+}
+
+func TestNewBoardFromFENErrors(t *testing.T) {
+	fens := []string{
+		"",
+		"x5o/7/7/7/7/7/o5x",
+		"x5o/7/7/7/7/7/o5x z 0 1",
+		"x5o/7/7/7/7/7/o6x x 0 1",
+		"x5o/7/7/7/7/7 x 0 1",
+		"x5o/7/7/7/7/7/o5y x 0 1",
+		"x5o/7/7/7/7/7/o5x x -1 1",
+		"x7o/9/9/9/9/9/9/9/o7x x 0 1",
+	}
+	for _, fen := range fens {
+		if _, err := NewBoardFromFEN(fen); err == nil {
+			t.Errorf("%q: expected error", fen)
+		}
+	}
+}
+
+func TestMoveNotationRoundTrip(t *testing.T) {
+	boards := []*Board{
+		NewBoard(StandardGeometry),
+		NewBoard(NewGeometry(8, BitBoard(0))),
+		NewBoard(CrossGeometry(7)),
+	}
+	for _, board := range boards {
+		for _, move := range board.GetLegalMoves() {
+			s := board.FormatMove(move)
+			parsed, err := board.ParseMove(s)
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", s, err)
+			}
+			after1 := board.Copy()
+			after1.Move(move)
+			after2 := board.Copy()
+			after2.Move(parsed)
+			if !reflect.DeepEqual(after1, after2) {
+				t.Errorf("%s: parsed move %v differs from %v", s, parsed, move)
+			}
+		}
+	}
+}
+
+func TestParseMove(t *testing.T) {
+	board := NewBoard(StandardGeometry)
+	type TestCase struct {
+		notation string
+		want     Move
+		wantErr  bool
+	}
+
+	testCases := []TestCase{
+		{"b6", Move{from: board.GetIndex(0, 0), to: board.GetIndex(1, 1)}, false},
+		{"a7b6", Move{from: board.GetIndex(0, 0), to: board.GetIndex(1, 1)}, false},
+		{"a7c5", Move{from: board.GetIndex(0, 0), to: board.GetIndex(2, 2), jump: true}, false},
+		{"g1e1", Move{from: board.GetIndex(6, 6), to: board.GetIndex(6, 4), jump: true}, false},
+		{"0000", Move{}, true},
+		{"d4", Move{}, true},
+		{"a7d4", Move{}, true},
+		{"g7f6", Move{}, true},
+		{"h1", Move{}, true},
+		{"a8", Move{}, true},
+		{"", Move{}, true},
+	}
+	for _, tc := range testCases {
+		got, err := board.ParseMove(tc.notation)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v", tc.notation, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tc.notation, err)
+		} else if got != tc.want {
+			t.Errorf("%q: want %v, got %v", tc.notation, tc.want, got)
+		}
+	}
+}
+
+func TestMoveCounters(t *testing.T) {
+	board, err := NewBoardFromFEN("o5x/7/7/7/7/7/x5o o 0 1")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, s := range []string{"b6", "f6", "c5", "e5"} {
+		move, err := board.ParseMove(s)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", s, err)
+		}
+		board.Move(move)
+	}
+	if got, want := board.FEN(), "o5x/1o3x1/2o1x2/7/7/7/x5o o 4 3"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+
+	// a capture resets the halfmove clock
+	move, err := board.ParseMove("d5")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	board.Move(move)
+	if got, want := board.FEN(), "o5x/1o3x1/2ooo2/7/7/7/x5o x 0 4"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
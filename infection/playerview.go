@@ -19,20 +19,6 @@ type PlayerView struct {
 	activeColor    color.Color
 }
 
-type namedEngine struct {
-	name   string
-	engine Engine
-}
-
-var namedEngines = []namedEngine{
-	{"Human", &HumanEngine{}},
-	{"Random", &RandomEngine{}},
-	{"Greedy", &GreedyEngine{}},
-	{"Minimax2", &MinimaxEngine{maxDepth: 2}},
-	{"Minimax3", &MinimaxEngine{maxDepth: 3}},
-	{"Minimax4", &MinimaxEngine{maxDepth: 4}},
-}
-
 const (
 	pvSpacing    = 15
 	pvLabelWidth = 55
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// GameRecord is the list of moves played from a starting position.  It
+// keeps a cursor so moves can be undone and redone; making a new move
+// when the cursor isn't at the end discards the moves after it.
+type GameRecord struct {
+	whiteName string
+	blackName string
+	date      time.Time
+	start     *Board
+	moves     []Move
+	current   int
+}
+
+func NewGameRecord(start *Board, whiteName, blackName string) *GameRecord {
+	return &GameRecord{
+		whiteName: whiteName,
+		blackName: blackName,
+		date:      time.Now(),
+		start:     start.Copy(),
+		moves:     []Move{},
+		current:   0,
+	}
+}
+
+// Board returns the position at the cursor.
+func (r *GameRecord) Board() *Board {
+	b := r.start.Copy()
+	for _, m := range r.moves[:r.current] {
+		b.Move(m)
+	}
+	return b
+}
+
+// Add plays a move at the cursor, dropping any moves that had been undone.
+func (r *GameRecord) Add(m Move) {
+	r.moves = append(r.moves[:r.current], m)
+	r.current++
+}
+
+func (r *GameRecord) CanUndo() bool { return r.current > 0 }
+func (r *GameRecord) CanRedo() bool { return r.current < len(r.moves) }
+
+// AtEnd is true if no moves have been undone, so the game can continue.
+func (r *GameRecord) AtEnd() bool { return r.current == len(r.moves) }
+
+func (r *GameRecord) Undo() bool {
+	if !r.CanUndo() {
+		return false
+	}
+	r.current--
+	return true
+}
+
+func (r *GameRecord) Redo() bool {
+	if !r.CanRedo() {
+		return false
+	}
+	r.current++
+	return true
+}
+
+// GoToStart moves the cursor before the first move, for replaying a game.
+func (r *GameRecord) GoToStart() {
+	r.current = 0
+}
+
+// outcomeAfter plays the first n moves and reports whether the game is
+// over at that point, including by threefold repetition.
+func (r *GameRecord) outcomeAfter(n int) (Outcome, bool) {
+	b := r.start.Copy()
+	seen := positionCounts{}
+	seen.add(b)
+	repeated := false
+	for _, m := range r.moves[:n] {
+		b.Move(m)
+		repeated = seen.add(b)
+	}
+	outcome, over := b.IsGameOver()
+	if !over && repeated {
+		outcome.reason = Repetition
+		over = true
+	}
+	return outcome, over
+}
+
+// IsGameOver reports whether the game is over at the cursor, and if so how.
+func (r *GameRecord) IsGameOver() (Outcome, bool) {
+	return r.outcomeAfter(r.current)
+}
+
+// Result gives the outcome of the full game as "1-0" (white wins),
+// "0-1" (black wins), "1/2-1/2" (draw) or "*" (unfinished).
+func (r *GameRecord) Result() string {
+	outcome, over := r.outcomeAfter(len(r.moves))
+	if !over {
+		return "*"
+	}
+	return outcome.Result()
+}
+
+// String writes the whole game in a format modeled on chess's PGN: a few
+// tag lines followed by the numbered moves and the result.
+func (r *GameRecord) String() string {
+	result := r.Result()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[Event \"Infection\"]\n")
+	fmt.Fprintf(&sb, "[Date \"%s\"]\n", r.date.Format("2006.01.02"))
+	fmt.Fprintf(&sb, "[White \"%s\"]\n", r.whiteName)
+	fmt.Fprintf(&sb, "[Black \"%s\"]\n", r.blackName)
+	fmt.Fprintf(&sb, "[FEN \"%s\"]\n", r.start.FEN())
+	fmt.Fprintf(&sb, "[Result \"%s\"]\n", result)
+	sb.WriteString("\n")
+
+	b := r.start.Copy()
+	tokens := []string{}
+	for i, m := range r.moves {
+		if b.playerToMove == White {
+			tokens = append(tokens, fmt.Sprintf("%d.", b.fullmoveNumber))
+		} else if i == 0 {
+			tokens = append(tokens, fmt.Sprintf("%d...", b.fullmoveNumber))
+		}
+		tokens = append(tokens, b.FormatMove(m))
+		b.Move(m)
+	}
+	tokens = append(tokens, result)
+
+	// wrap lines like PGN does
+	lineLen := 0
+	for i, tok := range tokens {
+		if i > 0 {
+			if lineLen+1+len(tok) > 79 {
+				sb.WriteString("\n")
+				lineLen = 0
+			} else {
+				sb.WriteString(" ")
+				lineLen++
+			}
+		}
+		sb.WriteString(tok)
+		lineLen += len(tok)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+var tagRegexp = regexp.MustCompile(`^\[(\w+)\s+"(.*)"\]$`)
+var moveNumberRegexp = regexp.MustCompile(`^\d+\.+$`)
+
+// ParseGameRecord reads a game written by GameRecord.String.  The cursor
+// is left at the end of the game.
+func ParseGameRecord(text string) (*GameRecord, error) {
+	tags := map[string]string{}
+	movetext := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if match := tagRegexp.FindStringSubmatch(line); match != nil {
+			tags[match[1]] = match[2]
+		} else if line != "" {
+			movetext = append(movetext, line)
+		}
+	}
+
+	start, err := NewBoardFromFEN(StartFEN)
+	if fen, ok := tags["FEN"]; ok {
+		start, err = NewBoardFromFEN(fen)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewGameRecord(start, tags["White"], tags["Black"])
+	if date, err := time.Parse("2006.01.02", tags["Date"]); err == nil {
+		r.date = date
+	}
+
+	b := start.Copy()
+	for _, tok := range strings.Fields(strings.Join(movetext, " ")) {
+		if moveNumberRegexp.MatchString(tok) {
+			continue
+		}
+		switch tok {
+		case "1-0", "0-1", "1/2-1/2", "*":
+			return r, nil
+		}
+		// allow the number to be attached, as in "12.b6"
+		if i := strings.LastIndex(tok, "."); i >= 0 {
+			tok = tok[i+1:]
+		}
+		m, err := b.ParseMove(tok)
+		if err != nil {
+			return nil, fmt.Errorf("move %d: %v", len(r.moves)+1, err)
+		}
+		b.Move(m)
+		r.Add(m)
+	}
+	return r, nil
+}
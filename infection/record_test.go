@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func playMoves(t *testing.T, r *GameRecord, moves ...string) {
+	for _, s := range moves {
+		b := r.Board()
+		m, err := b.ParseMove(s)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", s, err)
+		}
+		r.Add(m)
+	}
+}
+
+func TestGameRecordUndoRedo(t *testing.T) {
+	start := NewBoard(StandardGeometry)
+	r := NewGameRecord(start, "Human", "Greedy")
+	playMoves(t, r, "b6", "f6", "c5")
+	afterThree := r.Board()
+
+	if !r.Undo() || !r.Undo() {
+		t.Fatalf("expected undo to succeed")
+	}
+	if r.AtEnd() {
+		t.Errorf("expected not to be at end after undo")
+	}
+	if got := r.Board().FEN(); got != "o5x/1o5/7/7/7/7/x5o x 1 2" {
+		t.Errorf("after undo got %s", got)
+	}
+	if !r.Redo() || !r.Redo() || r.Redo() {
+		t.Fatalf("expected exactly two redos")
+	}
+	if !reflect.DeepEqual(r.Board(), afterThree) {
+		t.Errorf("redo did not restore position")
+	}
+
+	// a new move after undo discards the undone moves
+	r.Undo()
+	r.Undo()
+	playMoves(t, r, "b2")
+	if !r.AtEnd() || len(r.moves) != 2 {
+		t.Errorf("expected branch to replace undone moves, have %d moves", len(r.moves))
+	}
+
+	r.GoToStart()
+	if r.CanUndo() || !reflect.DeepEqual(r.Board(), start) {
+		t.Errorf("expected to be at start")
+	}
+}
+
+func TestGameRecordRoundTrip(t *testing.T) {
+	r := NewGameRecord(NewBoard(CrossGeometry(7)), "Human", "Minimax3")
+	board := r.Board()
+	engine := GreedyEngine{}
+	for len(r.moves) < 200 {
+		if _, over := r.IsGameOver(); over {
+			break
+		}
+		m := engine.GenMove(context.Background(), board)
+		r.Add(m)
+		board.Move(m)
+	}
+
+	text := r.String()
+	parsed, err := ParseGameRecord(text)
+	if err != nil {
+		t.Fatalf("unexpected error %v\n%s", err, text)
+	}
+	if parsed.whiteName != "Human" || parsed.blackName != "Minimax3" {
+		t.Errorf("names not preserved: %q %q", parsed.whiteName, parsed.blackName)
+	}
+	if parsed.Result() != r.Result() {
+		t.Errorf("want result %s, got %s", r.Result(), parsed.Result())
+	}
+	if !reflect.DeepEqual(parsed.Board(), r.Board()) {
+		t.Errorf("final positions differ:\n%s\n%s", r.Board().String(), parsed.Board().String())
+	}
+	if parsed.String() != text {
+		t.Errorf("want\n%s\ngot\n%s", text, parsed.String())
+	}
+}
+
+func TestParseGameRecord(t *testing.T) {
+	text := `[Event "Infection"]
+[White "a"]
+[Black "b"]
+[FEN "x5o/7/7/7/7/7/o5x x 0 1"]
+
+1. b6 g7e5 2. f2 *`
+	r, err := ParseGameRecord(text)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := r.Board().FEN(); got != "x6/1x5/4o2/7/7/5x1/o5x o 3 2" {
+		t.Errorf("got %s", got)
+	}
+
+	r, err = ParseGameRecord("1.b6 b2 2.c5")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(r.moves) != 3 {
+		t.Errorf("want 3 moves, got %d", len(r.moves))
+	}
+
+	if _, err := ParseGameRecord("1. b6 b6"); err == nil {
+		t.Errorf("expected error for illegal move")
+	}
+}
+
+func TestGameRecordRepetition(t *testing.T) {
+	start, err := NewBoardFromFEN("x5o/7/7/7/7/7/o5x x 0 1")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	r := NewGameRecord(start, "a", "b")
+	// both sides jump back and forth, so the start comes up again every 4 moves
+	shuffle := []string{"a7a5", "g7g5", "a5a7", "g5g7"}
+	for i := 0; i < 2; i++ {
+		if _, over := r.IsGameOver(); over {
+			t.Fatalf("game over too early, after %d moves", len(r.moves))
+		}
+		playMoves(t, r, shuffle...)
+	}
+
+	outcome, over := r.IsGameOver()
+	if !over || outcome.reason != Repetition {
+		t.Fatalf("want repetition, got %v (over=%v)", outcome.reason, over)
+	}
+	if got := r.Result(); got != "1/2-1/2" {
+		t.Errorf("want draw, got %s", got)
+	}
+
+	r.Undo()
+	if _, over := r.IsGameOver(); over {
+		t.Errorf("expected game to continue after undo")
+	}
+}
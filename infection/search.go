@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"math/bits"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// Zobrist keys for hashing positions.  A fixed seed keeps hashes stable
+// between runs, which makes searches reproducible.
+var zobristWhite [MaxSquares]uint64
+var zobristBlack [MaxSquares]uint64
+var zobristBlackToMove uint64
+
+func init() {
+	r := rand.New(rand.NewSource(20250101))
+	for i := range MaxSquares {
+		zobristWhite[i] = r.Uint64()
+		zobristBlack[i] = r.Uint64()
+	}
+	zobristBlackToMove = r.Uint64()
+}
+
+func (b *Board) Hash() uint64 {
+	var h uint64
+	for w := b.white; w != 0; w &= w - 1 {
+		h ^= zobristWhite[bits.TrailingZeros64(uint64(w))]
+	}
+	for k := b.black; k != 0; k &= k - 1 {
+		h ^= zobristBlack[bits.TrailingZeros64(uint64(k))]
+	}
+	if b.playerToMove == Black {
+		h ^= zobristBlackToMove
+	}
+	return h
+}
+
+type ttFlag uint8
+
+const (
+	ttExact ttFlag = iota
+	ttLowerBound
+	ttUpperBound
+)
+
+type ttEntry struct {
+	key      uint64
+	bestMove Move
+	score    int32
+	depth    int8
+	flag     ttFlag
+	valid    bool
+}
+
+const ttSize = 1 << 17
+
+type transpositionTable []ttEntry
+
+func newTranspositionTable() transpositionTable {
+	return make(transpositionTable, ttSize)
+}
+
+func (tt transpositionTable) probe(key uint64) (ttEntry, bool) {
+	e := tt[key%ttSize]
+	return e, e.valid && e.key == key
+}
+
+func (tt transpositionTable) store(key uint64, depth int, score int, flag ttFlag, bestMove Move) {
+	e := &tt[key%ttSize]
+	// depth-preferred replacement, but always replace stale positions
+	if e.valid && e.key == key && int(e.depth) > depth {
+		return
+	}
+	*e = ttEntry{
+		key:      key,
+		bestMove: bestMove,
+		score:    int32(score),
+		depth:    int8(depth),
+		flag:     flag,
+		valid:    true,
+	}
+}
+
+const (
+	// WinScore is added to the piece differential when a game is over,
+	// so that any finished game is preferred over an unfinished one.
+	WinScore = 10000
+	infinity = 1 << 30
+
+	maxSearchDepth = 64
+	// how many nodes to search between checks of the clock
+	timeCheckInterval = 1024
+)
+
+// SearchResult holds the outcome of a search from the point of view
+// of the player to move.
+type SearchResult struct {
+	bestMove Move
+	score    int
+	depth    int
+	numEvals int
+	// the expected line of play, starting with bestMove
+	pv []Move
+}
+
+type searcher struct {
+	ctx      context.Context
+	tt       transpositionTable
+	numEvals int
+	timeUp   bool
+}
+
+// evaluate scores a board from the point of view of the player to move.
+func evaluate(b *Board) int {
+	outcome, over := b.IsGameOver()
+	if !over {
+		return ScoreForPlayer(b, b.playerToMove)
+	}
+	score := outcome.PieceDiff()
+	if b.playerToMove == Black {
+		score = -score
+	}
+	if score > 0 {
+		return WinScore + score
+	} else if score < 0 {
+		return -WinScore + score
+	}
+	return 0
+}
+
+// numCaptures counts how many opposing pieces a move would infect.
+func (b *Board) numCaptures(m Move) int {
+	if m.pass {
+		return 0
+	}
+	opponent := b.black
+	if b.playerToMove == Black {
+		opponent = b.white
+	}
+	return (opponent & b.geom.adjacent[m.to]).GetNumSetBits()
+}
+
+// orderMoves sorts moves so the ones most likely to be good are searched
+// first: the hash move, then by most captures, then steps before jumps.
+func orderMoves(b *Board, moves []Move, hashMove Move, hasHashMove bool) {
+	priority := func(m Move) int {
+		if hasHashMove && m == hashMove {
+			return 1000
+		}
+		p := 2 * b.numCaptures(m)
+		if !m.jump {
+			p++
+		}
+		return p
+	}
+	slices.SortStableFunc(moves, func(m1, m2 Move) int {
+		return priority(m2) - priority(m1)
+	})
+}
+
+func (s *searcher) outOfTime() bool {
+	if s.numEvals%timeCheckInterval == 0 && s.ctx.Err() != nil {
+		s.timeUp = true
+	}
+	return s.timeUp
+}
+
+// negamax returns the score of the board from the point of view of the
+// player to move.  If the search runs out of time the result is meaningless
+// and timeUp is set.
+func (s *searcher) negamax(board *Board, depth int, alpha int, beta int) int {
+	s.numEvals++
+	if s.outOfTime() {
+		return 0
+	}
+	if _, over := board.IsGameOver(); depth == 0 || over {
+		return evaluate(board)
+	}
+
+	key := board.Hash()
+	origAlpha := alpha
+	entry, found := s.tt.probe(key)
+	if found && int(entry.depth) >= depth {
+		score := int(entry.score)
+		switch entry.flag {
+		case ttExact:
+			return score
+		case ttLowerBound:
+			alpha = max(alpha, score)
+		case ttUpperBound:
+			beta = min(beta, score)
+		}
+		if alpha >= beta {
+			return score
+		}
+	}
+
+	moves := board.GetLegalMoves()
+	orderMoves(board, moves, entry.bestMove, found)
+
+	bestScore := -infinity
+	bestMove := moves[0]
+	for _, move := range moves {
+		child := *board
+		child.Move(move)
+		score := -s.negamax(&child, depth-1, -beta, -alpha)
+		if s.timeUp {
+			return 0
+		}
+		if score > bestScore {
+			bestScore = score
+			bestMove = move
+		}
+		alpha = max(alpha, score)
+		if alpha >= beta {
+			break
+		}
+	}
+
+	flag := ttExact
+	if bestScore <= origAlpha {
+		flag = ttUpperBound
+	} else if bestScore >= beta {
+		flag = ttLowerBound
+	}
+	s.tt.store(key, depth, bestScore, flag, bestMove)
+	return bestScore
+}
+
+// searchRoot searches all moves at the root to the given depth.  If time runs
+// out partway through, the best move found so far is still returned, since
+// the previous iteration's best move is always searched first.
+func (s *searcher) searchRoot(board *Board, moves []Move, depth int) (bestMove Move, bestScore int, complete bool) {
+	alpha := -infinity
+	bestScore = -infinity
+	for _, move := range moves {
+		child := *board
+		child.Move(move)
+		score := -s.negamax(&child, depth-1, -infinity, -alpha)
+		if s.timeUp {
+			return bestMove, bestScore, false
+		}
+		if score > bestScore {
+			bestScore = score
+			bestMove = move
+		}
+		alpha = max(alpha, score)
+	}
+	return bestMove, bestScore, true
+}
+
+// principalVariation follows the best moves stored in the transposition
+// table after first, giving the line the search expects to be played.
+func (s *searcher) principalVariation(board *Board, first Move, depth int) []Move {
+	pv := []Move{first}
+	b := *board
+	b.Move(first)
+	seen := map[uint64]bool{}
+	for len(pv) < depth {
+		if _, over := b.IsGameOver(); over {
+			break
+		}
+		key := b.Hash()
+		entry, found := s.tt.probe(key)
+		// stop at a cycle of passes, or a move from a colliding position
+		if !found || seen[key] {
+			break
+		}
+		if valid, _ := entry.bestMove.IsValid(&b); !valid {
+			break
+		}
+		seen[key] = true
+		pv = append(pv, entry.bestMove)
+		b.Move(entry.bestMove)
+	}
+	return pv
+}
+
+// IterativeSearch runs an alpha-beta search with increasing depth until
+// ctx is done, returning the result of the deepest search.
+func IterativeSearch(ctx context.Context, board *Board) SearchResult {
+	return IterativeSearchWithInfo(ctx, board, nil)
+}
+
+// IterativeSearchWithInfo is IterativeSearch, but also calls info (if not
+// nil) with the result of each depth as it finishes.
+func IterativeSearchWithInfo(ctx context.Context, board *Board, info func(SearchResult)) SearchResult {
+	s := searcher{
+		ctx: ctx,
+		tt:  newTranspositionTable(),
+	}
+
+	moves := board.GetLegalMoves()
+	orderMoves(board, moves, Move{}, false)
+	result := SearchResult{bestMove: moves[0], score: evaluate(board), pv: moves[:1]}
+	if len(moves) == 1 {
+		if info != nil {
+			info(result)
+		}
+		return result
+	}
+
+	for depth := 1; depth <= maxSearchDepth; depth++ {
+		bestMove, bestScore, complete := s.searchRoot(board, moves, depth)
+		if complete || bestScore > -infinity {
+			result = SearchResult{
+				bestMove: bestMove,
+				score:    bestScore,
+				depth:    depth,
+				numEvals: s.numEvals,
+				pv:       s.principalVariation(board, bestMove, depth),
+			}
+			if info != nil {
+				info(result)
+			}
+		}
+		if !complete || bestScore >= WinScore || bestScore <= -WinScore {
+			break
+		}
+		// search the best move first in the next iteration
+		orderMoves(board, moves, bestMove, true)
+	}
+	result.numEvals = s.numEvals
+	return result
+}
+
+// IterativeDeepeningEngine searches for as long as timeLimit allows.
+type IterativeDeepeningEngine struct {
+	timeLimit time.Duration
+}
+
+func (e *IterativeDeepeningEngine) GenMove(ctx context.Context, board *Board) Move {
+	return e.GenMoveWithTime(ctx, board, e.timeLimit)
+}
+
+func (e *IterativeDeepeningEngine) GenMoveWithTime(ctx context.Context, board *Board, limit time.Duration) Move {
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+	result := IterativeSearch(ctx, board)
+	return result.bestMove
+}
+
+func (e *IterativeDeepeningEngine) RequiresHumanInput() bool {
+	return false
+}
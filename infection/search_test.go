@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHashDependsOnSideToMove(t *testing.T) {
+	board := NewBoard(StandardGeometry)
+	other := board.Copy()
+	other.playerToMove = Black
+	if board.Hash() == other.Hash() {
+		t.Errorf("expected different hashes for different side to move")
+	}
+
+	// Two move orders reaching the same position give the same hash.
+	b1 := NewBoard(StandardGeometry)
+	b1.Move(Move{from: b1.GetIndex(0, 0), to: b1.GetIndex(1, 1)})
+	b1.Move(Move{from: b1.GetIndex(0, 6), to: b1.GetIndex(1, 6)})
+	b1.Move(Move{from: b1.GetIndex(6, 6), to: b1.GetIndex(5, 5)})
+	b2 := NewBoard(StandardGeometry)
+	b2.Move(Move{from: b2.GetIndex(6, 6), to: b2.GetIndex(5, 5)})
+	b2.Move(Move{from: b2.GetIndex(0, 6), to: b2.GetIndex(1, 6)})
+	b2.Move(Move{from: b2.GetIndex(0, 0), to: b2.GetIndex(1, 1)})
+	if b1.Hash() != b2.Hash() {
+		t.Errorf("expected transposed positions to have the same hash")
+	}
+}
+
+func TestIterativeSearchFindsCapture(t *testing.T) {
+	board, err := NewBoardFromText(`
+		o . . . . . x
+		. o . . . . .
+		. . x x x . .
+		. . x . x . .
+		. . x x x . .
+		. . . . . . .
+		x . . . . . o`)
+	if err != nil {
+		t.Fatalf("Failed to create board from text: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	result := IterativeSearch(ctx, board)
+	want := board.GetIndex(3, 3)
+	if result.bestMove.to != want {
+		t.Errorf("want move to %d, got %v", want, result.bestMove)
+	}
+	if result.depth < 2 {
+		t.Errorf("expected search to reach depth 2, got %d", result.depth)
+	}
+}
+
+func TestIterativeSearchAgreesWithMinimax(t *testing.T) {
+	board := NewBoard(StandardGeometry)
+	board.Move(Move{from: board.GetIndex(0, 0), to: board.GetIndex(1, 1)})
+	board.Move(Move{from: board.GetIndex(0, 6), to: board.GetIndex(1, 5)})
+
+	_, minimaxScore, _ := minimax(context.Background(), board, 3, -infinity, infinity, board.playerToMove)
+	s := searcher{ctx: context.Background(), tt: newTranspositionTable()}
+	score := s.negamax(board, 3, -infinity, infinity)
+	if score != minimaxScore {
+		t.Errorf("negamax score %d, minimax score %d", score, minimaxScore)
+	}
+}
+
+func TestIterativeSearchStopsWhenCancelled(t *testing.T) {
+	board := NewBoard(StandardGeometry)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	result := IterativeSearch(ctx, board)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("search took %v after being cancelled", elapsed)
+	}
+	if valid, msg := result.bestMove.IsValid(board); !valid {
+		t.Errorf("expected a legal move, got %v: %s", result.bestMove, msg)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// This file runs round-robin matches between engines without the UI.
+// Every pair of engines plays each opening twice, once with each color,
+// and the games are spread over several goroutines.
+
+// maxGamePlies stops games where neither side makes progress; the
+// game is then scored on the pieces each side has.
+const maxGamePlies = 500
+
+// computerEngineNames lists the engines that can play without a human.
+func computerEngineNames() []string {
+	names := []string{}
+	for _, ne := range namedEngines {
+		if !ne.engine.RequiresHumanInput() {
+			names = append(names, ne.name)
+		}
+	}
+	return names
+}
+
+type TournamentConfig struct {
+	engineNames []string
+	openings    []*Board
+	numRounds   int
+	parallelism int
+}
+
+type tournamentGame struct {
+	white   string
+	black   string
+	opening *Board
+}
+
+// GameResult is the outcome of one game between two engines.
+type GameResult struct {
+	white string
+	black string
+	// white's score minus black's score at the end of the game
+	pieceDiff int
+}
+
+// PlayEngineGame plays a game between two engines from the given position,
+// returning white's score minus black's score at the end, or 0 for a draw.
+func PlayEngineGame(white, black Engine, start *Board) int {
+	board := start.Copy()
+	seen := positionCounts{}
+	seen.add(board)
+	for plies := 0; plies < maxGamePlies; plies++ {
+		if outcome, over := board.IsGameOver(); over {
+			return outcome.PieceDiff()
+		}
+		engine := white
+		if board.playerToMove == Black {
+			engine = black
+		}
+		board.Move(engine.GenMove(context.Background(), board))
+		if seen.add(board) {
+			return 0
+		}
+	}
+	w, b := board.Score()
+	return w - b
+}
+
+// DefaultOpenings gives the start positions of the fixed layouts, plus
+// numRandom positions reached by a few random moves from the standard start.
+func DefaultOpenings(numRandom int, seed int64) []*Board {
+	openings := []*Board{}
+	for _, layout := range namedLayouts {
+		if layout.name == "Holes" {
+			continue
+		}
+		openings = append(openings, NewBoard(layout.create()))
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	for range numRandom {
+		b := NewBoard(StandardGeometry)
+		for range 4 {
+			moves := b.GetLegalMoves()
+			b.Move(moves[r.Intn(len(moves))])
+		}
+		openings = append(openings, b)
+	}
+	return openings
+}
+
+func RunTournament(config TournamentConfig, out io.Writer) error {
+	engines := map[string]Engine{}
+	for _, name := range config.engineNames {
+		engine, err := findEngine(name)
+		if err != nil {
+			return err
+		}
+		if engine.RequiresHumanInput() {
+			return fmt.Errorf("engine %s needs a human", name)
+		}
+		engines[name] = engine
+	}
+
+	games := []tournamentGame{}
+	for i, name1 := range config.engineNames {
+		for _, name2 := range config.engineNames[i+1:] {
+			for _, opening := range config.openings {
+				for range config.numRounds {
+					games = append(games, tournamentGame{name1, name2, opening})
+					games = append(games, tournamentGame{name2, name1, opening})
+				}
+			}
+		}
+	}
+	fmt.Fprintf(out, "Playing %d games between %d engines\n", len(games), len(engines))
+
+	jobs := make(chan tournamentGame)
+	results := make(chan GameResult)
+	var wg sync.WaitGroup
+	for range max(config.parallelism, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for game := range jobs {
+				diff := PlayEngineGame(engines[game.white], engines[game.black], game.opening)
+				results <- GameResult{white: game.white, black: game.black, pieceDiff: diff}
+			}
+		}()
+	}
+	go func() {
+		for _, game := range games {
+			jobs <- game
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	stats := NewTournamentStats()
+	for result := range results {
+		stats.Add(result)
+		if stats.numGames%10 == 0 {
+			fmt.Fprintf(out, "  %d/%d games played\n", stats.numGames, len(games))
+		}
+	}
+	stats.Report(out, config.engineNames)
+	return nil
+}
+
+// MatchStats accumulates results from one engine's point of view.
+type MatchStats struct {
+	wins      int
+	draws     int
+	losses    int
+	pieceDiff int
+}
+
+func (s *MatchStats) add(pieceDiff int) {
+	switch {
+	case pieceDiff > 0:
+		s.wins++
+	case pieceDiff < 0:
+		s.losses++
+	default:
+		s.draws++
+	}
+	s.pieceDiff += pieceDiff
+}
+
+func (s *MatchStats) Games() int {
+	return s.wins + s.draws + s.losses
+}
+
+// Score is the fraction of points won, counting a draw as half a win.
+func (s *MatchStats) Score() float64 {
+	if s.Games() == 0 {
+		return 0
+	}
+	return (float64(s.wins) + 0.5*float64(s.draws)) / float64(s.Games())
+}
+
+func (s *MatchStats) AvgPieceDiff() float64 {
+	return float64(s.pieceDiff) / float64(s.Games())
+}
+
+// EloInterval estimates the Elo difference implied by the results, with a
+// 95% confidence interval from the standard error of the score.
+func (s *MatchStats) EloInterval() (elo, low, high float64) {
+	n := float64(s.Games())
+	p := s.Score()
+	// per-game variance of the score, which accounts for draws
+	w, d, l := float64(s.wins)/n, float64(s.draws)/n, float64(s.losses)/n
+	variance := w*(1-p)*(1-p) + d*(0.5-p)*(0.5-p) + l*p*p
+	margin := 1.96 * math.Sqrt(variance/n)
+	return scoreToElo(p), scoreToElo(p - margin), scoreToElo(p + margin)
+}
+
+// scoreToElo converts an expected score into an Elo difference.
+func scoreToElo(p float64) float64 {
+	if p <= 0 {
+		return math.Inf(-1)
+	}
+	if p >= 1 {
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/p-1)
+}
+
+type TournamentStats struct {
+	numGames int
+	// overall results for each engine
+	overall map[string]*MatchStats
+	// results for the first engine against the second
+	headToHead map[[2]string]*MatchStats
+}
+
+func NewTournamentStats() *TournamentStats {
+	return &TournamentStats{
+		overall:    map[string]*MatchStats{},
+		headToHead: map[[2]string]*MatchStats{},
+	}
+}
+
+func (t *TournamentStats) get(m map[string]*MatchStats, name string) *MatchStats {
+	if _, ok := m[name]; !ok {
+		m[name] = &MatchStats{}
+	}
+	return m[name]
+}
+
+func (t *TournamentStats) getPair(name1, name2 string) *MatchStats {
+	key := [2]string{name1, name2}
+	if _, ok := t.headToHead[key]; !ok {
+		t.headToHead[key] = &MatchStats{}
+	}
+	return t.headToHead[key]
+}
+
+func (t *TournamentStats) Add(r GameResult) {
+	t.numGames++
+	t.get(t.overall, r.white).add(r.pieceDiff)
+	t.get(t.overall, r.black).add(-r.pieceDiff)
+	t.getPair(r.white, r.black).add(r.pieceDiff)
+	t.getPair(r.black, r.white).add(-r.pieceDiff)
+}
+
+func formatElo(elo float64) string {
+	if math.IsInf(elo, 0) {
+		if elo > 0 {
+			return "+inf"
+		}
+		return "-inf"
+	}
+	return fmt.Sprintf("%+.0f", elo)
+}
+
+// Report prints a table of results for each engine, with Elo relative to
+// the average of its opponents, followed by each head-to-head match.
+func (t *TournamentStats) Report(out io.Writer, names []string) {
+	sorted := append([]string{}, names...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return t.get(t.overall, sorted[i]).Score() > t.get(t.overall, sorted[j]).Score()
+	})
+
+	fmt.Fprintf(out, "\n%-10s %6s %5s %5s %5s %7s %8s %6s %16s\n",
+		"Engine", "Games", "Win", "Draw", "Loss", "Score", "AvgDiff", "Elo", "95% CI")
+	fmt.Fprintln(out, strings.Repeat("-", 78))
+	for _, name := range sorted {
+		s := t.get(t.overall, name)
+		if s.Games() == 0 {
+			continue
+		}
+		elo, low, high := s.EloInterval()
+		fmt.Fprintf(out, "%-10s %6d %5d %5d %5d %6.1f%% %+8.2f %6s %16s\n",
+			name, s.Games(), s.wins, s.draws, s.losses, 100*s.Score(), s.AvgPieceDiff(),
+			formatElo(elo), fmt.Sprintf("[%s, %s]", formatElo(low), formatElo(high)))
+	}
+
+	fmt.Fprintf(out, "\n%-21s %6s %13s %8s %6s %16s\n", "Match", "Games", "W-D-L", "AvgDiff", "Elo", "95% CI")
+	fmt.Fprintln(out, strings.Repeat("-", 75))
+	for i, name1 := range sorted {
+		for _, name2 := range sorted[i+1:] {
+			s := t.getPair(name1, name2)
+			if s.Games() == 0 {
+				continue
+			}
+			elo, low, high := s.EloInterval()
+			fmt.Fprintf(out, "%-21s %6d %13s %+8.2f %6s %16s\n",
+				name1+" v "+name2, s.Games(), fmt.Sprintf("%d-%d-%d", s.wins, s.draws, s.losses),
+				s.AvgPieceDiff(), formatElo(elo), fmt.Sprintf("[%s, %s]", formatElo(low), formatElo(high)))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestScoreToElo(t *testing.T) {
+	type TestCase struct {
+		score float64
+		want  float64
+	}
+	testCases := []TestCase{
+		{0.5, 0},
+		{0.75, 190.85},
+		{0.25, -190.85},
+		{0, math.Inf(-1)},
+		{1, math.Inf(1)},
+	}
+	for _, tc := range testCases {
+		got := scoreToElo(tc.score)
+		if math.IsInf(tc.want, 0) {
+			if got != tc.want {
+				t.Errorf("score %v: want %v, got %v", tc.score, tc.want, got)
+			}
+		} else if math.Abs(got-tc.want) > 0.01 {
+			t.Errorf("score %v: want %v, got %v", tc.score, tc.want, got)
+		}
+	}
+}
+
+func TestTournamentStats(t *testing.T) {
+	stats := NewTournamentStats()
+	stats.Add(GameResult{white: "A", black: "B", pieceDiff: 10})
+	stats.Add(GameResult{white: "B", black: "A", pieceDiff: -4})
+	stats.Add(GameResult{white: "A", black: "B", pieceDiff: 0})
+	stats.Add(GameResult{white: "B", black: "A", pieceDiff: 6})
+
+	a := stats.getPair("A", "B")
+	if a.wins != 2 || a.draws != 1 || a.losses != 1 {
+		t.Errorf("want 2-1-1, got %d-%d-%d", a.wins, a.draws, a.losses)
+	}
+	if a.Score() != 0.625 {
+		t.Errorf("want score 0.625, got %v", a.Score())
+	}
+	if a.AvgPieceDiff() != 2 {
+		t.Errorf("want average diff 2, got %v", a.AvgPieceDiff())
+	}
+	elo, low, high := a.EloInterval()
+	if !(low < elo && elo < high) {
+		t.Errorf("expected %v in (%v, %v)", elo, low, high)
+	}
+	b := stats.get(stats.overall, "B")
+	if b.wins != a.losses || b.losses != a.wins {
+		t.Errorf("results for B should mirror A")
+	}
+}
+
+func TestRunTournament(t *testing.T) {
+	config := TournamentConfig{
+		engineNames: []string{"Random", "Greedy", "Minimax2"},
+		openings:    DefaultOpenings(1, 1)[:2],
+		numRounds:   1,
+		parallelism: 4,
+	}
+	var out bytes.Buffer
+	if err := RunTournament(config, &out); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// 3 pairs, 2 openings, 2 colors
+	if !strings.Contains(out.String(), "Playing 12 games") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+	for _, name := range config.engineNames {
+		if !strings.Contains(out.String(), name+" ") {
+			t.Errorf("no results for %s:\n%s", name, out.String())
+		}
+	}
+
+	config.engineNames = []string{"Human", "Random"}
+	if err := RunTournament(config, &out); err == nil {
+		t.Errorf("expected error for human engine")
+	}
+}
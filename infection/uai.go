@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file implements the UAI protocol, the Ataxx version of the UCI chess
+// engine protocol.  RunUAI lets any Engine be driven by an external program
+// such as a tournament manager, and ExternalEngine lets an external UAI
+// engine play in this program.
+
+const (
+	// time used when "go" gives no time control
+	defaultMoveTime = 1 * time.Second
+	// fraction of the remaining clock to spend on one move
+	clockFraction = 30
+	// time used for "go infinite"; the search is expected to be stopped
+	infiniteMoveTime = 24 * time.Hour
+)
+
+var (
+	// how long an external engine has to answer "uai"
+	handshakeTimeout = 10 * time.Second
+	// extra time an external engine has to answer after a search should
+	// have finished
+	replySlack = 5 * time.Second
+)
+
+var errEngineTimeout = errors.New("timed out")
+
+// uaiSession holds the state of RunUAI.  Searches run in the background so
+// that "stop" can be read while the engine is thinking.
+type uaiSession struct {
+	engine Engine
+	board  *Board
+
+	// guards out, which the search goroutine writes "bestmove" to
+	mu  sync.Mutex
+	out io.Writer
+
+	cancelSearch context.CancelFunc
+	searchDone   chan struct{}
+}
+
+func (s *uaiSession) printf(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.out, format, args...)
+}
+
+// startSearch looks for a move in the background and prints it when done.
+func (s *uaiSession) startSearch(args []string) {
+	s.stopSearch()
+	limit := parseGoCommand(args, s.board.playerToMove)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.cancelSearch = cancel
+	s.searchDone = done
+
+	board := s.board.Copy()
+	go func() {
+		defer close(done)
+		var move Move
+		if timed, ok := s.engine.(TimeLimitedEngine); ok {
+			move = timed.GenMoveWithTime(ctx, board, limit)
+		} else {
+			move = s.engine.GenMove(ctx, board)
+		}
+		s.printf("bestmove %s\n", board.FormatMove(move))
+	}()
+}
+
+// stopSearch cuts short any search in progress and waits for its result.
+func (s *uaiSession) stopSearch() {
+	if s.cancelSearch == nil {
+		return
+	}
+	s.cancelSearch()
+	<-s.searchDone
+	s.cancelSearch = nil
+	s.searchDone = nil
+}
+
+// waitForSearch waits for any search in progress to finish by itself.
+func (s *uaiSession) waitForSearch() {
+	if s.searchDone != nil {
+		<-s.searchDone
+	}
+	s.stopSearch()
+}
+
+// RunUAI reads UAI commands from in and writes responses to out, using
+// engine to choose moves.  It returns when it reads "quit" or in is closed.
+func RunUAI(engine Engine, name string, in io.Reader, out io.Writer) error {
+	board, _ := NewBoardFromFEN(StartFEN)
+	s := &uaiSession{engine: engine, board: board, out: out}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uai":
+			s.printf("id name %s\n", name)
+			s.printf("id author jonathanacross\n")
+			s.printf("uaiok\n")
+		case "isready":
+			s.printf("readyok\n")
+		case "uainewgame":
+			s.stopSearch()
+			s.board, _ = NewBoardFromFEN(StartFEN)
+		case "position":
+			s.stopSearch()
+			b, err := parsePositionCommand(fields[1:])
+			if err != nil {
+				s.printf("info string %v\n", err)
+				continue
+			}
+			s.board = b
+		case "go":
+			s.startSearch(fields[1:])
+		case "stop":
+			s.stopSearch()
+		case "d":
+			s.printf("%s%s\n", DrawBoard(s.board), s.board.FEN())
+		case "quit":
+			s.stopSearch()
+			return nil
+		default:
+			s.printf("info string unknown command %s\n", fields[0])
+		}
+	}
+	// let a search started just before the input ended report its move
+	s.waitForSearch()
+	return scanner.Err()
+}
+
+// parsePositionCommand parses the arguments of
+// "position (startpos | fen <fen>) [moves <move>...]".
+func parsePositionCommand(args []string) (*Board, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("position: missing arguments")
+	}
+
+	movesIdx := len(args)
+	for i, arg := range args {
+		if arg == "moves" {
+			movesIdx = i
+			break
+		}
+	}
+
+	var board *Board
+	var err error
+	switch args[0] {
+	case "startpos":
+		board, err = NewBoardFromFEN(StartFEN)
+	case "fen":
+		board, err = NewBoardFromFEN(strings.Join(args[1:movesIdx], " "))
+	default:
+		err = fmt.Errorf("position: expected startpos or fen, got %s", args[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range args[min(movesIdx+1, len(args)):] {
+		move, err := board.ParseMove(s)
+		if err != nil {
+			return nil, fmt.Errorf("position: %v", err)
+		}
+		board.Move(move)
+	}
+	return board, nil
+}
+
+// parseGoCommand works out how long to think from the arguments of "go".
+func parseGoCommand(args []string, player Player) time.Duration {
+	values := map[string]time.Duration{}
+	for i := 0; i+1 < len(args); i++ {
+		if ms, err := strconv.Atoi(args[i+1]); err == nil {
+			values[args[i]] = time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	if movetime, ok := values["movetime"]; ok {
+		return movetime
+	}
+	if slices.Contains(args, "infinite") {
+		// think until told to stop
+		return infiniteMoveTime
+	}
+	remaining, ok := values["wtime"]
+	increment := values["winc"]
+	if player == Black {
+		remaining, ok = values["btime"]
+		increment = values["binc"]
+	}
+	if ok {
+		return max(remaining/clockFraction+increment/2, time.Millisecond)
+	}
+	return defaultMoveTime
+}
+
+// ExternalEngine runs a UAI engine as a subprocess.  The process is started
+// the first time a move is needed.  Requests from different goroutines are
+// handled one at a time.
+type ExternalEngine struct {
+	command  string
+	args     []string
+	moveTime time.Duration
+
+	mu    sync.Mutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string
+}
+
+func NewExternalEngine(command string, args []string, moveTime time.Duration) *ExternalEngine {
+	return &ExternalEngine{
+		command:  command,
+		args:     args,
+		moveTime: moveTime,
+	}
+}
+
+func (e *ExternalEngine) start() error {
+	cmd := exec.Command(e.command, e.args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	lines := make(chan string)
+	e.cmd = cmd
+	e.stdin = stdin
+	e.lines = lines
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	e.send("uai")
+	if _, err := e.waitFor(context.Background(), "uaiok", handshakeTimeout); err != nil {
+		e.kill()
+		return err
+	}
+	return nil
+}
+
+// kill stops an engine that isn't answering properly, so that the next
+// request starts a fresh one.
+func (e *ExternalEngine) kill() {
+	if e.cmd == nil {
+		return
+	}
+	e.cmd.Process.Kill()
+	// let the reader finish with any output the engine already wrote
+	go func(lines chan string) {
+		for range lines {
+		}
+	}(e.lines)
+	e.cmd.Wait()
+	e.cmd = nil
+	e.stdin = nil
+	e.lines = nil
+}
+
+func (e *ExternalEngine) send(command string) error {
+	_, err := fmt.Fprintln(e.stdin, command)
+	return err
+}
+
+// waitFor reads lines from the engine until one starts with prefix.
+func (e *ExternalEngine) waitFor(ctx context.Context, prefix string, timeout time.Duration) (string, error) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case line, ok := <-e.lines:
+			if !ok {
+				return "", fmt.Errorf("engine %s exited", e.command)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line, nil
+			}
+		case <-deadline:
+			return "", fmt.Errorf("engine %s: %w waiting for %s", e.command, errEngineTimeout, prefix)
+		}
+	}
+}
+
+func (e *ExternalEngine) genMove(ctx context.Context, board *Board, limit time.Duration) (Move, error) {
+	if e.cmd == nil {
+		if err := e.start(); err != nil {
+			return Move{}, err
+		}
+	}
+	if err := e.send("position fen " + board.FEN()); err != nil {
+		return Move{}, err
+	}
+	if err := e.send(fmt.Sprintf("go movetime %d", limit.Milliseconds())); err != nil {
+		return Move{}, err
+	}
+	// allow for a slow engine, but don't hang forever
+	line, err := e.waitFor(ctx, "bestmove", 2*limit+replySlack)
+	if ctx.Err() != nil || errors.Is(err, errEngineTimeout) {
+		// the engine still sends a bestmove after stop; read it so it
+		// isn't mistaken for the answer to the next search
+		e.send("stop")
+		line, err = e.waitFor(context.Background(), "bestmove", replySlack)
+		if err != nil {
+			// a late reply could still arrive, so start again
+			e.kill()
+			return Move{}, err
+		}
+	}
+	if err != nil {
+		return Move{}, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return Move{}, fmt.Errorf("engine %s: malformed reply %q", e.command, line)
+	}
+	return board.ParseMove(fields[1])
+}
+
+func (e *ExternalEngine) GenMoveWithTime(ctx context.Context, board *Board, limit time.Duration) Move {
+	e.mu.Lock()
+	move, err := e.genMove(ctx, board, limit)
+	e.mu.Unlock()
+	if err != nil {
+		// fall back to something legal so the game can continue
+		log.Printf("external engine: %v; playing a random move", err)
+		return (&RandomEngine{}).GenMove(ctx, board)
+	}
+	return move
+}
+
+func (e *ExternalEngine) GenMove(ctx context.Context, board *Board) Move {
+	return e.GenMoveWithTime(ctx, board, e.moveTime)
+}
+
+func (e *ExternalEngine) RequiresHumanInput() bool {
+	return false
+}
+
+// Close asks the engine to quit and waits for it to exit.
+func (e *ExternalEngine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cmd == nil {
+		return nil
+	}
+	e.send("quit")
+	e.stdin.Close()
+	err := e.cmd.Wait()
+	e.cmd = nil
+	return err
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunUAI(t *testing.T) {
+	input := strings.Join([]string{
+		"uai",
+		"isready",
+		"position startpos moves g2 g7e5",
+		"go movetime 50",
+		"quit",
+	}, "\n")
+	var out bytes.Buffer
+	if err := RunUAI(&IterativeDeepeningEngine{timeLimit: time.Second}, "test", strings.NewReader(input), &out); err != nil {
+		t.Fatalf("RunUAI failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{"id name test", "id author jonathanacross", "uaiok", "readyok"}
+	for i, w := range want {
+		if lines[i] != w {
+			t.Errorf("line %d: want %q, got %q", i, w, lines[i])
+		}
+	}
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, "bestmove ") {
+		t.Fatalf("want bestmove, got %q", last)
+	}
+
+	board, err := parsePositionCommand(strings.Fields("startpos moves g2 g7e5"))
+	if err != nil {
+		t.Fatalf("parsePositionCommand failed: %v", err)
+	}
+	if _, err := board.ParseMove(strings.TrimPrefix(last, "bestmove ")); err != nil {
+		t.Errorf("engine played illegal move: %v", err)
+	}
+}
+
+func TestRunUAIStop(t *testing.T) {
+	input := strings.Join([]string{
+		"position startpos",
+		"go infinite",
+		"stop",
+	}, "\n")
+	var out bytes.Buffer
+	done := make(chan error)
+	go func() {
+		done <- RunUAI(&IterativeDeepeningEngine{timeLimit: time.Second}, "test", strings.NewReader(input), &out)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunUAI failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("search was not stopped")
+	}
+	if got := strings.TrimSpace(out.String()); !strings.HasPrefix(got, "bestmove ") {
+		t.Errorf("want bestmove, got %q", got)
+	}
+}
+
+func TestParsePositionCommand(t *testing.T) {
+	type TestCase struct {
+		name    string
+		args    string
+		wantFEN string
+		wantErr bool
+	}
+
+	testCases := []TestCase{
+		{
+			name:    "startpos",
+			args:    "startpos",
+			wantFEN: "x5o/7/7/7/7/7/o5x x 0 1",
+		},
+		{
+			name:    "startpos with moves",
+			args:    "startpos moves f1 g7e7",
+			wantFEN: "x3o2/7/7/7/7/7/o4xx x 2 2",
+		},
+		{
+			name:    "fen with moves",
+			args:    "fen x5o/7/7/7/7/7/o5x x 0 1 moves b6",
+			wantFEN: "x5o/1x5/7/7/7/7/o5x o 1 1",
+		},
+		{
+			name:    "illegal move",
+			args:    "startpos moves a1",
+			wantErr: true,
+		},
+		{
+			name:    "bad fen",
+			args:    "fen x5o/7/7 z",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		board, err := parsePositionCommand(strings.Fields(tc.args))
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		}
+		if got := board.FEN(); got != tc.wantFEN {
+			t.Errorf("%s: want %s, got %s", tc.name, tc.wantFEN, got)
+		}
+	}
+}
+
+func TestParseGoCommand(t *testing.T) {
+	if got := parseGoCommand(strings.Fields("movetime 250"), White); got != 250*time.Millisecond {
+		t.Errorf("movetime: got %v", got)
+	}
+	if got := parseGoCommand(strings.Fields("wtime 3000 btime 6000 winc 0 binc 0"), Black); got != 200*time.Millisecond {
+		t.Errorf("btime: got %v", got)
+	}
+	if got := parseGoCommand(nil, White); got != defaultMoveTime {
+		t.Errorf("no time control: got %v", got)
+	}
+}
+
+func TestExternalEngineHandshakeFails(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("needs sh")
+	}
+	defer func(old time.Duration) { handshakeTimeout = old }(handshakeTimeout)
+	handshakeTimeout = 100 * time.Millisecond
+
+	// an engine that never answers "uai"
+	e := NewExternalEngine("sh", []string{"-c", "exec sleep 30"}, 10*time.Millisecond)
+	board := NewBoard(StandardGeometry)
+	if _, err := e.genMove(context.Background(), board, e.moveTime); err == nil {
+		t.Fatal("want an error from an engine that doesn't answer")
+	}
+	if e.cmd != nil || e.stdin != nil || e.lines != nil {
+		t.Error("engine was left running after the handshake failed")
+	}
+}
+
+func TestExternalEngineSearchTimesOut(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("needs sh")
+	}
+	defer func(old time.Duration) { replySlack = old }(replySlack)
+	replySlack = 100 * time.Millisecond
+
+	// an engine that never sends bestmove, even after stop
+	e := NewExternalEngine("sh", []string{"-c", "read line; echo uaiok; exec sleep 30"}, 10*time.Millisecond)
+	board := NewBoard(StandardGeometry)
+	if _, err := e.genMove(context.Background(), board, e.moveTime); err == nil {
+		t.Fatal("want an error from an engine that doesn't answer")
+	}
+	if e.cmd != nil {
+		t.Error("engine was left running after the search timed out")
+	}
+}
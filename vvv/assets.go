@@ -88,10 +88,12 @@ func loadLevels(dir string) map[int]*tiled.Map {
 
 	loader := tiled.NewFsLoaderWithImageConverter(assets, ebitenImageConverter)
 	for _, entry := range dirEntries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
+		ext := filepath.Ext(entry.Name())
+		// Levels may be saved by Tiled as JSON (.json or .tmj) or XML (.tmx)
+		if !entry.IsDir() && (ext == ".json" || ext == ".tmj" || ext == ".tmx") {
 			name := entry.Name()
-			if len(name) > len("level.json") && name[:len("level")] == "level" {
-				numStr := name[len("level") : len(name)-len(".json")]
+			if len(name) > len("level"+ext) && name[:len("level")] == "level" {
+				numStr := name[len("level") : len(name)-len(ext)]
 				if num, err := strconv.Atoi(numStr); err == nil {
 					filePath := filepath.Join(dir, name)
 					level, err := loader.LoadMap(filePath)
@@ -1,12 +1,47 @@
 package main
 
 import (
+	"image"
+	"time"
+
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
 type Tile struct {
 	BaseSprite
 	solid bool
+	// Frames of an animated tile, if any
+	frames     []TileFrame
+	frame      int
+	frameTimer *Timer
+}
+
+// TileFrame is one frame of an animated tile.
+type TileFrame struct {
+	image    *ebiten.Image
+	srcRect  image.Rectangle
+	duration time.Duration
+}
+
+func (t *Tile) IsAnimated() bool {
+	return len(t.frames) > 0
+}
+
+func (t *Tile) showFrame(frame int) {
+	t.frame = frame
+	t.image = t.frames[frame].image
+	t.srcRect = t.frames[frame].srcRect
+	t.frameTimer = NewTimer(t.frames[frame].duration)
+}
+
+func (t *Tile) Update() {
+	if !t.IsAnimated() {
+		return
+	}
+	t.frameTimer.Update()
+	if t.frameTimer.IsReady() {
+		t.showFrame((t.frame + 1) % len(t.frames))
+	}
 }
 
 type Spike struct {
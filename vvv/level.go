@@ -9,12 +9,15 @@ import (
 )
 
 type Level struct {
-	tiles      []Tile
-	objects    []GameObject
-	width      float64
-	height     float64
-	startPoint Location
-	levelImage *ebiten.Image
+	tiles []Tile
+	// Indexes of the tiles that are animated, which are drawn each frame
+	// rather than into levelImage
+	animatedTiles []int
+	objects       []GameObject
+	width         float64
+	height        float64
+	startPoint    Location
+	levelImage    *ebiten.Image
 }
 
 func NewLevel(tm *tiled.Map, levelNum int) *Level {
@@ -22,19 +25,25 @@ func NewLevel(tm *tiled.Map, levelNum int) *Level {
 
 	levelImage := ebiten.NewImage(tm.WidthInTiles*tm.TileWidth, tm.HeightInTiles*tm.TileHeight)
 
-	// Get all tiles and draw them to the offscreen image.
+	// Get all tiles and draw the still ones to the offscreen image.
 	tiles := GetTiles(tm)
-	for _, tile := range tiles {
-		tile.Draw(levelImage)
+	animatedTiles := []int{}
+	for i := range tiles {
+		if tiles[i].IsAnimated() {
+			animatedTiles = append(animatedTiles, i)
+			continue
+		}
+		tiles[i].Draw(levelImage)
 	}
 
 	return &Level{
-		tiles:      tiles,
-		objects:    objects,
-		width:      float64(tm.WidthInTiles * tm.TileWidth),
-		height:     float64(tm.HeightInTiles * TileSize),
-		startPoint: startPoint,
-		levelImage: levelImage,
+		tiles:         tiles,
+		animatedTiles: animatedTiles,
+		objects:       objects,
+		width:         float64(tm.WidthInTiles * tm.TileWidth),
+		height:        float64(tm.HeightInTiles * TileSize),
+		startPoint:    startPoint,
+		levelImage:    levelImage,
 	}
 }
 
@@ -64,6 +73,9 @@ func (level *Level) Draw(screen *ebiten.Image, debug bool) {
 	// Draw the pre-rendered level image
 	op := &ebiten.DrawImageOptions{}
 	screen.DrawImage(level.levelImage, op)
+	for _, i := range level.animatedTiles {
+		level.tiles[i].Draw(screen)
+	}
 
 	// Draw dynamic objects (spikes, exits, checkpoints)
 	for _, object := range level.objects {
@@ -77,6 +89,9 @@ func (level *Level) Draw(screen *ebiten.Image, debug bool) {
 }
 
 func (level *Level) Update() {
+	for _, i := range level.animatedTiles {
+		level.tiles[i].Update()
+	}
 	for _, obj := range level.objects {
 		obj.Update()
 	}
@@ -25,6 +25,29 @@ func toImageRectangle(r tiled.Rect) image.Rectangle {
 	}
 }
 
+// flipHitbox returns where a hitbox in a w by h tile ends up when the tile
+// is flipped, to match flipGeoM.
+func flipHitbox(t tiled.Transform, r tiled.Rect, w float64, h float64) tiled.Rect {
+	if t.FlipDiag {
+		r = tiled.Rect{X: r.Y, Y: r.X, Width: r.Height, Height: r.Width}
+		w, h = h, w
+	}
+	if t.FlipHoriz {
+		r.X = w - r.X - r.Width
+	}
+	if t.FlipVert {
+		r.Y = h - r.Y - r.Height
+	}
+	return r
+}
+
+// objectHitbox returns the hitbox of the object's tile, flipped as the
+// object is and placed at the object's location.
+func objectHitbox(obj tiled.Object, tile tiled.Tile) Rect {
+	hitRect := flipHitbox(obj.Transform, tile.HitRect, tile.SrcRect.Width, tile.SrcRect.Height)
+	return toRect(hitRect).Offset(obj.Location.X, obj.Location.Y)
+}
+
 func getLocation(o tiled.Object) Location {
 	return Location{
 		X: o.Location.X,
@@ -77,10 +100,11 @@ func GetLevelObjects(tm *tiled.Map, levelNum int) ([]GameObject, Location) {
 func processSpikeObject(obj tiled.Object, tile tiled.Tile) *Spike {
 	return &Spike{
 		BaseSprite: BaseSprite{
-			Location: getLocation(obj),
-			image:    tile.SrcImage.(*ebiten.Image),
-			srcRect:  toImageRectangle(tile.SrcRect),
-			hitbox:   toRect(tile.HitRect).Offset(obj.Location.X, obj.Location.Y),
+			Location:  getLocation(obj),
+			image:     tile.SrcImage.(*ebiten.Image),
+			srcRect:   toImageRectangle(tile.SrcRect),
+			hitbox:    objectHitbox(obj, tile),
+			transform: obj.Transform,
 		},
 	}
 }
@@ -107,10 +131,11 @@ func processCheckpointObject(obj tiled.Object, tile tiled.Tile, levelNum int) *C
 	srcRect2 := spriteSheet.Rect(0)
 	checkpoint := Checkpoint{
 		BaseSprite: BaseSprite{
-			image:    CheckpointSprite,
-			Location: getLocation(obj),
-			srcRect:  srcRect2,
-			hitbox:   toRect(tile.HitRect).Offset(obj.Location.X, obj.Location.Y),
+			image:     CheckpointSprite,
+			Location:  getLocation(obj),
+			srcRect:   srcRect2,
+			hitbox:    objectHitbox(obj, tile),
+			transform: obj.Transform,
 		},
 		spriteSheet: spriteSheet,
 		Active:      isActive,
@@ -141,10 +166,11 @@ func processPlatformObject(obj tiled.Object, tile tiled.Tile) *Platform {
 
 	return &Platform{
 		BaseSprite: BaseSprite{
-			Location: getLocation(obj),
-			image:    tile.SrcImage.(*ebiten.Image),
-			srcRect:  toImageRectangle(tile.SrcRect),
-			hitbox:   toRect(tile.HitRect).Offset(obj.Location.X, obj.Location.Y),
+			Location:  getLocation(obj),
+			image:     tile.SrcImage.(*ebiten.Image),
+			srcRect:   toImageRectangle(tile.SrcRect),
+			hitbox:    objectHitbox(obj, tile),
+			transform: obj.Transform,
 		},
 		low:   low,
 		high:  high,
@@ -173,10 +199,11 @@ func processHelicopterMonsterObject(obj tiled.Object, tile tiled.Tile) *Helicopt
 
 	return &HelicopterMonster{
 		BaseSprite: BaseSprite{
-			Location: getLocation(obj),
-			image:    MonsterSprite,
-			srcRect:  toImageRectangle(tile.SrcRect),
-			hitbox:   toRect(tile.HitRect).Offset(obj.Location.X, obj.Location.Y),
+			Location:  getLocation(obj),
+			image:     MonsterSprite,
+			srcRect:   toImageRectangle(tile.SrcRect),
+			hitbox:    objectHitbox(obj, tile),
+			transform: obj.Transform,
 		},
 		spriteSheet: NewGridTileSet(16, 16, 2, 1),
 		animation:   NewAnimation(0, 1, 20),
@@ -190,10 +217,11 @@ func processHelicopterMonsterObject(obj tiled.Object, tile tiled.Tile) *Helicopt
 func processCrystalObject(obj tiled.Object, tile tiled.Tile) *Crystal {
 	return &Crystal{
 		BaseSprite: BaseSprite{
-			Location: getLocation(obj),
-			image:    tile.SrcImage.(*ebiten.Image),
-			srcRect:  toImageRectangle(tile.SrcRect),
-			hitbox:   toRect(tile.HitRect).Offset(obj.Location.X, obj.Location.Y),
+			Location:  getLocation(obj),
+			image:     tile.SrcImage.(*ebiten.Image),
+			srcRect:   toImageRectangle(tile.SrcRect),
+			hitbox:    objectHitbox(obj, tile),
+			transform: obj.Transform,
 		},
 		Collected: false,
 	}
@@ -202,10 +230,11 @@ func processCrystalObject(obj tiled.Object, tile tiled.Tile) *Crystal {
 func processBreakingFloorObject(obj tiled.Object, tile tiled.Tile) *BreakingFloor {
 	return &BreakingFloor{
 		BaseSprite: BaseSprite{
-			Location: getLocation(obj),
-			image:    BreakingFloorSprite,
-			srcRect:  toImageRectangle(tile.SrcRect),
-			hitbox:   toRect(tile.HitRect).Offset(obj.Location.X, obj.Location.Y),
+			Location:  getLocation(obj),
+			image:     BreakingFloorSprite,
+			srcRect:   toImageRectangle(tile.SrcRect),
+			hitbox:    objectHitbox(obj, tile),
+			transform: obj.Transform,
 		},
 		spriteSheet: NewGridTileSet(16, 16, 5, 1),
 		state:       Intact,
@@ -235,8 +264,9 @@ func GetTiles(tm *tiled.Map) []Tile {
 							X: float64(x),
 							Y: float64(y),
 						},
-						image:   t.SrcImage.(*ebiten.Image),
-						srcRect: toImageRectangle(t.SrcRect),
+						image:     t.SrcImage.(*ebiten.Image),
+						srcRect:   toImageRectangle(t.SrcRect),
+						transform: layer.Transforms[idx],
 						hitbox: Rect{
 							left:   float64(x),
 							top:    float64(y),
@@ -246,9 +276,32 @@ func GetTiles(tm *tiled.Map) []Tile {
 					},
 					solid: isSolid(t),
 				}
+				tile.frames = getTileFrames(tm, t.Animation)
+				if tile.IsAnimated() {
+					tile.showFrame(0)
+				}
 				tiles = append(tiles, tile)
 			}
 		}
 	}
 	return tiles
 }
+
+// getTileFrames returns the frames of an animated tile, leaving out any
+// that show a tile that isn't in the map's tilesets.
+func getTileFrames(tm *tiled.Map, animation []tiled.Frame) []TileFrame {
+	frames := []TileFrame{}
+	for _, f := range animation {
+		t, ok := tm.Tiles[f.TileID]
+		if !ok {
+			log.Printf("Animation frame shows unknown tile %d\n", f.TileID)
+			continue
+		}
+		frames = append(frames, TileFrame{
+			image:    t.SrcImage.(*ebiten.Image),
+			srcRect:  toImageRectangle(t.SrcRect),
+			duration: f.Duration,
+		})
+	}
+	return frames
+}
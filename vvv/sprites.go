@@ -5,6 +5,7 @@ import (
 	"image/color"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/jonathanacross/gamedev/vvv/tiled"
 )
 
 // BaseSprite provides common fields and methods for any visible game entity.
@@ -14,6 +15,8 @@ type BaseSprite struct {
 	image   *ebiten.Image
 	srcRect image.Rectangle
 	hitbox  Rect
+	// How the sprite was flipped when it was placed in Tiled
+	transform tiled.Transform
 }
 
 // HitBox returns the collision rectangle for the BaseSprite.
@@ -29,6 +32,7 @@ func (bs *BaseSprite) GetY() float64 { return bs.Y }
 
 func (bs *BaseSprite) Draw(screen *ebiten.Image) {
 	op := &ebiten.DrawImageOptions{}
+	op.GeoM = flipGeoM(bs.transform, float64(bs.srcRect.Dx()), float64(bs.srcRect.Dy()))
 	op.GeoM.Translate(bs.X, bs.Y)
 	currImage := bs.image.SubImage(bs.srcRect).(*ebiten.Image)
 	screen.DrawImage(currImage, op)
@@ -94,3 +98,26 @@ func (f *FlippableSprite) FlippedHitbox() Rect {
 
 	return box
 }
+
+// flipGeoM returns the transformation that flips a w by h image in place
+// the way Tiled does: diagonally first, then horizontally, then vertically.
+func flipGeoM(t tiled.Transform, w float64, h float64) ebiten.GeoM {
+	var g ebiten.GeoM
+	if t.FlipDiag {
+		// Swap x and y
+		g.SetElement(0, 0, 0)
+		g.SetElement(0, 1, 1)
+		g.SetElement(1, 0, 1)
+		g.SetElement(1, 1, 0)
+		w, h = h, w
+	}
+	if t.FlipHoriz {
+		g.Scale(-1, 1)
+		g.Translate(w, 0)
+	}
+	if t.FlipVert {
+		g.Scale(1, -1)
+		g.Translate(0, h)
+	}
+	return g
+}
@@ -0,0 +1,34 @@
+package tiled
+
+// Tiled stores how a tile is flipped in the high bits of its GID. The flags
+// don't fit in an int on 32-bit systems, so GIDs are read as uint32.
+const (
+	flippedHorizFlag uint32 = 0x80000000
+	flippedVertFlag  uint32 = 0x40000000
+	flippedDiagFlag  uint32 = 0x20000000
+	// Used for hexagonal maps, which aren't supported, but cleared so the
+	// ID is still found
+	rotatedHexFlag uint32 = 0x10000000
+
+	gidFlags = flippedHorizFlag | flippedVertFlag | flippedDiagFlag | rotatedHexFlag
+)
+
+// decodeGID splits a GID into the tile ID and how the tile is flipped.
+func decodeGID(gid uint32) (int, Transform) {
+	transform := Transform{
+		FlipHoriz: gid&flippedHorizFlag != 0,
+		FlipVert:  gid&flippedVertFlag != 0,
+		FlipDiag:  gid&flippedDiagFlag != 0,
+	}
+	return int(gid &^ gidFlags), transform
+}
+
+// decodeGIDs decodes each GID in a tile layer.
+func decodeGIDs(gids []uint32) ([]int, []Transform) {
+	ids := make([]int, len(gids))
+	transforms := make([]Transform, len(gids))
+	for i, gid := range gids {
+		ids[i], transforms[i] = decodeGID(gid)
+	}
+	return ids, transforms
+}
@@ -0,0 +1,34 @@
+package tiled
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeGID(t *testing.T) {
+	tests := []struct {
+		name      string
+		gid       uint32
+		id        int
+		transform Transform
+	}{
+		{"Empty", 0, 0, Transform{}},
+		{"Not flipped", 25, 25, Transform{}},
+		{"Horizontal", 0x80000000 | 25, 25, Transform{FlipHoriz: true}},
+		{"Vertical", 0x40000000 | 25, 25, Transform{FlipVert: true}},
+		{"Rotated 90 degrees", 0xA0000000 | 25, 25, Transform{FlipHoriz: true, FlipDiag: true}},
+		{"All flips", 0xE0000000 | 3, 3, Transform{FlipHoriz: true, FlipVert: true, FlipDiag: true}},
+		{"Hexagonal rotation", 0x10000000 | 7, 7, Transform{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, transform := decodeGID(tt.gid)
+			if id != tt.id {
+				t.Errorf("Expected ID %d, got %d", tt.id, id)
+			}
+			if !reflect.DeepEqual(transform, tt.transform) {
+				t.Errorf("Expected transform %+v, got %+v", tt.transform, transform)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// layerData holds the GIDs of a tile layer. Tiled saves them either as a
+// plain list, or as a string in the layer's encoding.
+type layerData struct {
+	IDs     []uint32
+	Encoded string
+}
+
+// UnmarshalJSON accepts either form of the data.
+func (d *layerData) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &d.Encoded)
+	}
+	return json.Unmarshal(data, &d.IDs)
+}
+
+// decodeData turns the layer's encoded data, if any, into tile IDs.
+func (layer *tiledLayer) decodeData() error {
+	if layer.Encoding == "" {
+		return nil
+	}
+	ids, err := decodeLayerData(layer.Data.Encoded, layer.Encoding, layer.Compression)
+	if err != nil {
+		return fmt.Errorf("layer '%s': %w", layer.Name, err)
+	}
+	if size := layer.Width * layer.Height; size > 0 && len(ids) != size {
+		return fmt.Errorf("layer '%s': expected %d tiles, got %d", layer.Name, size, len(ids))
+	}
+	layer.Data = layerData{IDs: ids}
+	return nil
+}
+
+// decodeLayerData decodes tile IDs stored as comma-separated numbers
+// ("csv"), or as base64 of 32-bit little-endian numbers, which may also be
+// compressed.
+func decodeLayerData(text string, encoding string, compression string) ([]uint32, error) {
+	switch encoding {
+	case "csv":
+		return decodeCSV(text)
+	case "base64":
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 data: %w", err)
+		}
+		raw, err = decompress(raw, compression)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw)%4 != 0 {
+			return nil, fmt.Errorf("data is %d bytes, not a whole number of tiles", len(raw))
+		}
+		ids := make([]uint32, len(raw)/4)
+		for i := range ids {
+			ids[i] = binary.LittleEndian.Uint32(raw[i*4:])
+		}
+		return ids, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding '%s'", encoding)
+}
+
+func decodeCSV(text string) ([]uint32, error) {
+	ids := []uint32{}
+	for _, field := range strings.Split(text, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tile ID '%s'", field)
+		}
+		ids = append(ids, uint32(id))
+	}
+	return ids, nil
+}
+
+// decompress undoes the given compression.
+func decompress(data []byte, compression string) ([]byte, error) {
+	var r io.Reader
+	switch compression {
+	case "":
+		return data, nil
+	case "zlib":
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zlib data: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case "gzip":
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd data: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("unsupported compression '%s'", compression)
+	}
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s data: %w", compression, err)
+	}
+	return out, nil
+}
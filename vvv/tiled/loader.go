@@ -100,19 +100,35 @@ func (l *FsLoader) LoadMap(filePath string) (*Map, error) {
 	return gameMap, nil
 }
 
-// loadMapData handles reading and unmarshalling the main map JSON file.
+// loadMapData handles reading and unmarshalling the main map file, which
+// may be JSON or XML (.tmx).
 func (l *FsLoader) loadMapData(filePath string) (*tiledMap, error) {
 	data, err := l.loadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load map file %s: %w", filePath, err)
 	}
 
-	var tiledMap tiledMap
-	if err := json.Unmarshal(data, &tiledMap); err != nil {
-		return nil, fmt.Errorf("failed to parse map JSON %s: %w", filePath, err)
+	var tiledMapData *tiledMap
+	if isXML(data) {
+		tiledMapData, err = parseTMX(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse map XML %s: %w", filePath, err)
+		}
+	} else {
+		tiledMapData = &tiledMap{}
+		if err := json.Unmarshal(data, tiledMapData); err != nil {
+			return nil, fmt.Errorf("failed to parse map JSON %s: %w", filePath, err)
+		}
+	}
+
+	// Tile layers may be stored encoded or compressed.
+	for i := range tiledMapData.Layers {
+		if err := tiledMapData.Layers[i].decodeData(); err != nil {
+			return nil, fmt.Errorf("failed to decode map %s: %w", filePath, err)
+		}
 	}
 
-	return &tiledMap, nil
+	return tiledMapData, nil
 }
 
 // loadTilesets iterates through the tileset references in the map and loads them.
@@ -131,7 +147,13 @@ func (l *FsLoader) loadTilesets(tsRefs []tiledTileset, mapDir string) (map[int]T
 			if err != nil {
 				return nil, fmt.Errorf("failed to load tileset file %s: %w", tsPath, err)
 			}
-			if err := json.Unmarshal(data, &tsData); err != nil {
+			if isXML(data) {
+				parsed, err := parseTSX(data)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse tileset XML %s: %w", tsPath, err)
+				}
+				tsData = *parsed
+			} else if err := json.Unmarshal(data, &tsData); err != nil {
 				return nil, fmt.Errorf("failed to parse tileset JSON %s: %w", tsPath, err)
 			}
 		} else {
@@ -200,7 +222,7 @@ func convertLayers(tiledLayers []tiledLayer, tiles *map[int]Tile) ([]MapLayer, e
 
 		switch newLayer.Type {
 		case "tilelayer":
-			newLayer.TileIds = layerJSON.Data
+			newLayer.TileIds, newLayer.Transforms = decodeGIDs(layerJSON.Data.IDs)
 		case "objectgroup":
 			objects, err := convertObjectGroup(layerJSON.Objects, tiles)
 			if err != nil {
@@ -222,8 +244,9 @@ func convertObjectGroup(tiledObjects []tiledObject, tiles *map[int]Tile) ([]Obje
 		objType := ""
 
 		// Look up the tile data if this object has a GID.
+		gid, transform := decodeGID(objJSON.GID)
 		yOffset := 0.0
-		if tileData, ok := (*tiles)[objJSON.GID]; ok {
+		if tileData, ok := (*tiles)[gid]; ok {
 			objType = tileData.Type
 			// Copy properties from the tile, if any
 			if tileData.Properties != nil {
@@ -257,7 +280,8 @@ func convertObjectGroup(tiledObjects []tiledObject, tiles *map[int]Tile) ([]Obje
 				Width:  objJSON.Width,
 				Height: objJSON.Height,
 			},
-			GID: objJSON.GID,
+			GID:       gid,
+			Transform: transform,
 		}
 	}
 	return objects, nil
@@ -189,6 +189,71 @@ func TestLoadMap(t *testing.T) {
 		}
 	})
 
+	t.Run("Flipped tiles and objects", func(t *testing.T) {
+		mockFS := newMockFS()
+		// GIDs with the horizontal, vertical and diagonal flip bits set
+		mockFS.files["assets/levels/flipped.json"] = []byte(`
+			{
+				"width": 2,
+				"height": 2,
+				"tilewidth": 16,
+				"tileheight": 16,
+				"layers": [
+					{
+						"data": [1, 2147483650, 1073741825, 3758096387],
+						"height": 2,
+						"name": "Tiles",
+						"type": "tilelayer",
+						"width": 2
+					},
+					{
+						"name": "Objects",
+						"objects": [
+							{ "gid": 2147483649, "height": 16, "id": 1, "width": 16, "x": 16, "y": 32 }
+						],
+						"type": "objectgroup"
+					}
+				],
+				"tilesets": [
+					{ "firstgid": 1, "source": "../tilesets/tileset.json" }
+				]
+			}
+		`)
+		loader := NewFsLoader(mockFS)
+
+		gameMap, err := loader.LoadMap("assets/levels/flipped.json")
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+
+		tileLayer := gameMap.Layers[0]
+		if !reflect.DeepEqual(tileLayer.TileIds, []int{1, 2, 1, 3}) {
+			t.Errorf("Expected tile data [1 2 1 3], got %v", tileLayer.TileIds)
+		}
+		expectedTransforms := []Transform{
+			{},
+			{FlipHoriz: true},
+			{FlipVert: true},
+			{FlipHoriz: true, FlipVert: true, FlipDiag: true},
+		}
+		if !reflect.DeepEqual(tileLayer.Transforms, expectedTransforms) {
+			t.Errorf("Expected transforms %+v, got %+v", expectedTransforms, tileLayer.Transforms)
+		}
+
+		// The object's tile is found despite the flip.
+		obj := gameMap.Layers[1].Objects[0]
+		if obj.GID != 1 || !obj.Transform.FlipHoriz {
+			t.Errorf("Expected GID 1 flipped horizontally, got GID %d with %+v", obj.GID, obj.Transform)
+		}
+		solid, err := obj.Properties.GetPropertyBool("solid")
+		if err != nil || !solid {
+			t.Errorf("Expected solid property from the tile, got %v (error: %v)", solid, err)
+		}
+		if obj.Location.Y != 16 {
+			t.Errorf("Expected object Y 16, got %v", obj.Location.Y)
+		}
+	})
+
 	t.Run("Map file not found", func(t *testing.T) {
 		mockFS := newMockFS()
 		loader := NewFsLoader(mockFS)
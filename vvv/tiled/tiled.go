@@ -13,12 +13,16 @@ type tiledMap struct {
 }
 
 type tiledLayer struct {
-	Name    string        `json:"name"`
-	Type    string        `json:"type"`
-	Width   int           `json:"width"`
-	Height  int           `json:"height"`
-	Data    []int         `json:"data"`
-	Objects []tiledObject `json:"objects"`
+	Name   string    `json:"name"`
+	Type   string    `json:"type"`
+	Width  int       `json:"width"`
+	Height int       `json:"height"`
+	Data   layerData `json:"data"`
+	// How Data is stored if it is a string: "base64" or "csv", and for
+	// base64 optionally "zlib", "gzip" or "zstd" compressed
+	Encoding    string        `json:"encoding"`
+	Compression string        `json:"compression"`
+	Objects     []tiledObject `json:"objects"`
 }
 
 type tiledTileset struct {
@@ -41,6 +45,13 @@ type tiledTile struct {
 	Properties  []tiledProperty  `json:"properties"`
 	Type        string           `json:"type"`
 	ObjectGroup tiledObjectGroup `json:"objectgroup"`
+	Animation   []tiledFrame     `json:"animation"`
+}
+
+type tiledFrame struct {
+	TileID int `json:"tileid"`
+	// In milliseconds
+	Duration int `json:"duration"`
 }
 
 type tiledProperty struct {
@@ -58,7 +69,7 @@ type tiledObject struct {
 	Width      float64         `json:"width"`
 	Height     float64         `json:"height"`
 	Rotation   float64         `json:"rotation"`
-	GID        int             `json:"gid"`
+	GID        uint32          `json:"gid"`
 	Properties []tiledProperty `json:"properties"`
 }
 
@@ -2,6 +2,7 @@ package tiled
 
 import (
 	"fmt"
+	"time"
 )
 
 // ConvertTileset converts an intermediate tiledTileset struct into a slice of
@@ -40,6 +41,7 @@ func convertCollectionTileset(tsData *tiledTileset, images map[string]ImageProvi
 			HitRect:    hitRect,
 			Properties: &properties,
 			Type:       tiledTile.Type,
+			Animation:  convertAnimation(tiledTile.Animation, firstGID),
 		}
 		tiles = append(tiles, tile)
 	}
@@ -79,11 +81,28 @@ func convertSpriteSheetTileset(tsData *tiledTileset, srcImage ImageProvider, fir
 		tiles[tiledTile.ID].HitRect = hitRect
 		tiles[tiledTile.ID].Properties = &properties
 		tiles[tiledTile.ID].Type = tiledTile.Type
+		tiles[tiledTile.ID].Animation = convertAnimation(tiledTile.Animation, firstGID)
 	}
 
 	return tiles, nil
 }
 
+// convertAnimation converts the frames of an animated tile, whose tile IDs
+// are local to the tileset. It returns nil if the tile isn't animated.
+func convertAnimation(tiledFrames []tiledFrame, firstGID int) []Frame {
+	if len(tiledFrames) == 0 {
+		return nil
+	}
+	frames := make([]Frame, len(tiledFrames))
+	for i, f := range tiledFrames {
+		frames[i] = Frame{
+			TileID:   f.TileID + firstGID,
+			Duration: time.Duration(f.Duration) * time.Millisecond,
+		}
+	}
+	return frames
+}
+
 // getHitbox calculates the hitbox for a tile based on its object group.
 func getHitbox(tiledTile *tiledTile, width float64, height float64) Rect {
 	// Tiled allows a custom hitbox to be defined via a single object in the object group.
@@ -3,6 +3,7 @@ package tiled
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 // Implements the ImageProvider interface for testing.
@@ -112,6 +113,37 @@ func TestConvertTileset(t *testing.T) {
 			t.Errorf("expected tile %+v, got %+v", expectedTile, tiles[0])
 		}
 	})
+
+	// Test case for animated tiles, whose frames get global IDs.
+	t.Run("AnimatedTile", func(t *testing.T) {
+		tsData := &tiledTileset{
+			Image:      "tileset.png",
+			TileWidth:  16,
+			TileHeight: 16,
+			Columns:    5,
+			Tiles: []tiledTile{
+				{ID: 1, Animation: []tiledFrame{{TileID: 1, Duration: 100}, {TileID: 2, Duration: 250}}},
+			},
+			TileCount: 3,
+		}
+
+		images := map[string]ImageProvider{"tileset.png": mockImage}
+		tiles, err := ConvertTileset(tsData, images, 10)
+		if err != nil {
+			t.Fatalf("ConvertTileset failed: %v", err)
+		}
+
+		expected := []Frame{
+			{TileID: 11, Duration: 100 * time.Millisecond},
+			{TileID: 12, Duration: 250 * time.Millisecond},
+		}
+		if !reflect.DeepEqual(tiles[1].Animation, expected) {
+			t.Errorf("expected animation %+v, got %+v", expected, tiles[1].Animation)
+		}
+		if tiles[0].Animation != nil {
+			t.Errorf("expected no animation for tile 10, got %+v", tiles[0].Animation)
+		}
+	})
 }
 
 func TestGetHitbox(t *testing.T) {
@@ -0,0 +1,255 @@
+package tiled
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// --- Tiled XML (.tmx and .tsx) structures, converted to the JSON ones ---
+
+type tmxMap struct {
+	Width      int          `xml:"width,attr"`
+	Height     int          `xml:"height,attr"`
+	TileWidth  int          `xml:"tilewidth,attr"`
+	TileHeight int          `xml:"tileheight,attr"`
+	Tilesets   []tsxTileset `xml:"tileset"`
+	// Tile layers, object groups and any other layers, in order
+	Layers []tmxLayer `xml:",any"`
+}
+
+type tmxLayer struct {
+	XMLName xml.Name
+	Name    string      `xml:"name,attr"`
+	Width   int         `xml:"width,attr"`
+	Height  int         `xml:"height,attr"`
+	Data    *tmxData    `xml:"data"`
+	Objects []tmxObject `xml:"object"`
+}
+
+type tmxData struct {
+	Encoding    string `xml:"encoding,attr"`
+	Compression string `xml:"compression,attr"`
+	Text        string `xml:",chardata"`
+	// Tiles as elements, if there is no encoding
+	Tiles []struct {
+		GID uint32 `xml:"gid,attr"`
+	} `xml:"tile"`
+}
+
+type tsxTileset struct {
+	FirstGID   int       `xml:"firstgid,attr"`
+	Source     string    `xml:"source,attr"`
+	Name       string    `xml:"name,attr"`
+	TileWidth  int       `xml:"tilewidth,attr"`
+	TileHeight int       `xml:"tileheight,attr"`
+	TileCount  int       `xml:"tilecount,attr"`
+	Columns    int       `xml:"columns,attr"`
+	Image      tmxImage  `xml:"image"`
+	Tiles      []tsxTile `xml:"tile"`
+}
+
+type tmxImage struct {
+	Source string `xml:"source,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+type tsxTile struct {
+	ID   int    `xml:"id,attr"`
+	Type string `xml:"type,attr"`
+	// Tiled 1.9 renamed type to class
+	Class       string        `xml:"class,attr"`
+	Image       tmxImage      `xml:"image"`
+	Properties  []tmxProperty `xml:"properties>property"`
+	ObjectGroup struct {
+		Objects []tmxObject `xml:"object"`
+	} `xml:"objectgroup"`
+	Animation []struct {
+		TileID   int `xml:"tileid,attr"`
+		Duration int `xml:"duration,attr"`
+	} `xml:"animation>frame"`
+}
+
+type tmxObject struct {
+	ID         int           `xml:"id,attr"`
+	Name       string        `xml:"name,attr"`
+	Type       string        `xml:"type,attr"`
+	Class      string        `xml:"class,attr"`
+	X          float64       `xml:"x,attr"`
+	Y          float64       `xml:"y,attr"`
+	Width      float64       `xml:"width,attr"`
+	Height     float64       `xml:"height,attr"`
+	Rotation   float64       `xml:"rotation,attr"`
+	GID        uint32        `xml:"gid,attr"`
+	Properties []tmxProperty `xml:"properties>property"`
+}
+
+type tmxProperty struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+	// Multi-line strings are saved as text rather than in value
+	Text string `xml:",chardata"`
+}
+
+// isXML reports whether a Tiled file is XML rather than JSON.
+func isXML(data []byte) bool {
+	trimmed := strings.TrimSpace(string(data))
+	return strings.HasPrefix(trimmed, "<")
+}
+
+// parseTMX reads a map saved as XML.
+func parseTMX(data []byte) (*tiledMap, error) {
+	var m tmxMap
+	if err := xml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	result := &tiledMap{
+		Width:      m.Width,
+		Height:     m.Height,
+		TileWidth:  m.TileWidth,
+		TileHeight: m.TileHeight,
+	}
+	for _, ts := range m.Tilesets {
+		result.Tilesets = append(result.Tilesets, ts.convert())
+	}
+	for _, layer := range m.Layers {
+		converted, ok := layer.convert()
+		if ok {
+			result.Layers = append(result.Layers, converted)
+		}
+	}
+	return result, nil
+}
+
+// parseTSX reads a tileset saved as XML.
+func parseTSX(data []byte) (*tiledTileset, error) {
+	var ts tsxTileset
+	if err := xml.Unmarshal(data, &ts); err != nil {
+		return nil, err
+	}
+	converted := ts.convert()
+	return &converted, nil
+}
+
+// convert returns the layer as it would be in JSON. It reports false for
+// elements of the map that aren't layers.
+func (layer *tmxLayer) convert() (tiledLayer, bool) {
+	result := tiledLayer{
+		Name:   layer.Name,
+		Width:  layer.Width,
+		Height: layer.Height,
+	}
+	switch layer.XMLName.Local {
+	case "layer":
+		result.Type = "tilelayer"
+		if layer.Data == nil {
+			break
+		}
+		result.Encoding = layer.Data.Encoding
+		result.Compression = layer.Data.Compression
+		if result.Encoding != "" {
+			result.Data.Encoded = layer.Data.Text
+			break
+		}
+		for _, tile := range layer.Data.Tiles {
+			result.Data.IDs = append(result.Data.IDs, tile.GID)
+		}
+	case "objectgroup":
+		result.Type = "objectgroup"
+		for _, obj := range layer.Objects {
+			result.Objects = append(result.Objects, obj.convert())
+		}
+	case "imagelayer", "group":
+		result.Type = layer.XMLName.Local
+	default:
+		return tiledLayer{}, false
+	}
+	return result, true
+}
+
+func (ts *tsxTileset) convert() tiledTileset {
+	result := tiledTileset{
+		FirstGID:   ts.FirstGID,
+		Source:     ts.Source,
+		Image:      ts.Image.Source,
+		Name:       ts.Name,
+		TileWidth:  ts.TileWidth,
+		TileHeight: ts.TileHeight,
+		TileCount:  ts.TileCount,
+		Columns:    ts.Columns,
+	}
+	for _, tile := range ts.Tiles {
+		converted := tiledTile{
+			ID:          tile.ID,
+			Image:       tile.Image.Source,
+			ImageWidth:  tile.Image.Width,
+			ImageHeight: tile.Image.Height,
+			Properties:  convertProperties(tile.Properties),
+			Type:        firstNonEmpty(tile.Type, tile.Class),
+		}
+		for _, frame := range tile.Animation {
+			converted.Animation = append(converted.Animation, tiledFrame{TileID: frame.TileID, Duration: frame.Duration})
+		}
+		for _, obj := range tile.ObjectGroup.Objects {
+			converted.ObjectGroup.Objects = append(converted.ObjectGroup.Objects, obj.convert())
+		}
+		result.Tiles = append(result.Tiles, converted)
+	}
+	return result
+}
+
+func (obj *tmxObject) convert() tiledObject {
+	return tiledObject{
+		ID:         obj.ID,
+		Name:       obj.Name,
+		Type:       firstNonEmpty(obj.Type, obj.Class),
+		X:          obj.X,
+		Y:          obj.Y,
+		Width:      obj.Width,
+		Height:     obj.Height,
+		Rotation:   obj.Rotation,
+		GID:        obj.GID,
+		Properties: convertProperties(obj.Properties),
+	}
+}
+
+// convertProperties gives the properties the values they would have in
+// JSON, so numbers are float64 and bools are bool. Values that don't
+// parse are left as strings, for GetProperties to report.
+func convertProperties(props []tmxProperty) []tiledProperty {
+	result := []tiledProperty{}
+	for _, p := range props {
+		text := p.Value
+		if text == "" {
+			text = p.Text
+		}
+		var value interface{} = text
+		switch p.Type {
+		case "int", "float":
+			if f, err := strconv.ParseFloat(text, 64); err == nil {
+				value = f
+			}
+		case "bool":
+			if b, err := strconv.ParseBool(text); err == nil {
+				value = b
+			}
+		case "":
+			// Tiled leaves out the type of strings
+			p.Type = "string"
+		}
+		result = append(result, tiledProperty{Name: p.Name, Type: p.Type, Value: value})
+	}
+	return result
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
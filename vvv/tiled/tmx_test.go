@@ -0,0 +1,338 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// encodeLayer stores tile IDs the way Tiled does for base64 data, with the
+// given compression.
+func encodeLayer(t *testing.T, ids []int, compression string) string {
+	t.Helper()
+	raw := make([]byte, 4*len(ids))
+	for i, id := range ids {
+		binary.LittleEndian.PutUint32(raw[i*4:], uint32(id))
+	}
+
+	var buf bytes.Buffer
+	switch compression {
+	case "":
+		buf.Write(raw)
+	case "zlib":
+		w := zlib.NewWriter(&buf)
+		w.Write(raw)
+		w.Close()
+	case "gzip":
+		w := gzip.NewWriter(&buf)
+		w.Write(raw)
+		w.Close()
+	case "zstd":
+		w, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatalf("Expected no error creating a zstd writer, got: %v", err)
+		}
+		w.Write(raw)
+		w.Close()
+	default:
+		t.Fatalf("Unknown compression '%s'", compression)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// tmxMapWithData returns a 2x2 map whose only tile layer has the given
+// <data> element.
+func tmxMapWithData(data string) []byte {
+	return []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="2" height="2" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" source="../tilesets/tileset.tsx"/>
+ <layer id="1" name="Tiles" width="2" height="2">
+  ` + data + `
+ </layer>
+</map>
+`)
+}
+
+func newTMXMockFS() *mockFS {
+	mockFS := newMockFS()
+	mockFS.files["assets/tilesets/tileset.tsx"] = []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<tileset version="1.10" name="tileset" tilewidth="16" tileheight="16" tilecount="3" columns="5">
+ <image source="../images/tileset.png" width="80" height="128"/>
+ <tile id="0" type="Wall">
+  <properties>
+   <property name="solid" type="bool" value="true"/>
+  </properties>
+ </tile>
+ <tile id="1">
+  <animation>
+   <frame tileid="1" duration="200"/>
+   <frame tileid="2" duration="300"/>
+  </animation>
+ </tile>
+ <tile id="2" class="Spikes">
+  <properties>
+   <property name="damage" type="int" value="10"/>
+  </properties>
+ </tile>
+</tileset>
+`)
+	mockFS.files["assets/levels/level1.tmx"] = []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="3" height="2" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" source="../tilesets/tileset.tsx"/>
+ <layer id="1" name="Background" width="3" height="2">
+  <data encoding="csv">
+1,2,3,
+0,0,1
+</data>
+ </layer>
+ <objectgroup id="2" name="Objects">
+  <object id="1" name="Spike" gid="3" x="32" y="32" width="16" height="16">
+   <properties>
+    <property name="speed" type="float" value="1.5"/>
+   </properties>
+  </object>
+  <object id="2" name="Door" type="Door" x="8" y="4" width="16" height="32">
+   <properties>
+    <property name="target" value="level2"/>
+   </properties>
+  </object>
+ </objectgroup>
+ <layer id="3" name="Foreground" width="3" height="2">
+  <data>
+   <tile gid="1"/>
+   <tile/>
+   <tile/>
+   <tile/>
+   <tile/>
+   <tile gid="2"/>
+  </data>
+ </layer>
+</map>
+`)
+	return mockFS
+}
+
+func TestLoadTMXMap(t *testing.T) {
+	t.Run("Successfully load a TMX map", func(t *testing.T) {
+		loader := NewFsLoader(newTMXMockFS())
+
+		gameMap, err := loader.LoadMap("assets/levels/level1.tmx")
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+
+		if gameMap.WidthInTiles != 3 || gameMap.HeightInTiles != 2 {
+			t.Errorf("Expected map dimensions 3x2, got %dx%d", gameMap.WidthInTiles, gameMap.HeightInTiles)
+		}
+		if gameMap.TileWidth != 16 || gameMap.TileHeight != 16 {
+			t.Errorf("Expected tile size 16x16, got %dx%d", gameMap.TileWidth, gameMap.TileHeight)
+		}
+		if len(gameMap.Tiles) != 3 {
+			t.Errorf("Expected 3 tiles, got %d", len(gameMap.Tiles))
+		}
+		if len(gameMap.Layers) != 3 {
+			t.Fatalf("Expected 3 layers, got %d", len(gameMap.Layers))
+		}
+
+		// Layers keep their order in the file.
+		names := []string{}
+		for _, layer := range gameMap.Layers {
+			names = append(names, layer.Name)
+		}
+		if !reflect.DeepEqual(names, []string{"Background", "Objects", "Foreground"}) {
+			t.Errorf("Expected layers [Background Objects Foreground], got %v", names)
+		}
+
+		background := gameMap.Layers[0]
+		if background.Type != "tilelayer" {
+			t.Errorf("Expected type 'tilelayer', got '%s'", background.Type)
+		}
+		if !reflect.DeepEqual(background.TileIds, []int{1, 2, 3, 0, 0, 1}) {
+			t.Errorf("Expected tile data [1 2 3 0 0 1], got %v", background.TileIds)
+		}
+		foreground := gameMap.Layers[2]
+		if !reflect.DeepEqual(foreground.TileIds, []int{1, 0, 0, 0, 0, 2}) {
+			t.Errorf("Expected tile data [1 0 0 0 0 2], got %v", foreground.TileIds)
+		}
+	})
+
+	t.Run("Tileset properties and types", func(t *testing.T) {
+		loader := NewFsLoader(newTMXMockFS())
+
+		gameMap, err := loader.LoadMap("assets/levels/level1.tmx")
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+
+		wall := gameMap.Tiles[1]
+		if wall.Type != "Wall" {
+			t.Errorf("Expected tile type 'Wall', got '%s'", wall.Type)
+		}
+		solid, err := wall.Properties.GetPropertyBool("solid")
+		if err != nil || !solid {
+			t.Errorf("Expected solid property true, got %v (error: %v)", solid, err)
+		}
+
+		animated := gameMap.Tiles[2]
+		expectedFrames := []Frame{
+			{TileID: 2, Duration: 200 * time.Millisecond},
+			{TileID: 3, Duration: 300 * time.Millisecond},
+		}
+		if !reflect.DeepEqual(animated.Animation, expectedFrames) {
+			t.Errorf("Expected animation %+v, got %+v", expectedFrames, animated.Animation)
+		}
+
+		spikes := gameMap.Tiles[3]
+		if spikes.Type != "Spikes" {
+			t.Errorf("Expected tile type 'Spikes' from class, got '%s'", spikes.Type)
+		}
+	})
+
+	t.Run("Objects", func(t *testing.T) {
+		loader := NewFsLoader(newTMXMockFS())
+
+		gameMap, err := loader.LoadMap("assets/levels/level1.tmx")
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+
+		objects := gameMap.Layers[1].Objects
+		if len(objects) != 2 {
+			t.Fatalf("Expected 2 objects, got %d", len(objects))
+		}
+
+		// A tile object takes its type and properties from the tile.
+		spike := objects[0]
+		if spike.Type != "Spikes" {
+			t.Errorf("Expected object type 'Spikes', got '%s'", spike.Type)
+		}
+		if spike.Location.X != 32 || spike.Location.Y != 16 {
+			t.Errorf("Expected object location {32, 16}, got {%v, %v}", spike.Location.X, spike.Location.Y)
+		}
+		damage, err := spike.Properties.GetPropertyInt("damage")
+		if err != nil || damage != 10 {
+			t.Errorf("Expected damage property 10, got %d (error: %v)", damage, err)
+		}
+		speed, err := spike.Properties.GetPropertyFloat64("speed")
+		if err != nil || speed != 1.5 {
+			t.Errorf("Expected speed property 1.5, got %v (error: %v)", speed, err)
+		}
+
+		door := objects[1]
+		if door.Type != "Door" {
+			t.Errorf("Expected object type 'Door', got '%s'", door.Type)
+		}
+		target, err := door.Properties.GetPropertyString("target")
+		if err != nil || target != "level2" {
+			t.Errorf("Expected target property 'level2', got '%s' (error: %v)", target, err)
+		}
+	})
+
+	t.Run("Invalid map XML", func(t *testing.T) {
+		mockFS := newTMXMockFS()
+		mockFS.files["assets/levels/invalid.tmx"] = []byte(`<map width="2"><layer></map>`)
+		loader := NewFsLoader(mockFS)
+		_, err := loader.LoadMap("assets/levels/invalid.tmx")
+		if err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+		if !strings.Contains(err.Error(), "failed to parse map XML") {
+			t.Errorf("Expected 'XML parse' error, got: %v", err)
+		}
+	})
+}
+
+func TestLayerDataEncodings(t *testing.T) {
+	ids := []int{1, 0, 3, 2}
+	for _, compression := range []string{"", "zlib", "gzip", "zstd"} {
+		t.Run(fmt.Sprintf("TMX base64 compression '%s'", compression), func(t *testing.T) {
+			mockFS := newTMXMockFS()
+			data := fmt.Sprintf(`<data encoding="base64" compression="%s">
+   %s
+  </data>`, compression, encodeLayer(t, ids, compression))
+			mockFS.files["assets/levels/encoded.tmx"] = tmxMapWithData(data)
+			loader := NewFsLoader(mockFS)
+
+			gameMap, err := loader.LoadMap("assets/levels/encoded.tmx")
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if !reflect.DeepEqual(gameMap.Layers[0].TileIds, ids) {
+				t.Errorf("Expected tile data %v, got %v", ids, gameMap.Layers[0].TileIds)
+			}
+		})
+	}
+
+	t.Run("JSON base64 with zlib", func(t *testing.T) {
+		mockFS := newMockFS()
+		mockFS.files["assets/levels/encoded.json"] = []byte(fmt.Sprintf(`
+			{
+				"width": 2,
+				"height": 2,
+				"tilewidth": 16,
+				"tileheight": 16,
+				"layers": [
+					{
+						"data": "%s",
+						"encoding": "base64",
+						"compression": "zlib",
+						"height": 2,
+						"name": "Tiles",
+						"type": "tilelayer",
+						"width": 2
+					}
+				],
+				"tilesets": [
+					{ "firstgid": 1, "source": "../tilesets/tileset.json" }
+				]
+			}
+		`, encodeLayer(t, ids, "zlib")))
+		loader := NewFsLoader(mockFS)
+
+		gameMap, err := loader.LoadMap("assets/levels/encoded.json")
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if !reflect.DeepEqual(gameMap.Layers[0].TileIds, ids) {
+			t.Errorf("Expected tile data %v, got %v", ids, gameMap.Layers[0].TileIds)
+		}
+	})
+
+	t.Run("Unsupported compression", func(t *testing.T) {
+		mockFS := newTMXMockFS()
+		data := fmt.Sprintf(`<data encoding="base64" compression="lzma">%s</data>`, encodeLayer(t, ids, ""))
+		mockFS.files["assets/levels/encoded.tmx"] = tmxMapWithData(data)
+		loader := NewFsLoader(mockFS)
+
+		_, err := loader.LoadMap("assets/levels/encoded.tmx")
+		if err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+		if !strings.Contains(err.Error(), "unsupported compression 'lzma'") {
+			t.Errorf("Expected 'unsupported compression' error, got: %v", err)
+		}
+	})
+
+	t.Run("Wrong number of tiles", func(t *testing.T) {
+		mockFS := newTMXMockFS()
+		mockFS.files["assets/levels/encoded.tmx"] = tmxMapWithData(`<data encoding="csv">1,2,3</data>`)
+		loader := NewFsLoader(mockFS)
+
+		_, err := loader.LoadMap("assets/levels/encoded.tmx")
+		if err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+		if !strings.Contains(err.Error(), "expected 4 tiles, got 3") {
+			t.Errorf("Expected tile count error, got: %v", err)
+		}
+	})
+}
@@ -1,5 +1,7 @@
 package tiled
 
+import "time"
+
 // ImageProvider represents an image-like type,
 // such as *image.Image or *ebiten.Image.
 type ImageProvider interface{}
@@ -23,6 +25,24 @@ type Tile struct {
 	HitRect    Rect
 	Properties *PropertySet
 	Type       string
+	// Frames the tile cycles through, if it is animated
+	Animation []Frame
+}
+
+// Frame is one frame of an animated tile.
+type Frame struct {
+	// ID of the tile shown in this frame
+	TileID   int
+	Duration time.Duration
+}
+
+// Transform says how a placed tile is flipped. A diagonal flip swaps x and
+// y, and is done before the others, so together they give rotations by
+// multiples of 90 degrees.
+type Transform struct {
+	FlipHoriz bool
+	FlipVert  bool
+	FlipDiag  bool
 }
 
 // A property set is just a map of key value pairs.
@@ -36,7 +56,9 @@ type Object struct {
 	Type       string
 	Properties *PropertySet
 	Location   Rect
-	GID        int
+	// ID of the tile shown for the object, if any, and how it is flipped
+	GID       int
+	Transform Transform
 }
 
 // MapLayer represents a single layer in the map.
@@ -46,7 +68,9 @@ type MapLayer struct {
 	Width   int
 	Height  int
 	TileIds []int
-	Objects []Object
+	// How each tile in TileIds is flipped
+	Transforms []Transform
+	Objects    []Object
 }
 
 // Map represents the entire Tiled map file.